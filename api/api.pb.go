@@ -2,14 +2,13 @@
 
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.25.0
+// 	protoc-gen-go v1.28.0
 // 	protoc        v3.14.0
 // source: api.proto
 
 package api
 
 import (
-	proto "github.com/golang/protobuf/proto"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
@@ -24,9 +23,69 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
-// This is a compile-time assertion that a sufficiently up-to-date version
-// of the legacy proto package is being used.
-const _ = proto.ProtoPackageIsVersion4
+// Capability is an optional feature of plugin
+type Capability int32
+
+const (
+	Capability_CapNone Capability = 0
+	// plugin implements Search
+	Capability_CapSearch Capability = 1
+	// plugin honors GetBookReq.UpdateOnly, only returns chapters after CurrentChaptCount
+	Capability_CapUpdateOnly Capability = 2
+	// plugin provides book cover
+	Capability_CapCover Capability = 3
+	// plugin requires user login
+	Capability_CapAuth Capability = 4
+	// plugin implements ListChapters
+	Capability_CapListChapters Capability = 5
+)
+
+// Enum value maps for Capability.
+var (
+	Capability_name = map[int32]string{
+		0: "CapNone",
+		1: "CapSearch",
+		2: "CapUpdateOnly",
+		3: "CapCover",
+		4: "CapAuth",
+		5: "CapListChapters",
+	}
+	Capability_value = map[string]int32{
+		"CapNone":         0,
+		"CapSearch":       1,
+		"CapUpdateOnly":   2,
+		"CapCover":        3,
+		"CapAuth":         4,
+		"CapListChapters": 5,
+	}
+)
+
+func (x Capability) Enum() *Capability {
+	p := new(Capability)
+	*p = x
+	return p
+}
+
+func (x Capability) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Capability) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_enumTypes[0].Descriptor()
+}
+
+func (Capability) Type() protoreflect.EnumType {
+	return &file_api_proto_enumTypes[0]
+}
+
+func (x Capability) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Capability.Descriptor instead.
+func (Capability) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{0}
+}
 
 type Empty struct {
 	state         protoimpl.MessageState
@@ -467,6 +526,330 @@ func (x *GetBookReq) GetCurrentChaptCount() uint32 {
 	return 0
 }
 
+type Manifest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name    string `protobuf:"bytes,1,opt,name=Name,proto3" json:"Name,omitempty"`
+	Version string `protobuf:"bytes,2,opt,name=Version,proto3" json:"Version,omitempty"`
+	// site domains supported by the plugin
+	Domains []string `protobuf:"bytes,3,rep,name=Domains,proto3" json:"Domains,omitempty"`
+	// version of plugin API the plugin implements
+	APIVersion   uint32       `protobuf:"varint,4,opt,name=APIVersion,proto3" json:"APIVersion,omitempty"`
+	Capabilities []Capability `protobuf:"varint,5,rep,packed,name=Capabilities,proto3,enum=api.Capability" json:"Capabilities,omitempty"`
+}
+
+func (x *Manifest) Reset() {
+	*x = Manifest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Manifest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Manifest) ProtoMessage() {}
+
+func (x *Manifest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Manifest.ProtoReflect.Descriptor instead.
+func (*Manifest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Manifest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Manifest) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *Manifest) GetDomains() []string {
+	if x != nil {
+		return x.Domains
+	}
+	return nil
+}
+
+func (x *Manifest) GetAPIVersion() uint32 {
+	if x != nil {
+		return x.APIVersion
+	}
+	return 0
+}
+
+func (x *Manifest) GetCapabilities() []Capability {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+type ListChaptersReq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// BookIndexURL returned by GetBookInfo
+	BookIndexURL string `protobuf:"bytes,1,opt,name=BookIndexURL,proto3" json:"BookIndexURL,omitempty"`
+}
+
+func (x *ListChaptersReq) Reset() {
+	*x = ListChaptersReq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListChaptersReq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListChaptersReq) ProtoMessage() {}
+
+func (x *ListChaptersReq) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListChaptersReq.ProtoReflect.Descriptor instead.
+func (*ListChaptersReq) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListChaptersReq) GetBookIndexURL() string {
+	if x != nil {
+		return x.BookIndexURL
+	}
+	return ""
+}
+
+// ChapterInfo is an entry of the chapter index of a book
+type ChapterInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// same as GetChapterResp.ChapterId
+	ChapterId   uint32 `protobuf:"varint,1,opt,name=ChapterId,proto3" json:"ChapterId,omitempty"`
+	ChapterName string `protobuf:"bytes,2,opt,name=ChapterName,proto3" json:"ChapterName,omitempty"`
+	ChapterURL  string `protobuf:"bytes,3,opt,name=ChapterURL,proto3" json:"ChapterURL,omitempty"`
+}
+
+func (x *ChapterInfo) Reset() {
+	*x = ChapterInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ChapterInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChapterInfo) ProtoMessage() {}
+
+func (x *ChapterInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChapterInfo.ProtoReflect.Descriptor instead.
+func (*ChapterInfo) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ChapterInfo) GetChapterId() uint32 {
+	if x != nil {
+		return x.ChapterId
+	}
+	return 0
+}
+
+func (x *ChapterInfo) GetChapterName() string {
+	if x != nil {
+		return x.ChapterName
+	}
+	return ""
+}
+
+func (x *ChapterInfo) GetChapterURL() string {
+	if x != nil {
+		return x.ChapterURL
+	}
+	return ""
+}
+
+type ListChaptersResp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Chapters []*ChapterInfo `protobuf:"bytes,1,rep,name=Chapters,proto3" json:"Chapters,omitempty"`
+}
+
+func (x *ListChaptersResp) Reset() {
+	*x = ListChaptersResp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListChaptersResp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListChaptersResp) ProtoMessage() {}
+
+func (x *ListChaptersResp) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListChaptersResp.ProtoReflect.Descriptor instead.
+func (*ListChaptersResp) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListChaptersResp) GetChapters() []*ChapterInfo {
+	if x != nil {
+		return x.Chapters
+	}
+	return nil
+}
+
+// BookMeta is the metadata of a book
+type BookMeta struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// cover image, only provided by plugin with CapCover
+	Cover []byte `protobuf:"bytes,1,opt,name=Cover,proto3" json:"Cover,omitempty"`
+	// MIME type of Cover, like image/jpeg
+	CoverType string `protobuf:"bytes,2,opt,name=CoverType,proto3" json:"CoverType,omitempty"`
+	Synopsis  string `protobuf:"bytes,3,opt,name=Synopsis,proto3" json:"Synopsis,omitempty"`
+	// genre tags
+	Tags      []string `protobuf:"bytes,4,rep,name=Tags,proto3" json:"Tags,omitempty"`
+	WordCount uint64   `protobuf:"varint,5,opt,name=WordCount,proto3" json:"WordCount,omitempty"`
+}
+
+func (x *BookMeta) Reset() {
+	*x = BookMeta{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BookMeta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BookMeta) ProtoMessage() {}
+
+func (x *BookMeta) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BookMeta.ProtoReflect.Descriptor instead.
+func (*BookMeta) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *BookMeta) GetCover() []byte {
+	if x != nil {
+		return x.Cover
+	}
+	return nil
+}
+
+func (x *BookMeta) GetCoverType() string {
+	if x != nil {
+		return x.CoverType
+	}
+	return ""
+}
+
+func (x *BookMeta) GetSynopsis() string {
+	if x != nil {
+		return x.Synopsis
+	}
+	return ""
+}
+
+func (x *BookMeta) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *BookMeta) GetWordCount() uint64 {
+	if x != nil {
+		return x.WordCount
+	}
+	return 0
+}
+
 type GetChapterResp struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -480,7 +863,7 @@ type GetChapterResp struct {
 func (x *GetChapterResp) Reset() {
 	*x = GetChapterResp{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_api_proto_msgTypes[8]
+		mi := &file_api_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -493,7 +876,7 @@ func (x *GetChapterResp) String() string {
 func (*GetChapterResp) ProtoMessage() {}
 
 func (x *GetChapterResp) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_msgTypes[8]
+	mi := &file_api_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -506,7 +889,7 @@ func (x *GetChapterResp) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetChapterResp.ProtoReflect.Descriptor instead.
 func (*GetChapterResp) Descriptor() ([]byte, []int) {
-	return file_api_proto_rawDescGZIP(), []int{8}
+	return file_api_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *GetChapterResp) GetChapterContent() string {
@@ -579,32 +962,83 @@ var file_api_proto_rawDesc = []byte{
 	0x08, 0x52, 0x0a, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x4f, 0x6e, 0x6c, 0x79, 0x12, 0x2c, 0x0a,
 	0x11, 0x43, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x43, 0x68, 0x61, 0x70, 0x74, 0x43, 0x6f, 0x75,
 	0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x11, 0x43, 0x75, 0x72, 0x72, 0x65, 0x6e,
-	0x74, 0x43, 0x68, 0x61, 0x70, 0x74, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x78, 0x0a, 0x0e, 0x47,
-	0x65, 0x74, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x12, 0x26, 0x0a,
-	0x0e, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x43, 0x6f,
-	0x6e, 0x74, 0x65, 0x6e, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72,
-	0x49, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65,
-	0x72, 0x49, 0x64, 0x12, 0x20, 0x0a, 0x0b, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x4e, 0x61,
-	0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65,
-	0x72, 0x4e, 0x61, 0x6d, 0x65, 0x32, 0xf9, 0x01, 0x0a, 0x10, 0x47, 0x6f, 0x4c, 0x69, 0x74, 0x65,
-	0x62, 0x6f, 0x6f, 0x6b, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x12, 0x29, 0x0a, 0x06, 0x53, 0x65,
-	0x61, 0x72, 0x63, 0x68, 0x12, 0x0e, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x53, 0x65, 0x61, 0x72, 0x63,
-	0x68, 0x52, 0x65, 0x71, 0x1a, 0x0f, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x53, 0x65, 0x61, 0x72, 0x63,
-	0x68, 0x52, 0x65, 0x73, 0x70, 0x12, 0x26, 0x0a, 0x07, 0x47, 0x65, 0x74, 0x44, 0x65, 0x73, 0x63,
-	0x12, 0x0a, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0f, 0x2e, 0x61,
-	0x70, 0x69, 0x2e, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x44, 0x65, 0x73, 0x63, 0x12, 0x38, 0x0a,
-	0x0b, 0x47, 0x65, 0x74, 0x42, 0x6f, 0x6f, 0x6b, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x13, 0x2e, 0x61,
-	0x70, 0x69, 0x2e, 0x47, 0x65, 0x74, 0x42, 0x6f, 0x6f, 0x6b, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65,
-	0x71, 0x1a, 0x14, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x47, 0x65, 0x74, 0x42, 0x6f, 0x6f, 0x6b, 0x49,
-	0x6e, 0x66, 0x6f, 0x52, 0x65, 0x73, 0x70, 0x12, 0x31, 0x0a, 0x07, 0x47, 0x65, 0x74, 0x42, 0x6f,
-	0x6f, 0x6b, 0x12, 0x0f, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x47, 0x65, 0x74, 0x42, 0x6f, 0x6f, 0x6b,
-	0x52, 0x65, 0x71, 0x1a, 0x13, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x47, 0x65, 0x74, 0x43, 0x68, 0x61,
-	0x70, 0x74, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x30, 0x01, 0x12, 0x25, 0x0a, 0x09, 0x4b, 0x65,
-	0x65, 0x70, 0x61, 0x6c, 0x69, 0x76, 0x65, 0x12, 0x0a, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x45, 0x6d,
-	0x70, 0x74, 0x79, 0x1a, 0x0a, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x28,
-	0x01, 0x42, 0x10, 0x5a, 0x0e, 0x67, 0x6f, 0x6c, 0x69, 0x74, 0x65, 0x62, 0x6f, 0x6f, 0x6b, 0x2f,
-	0x61, 0x70, 0x69, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x74, 0x43, 0x68, 0x61, 0x70, 0x74, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0xa7, 0x01, 0x0a, 0x08,
+	0x4d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x4e, 0x61, 0x6d, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07,
+	0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x56,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x44, 0x6f, 0x6d, 0x61, 0x69, 0x6e,
+	0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x44, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x73,
+	0x12, 0x1e, 0x0a, 0x0a, 0x41, 0x50, 0x49, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x41, 0x50, 0x49, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x12, 0x33, 0x0a, 0x0c, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73,
+	0x18, 0x05, 0x20, 0x03, 0x28, 0x0e, 0x32, 0x0f, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x43, 0x61, 0x70,
+	0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x52, 0x0c, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c,
+	0x69, 0x74, 0x69, 0x65, 0x73, 0x22, 0x35, 0x0a, 0x0f, 0x4c, 0x69, 0x73, 0x74, 0x43, 0x68, 0x61,
+	0x70, 0x74, 0x65, 0x72, 0x73, 0x52, 0x65, 0x71, 0x12, 0x22, 0x0a, 0x0c, 0x42, 0x6f, 0x6f, 0x6b,
+	0x49, 0x6e, 0x64, 0x65, 0x78, 0x55, 0x52, 0x4c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c,
+	0x42, 0x6f, 0x6f, 0x6b, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x55, 0x52, 0x4c, 0x22, 0x6d, 0x0a, 0x0b,
+	0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x1c, 0x0a, 0x09, 0x43,
+	0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09,
+	0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x49, 0x64, 0x12, 0x20, 0x0a, 0x0b, 0x43, 0x68, 0x61,
+	0x70, 0x74, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b,
+	0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x43,
+	0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0a, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x55, 0x52, 0x4c, 0x22, 0x40, 0x0a, 0x10, 0x4c,
+	0x69, 0x73, 0x74, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x73, 0x52, 0x65, 0x73, 0x70, 0x12,
+	0x2c, 0x0a, 0x08, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x10, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x49,
+	0x6e, 0x66, 0x6f, 0x52, 0x08, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x73, 0x22, 0x8c, 0x01,
+	0x0a, 0x08, 0x42, 0x6f, 0x6f, 0x6b, 0x4d, 0x65, 0x74, 0x61, 0x12, 0x14, 0x0a, 0x05, 0x43, 0x6f,
+	0x76, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x43, 0x6f, 0x76, 0x65, 0x72,
+	0x12, 0x1c, 0x0a, 0x09, 0x43, 0x6f, 0x76, 0x65, 0x72, 0x54, 0x79, 0x70, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x09, 0x43, 0x6f, 0x76, 0x65, 0x72, 0x54, 0x79, 0x70, 0x65, 0x12, 0x1a,
+	0x0a, 0x08, 0x53, 0x79, 0x6e, 0x6f, 0x70, 0x73, 0x69, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x08, 0x53, 0x79, 0x6e, 0x6f, 0x70, 0x73, 0x69, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x61,
+	0x67, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x54, 0x61, 0x67, 0x73, 0x12, 0x1c,
+	0x0a, 0x09, 0x57, 0x6f, 0x72, 0x64, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x09, 0x57, 0x6f, 0x72, 0x64, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x78, 0x0a, 0x0e,
+	0x47, 0x65, 0x74, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x12, 0x26,
+	0x0a, 0x0e, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x43,
+	0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65,
+	0x72, 0x49, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x43, 0x68, 0x61, 0x70, 0x74,
+	0x65, 0x72, 0x49, 0x64, 0x12, 0x20, 0x0a, 0x0b, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x4e,
+	0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x43, 0x68, 0x61, 0x70, 0x74,
+	0x65, 0x72, 0x4e, 0x61, 0x6d, 0x65, 0x2a, 0x6b, 0x0a, 0x0a, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69,
+	0x6c, 0x69, 0x74, 0x79, 0x12, 0x0b, 0x0a, 0x07, 0x43, 0x61, 0x70, 0x4e, 0x6f, 0x6e, 0x65, 0x10,
+	0x00, 0x12, 0x0d, 0x0a, 0x09, 0x43, 0x61, 0x70, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x10, 0x01,
+	0x12, 0x11, 0x0a, 0x0d, 0x43, 0x61, 0x70, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x4f, 0x6e, 0x6c,
+	0x79, 0x10, 0x02, 0x12, 0x0c, 0x0a, 0x08, 0x43, 0x61, 0x70, 0x43, 0x6f, 0x76, 0x65, 0x72, 0x10,
+	0x03, 0x12, 0x0b, 0x0a, 0x07, 0x43, 0x61, 0x70, 0x41, 0x75, 0x74, 0x68, 0x10, 0x04, 0x12, 0x13,
+	0x0a, 0x0f, 0x43, 0x61, 0x70, 0x4c, 0x69, 0x73, 0x74, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72,
+	0x73, 0x10, 0x05, 0x32, 0x93, 0x03, 0x0a, 0x10, 0x47, 0x6f, 0x4c, 0x69, 0x74, 0x65, 0x62, 0x6f,
+	0x6f, 0x6b, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x12, 0x29, 0x0a, 0x06, 0x53, 0x65, 0x61, 0x72,
+	0x63, 0x68, 0x12, 0x0e, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52,
+	0x65, 0x71, 0x1a, 0x0f, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52,
+	0x65, 0x73, 0x70, 0x12, 0x26, 0x0a, 0x07, 0x47, 0x65, 0x74, 0x44, 0x65, 0x73, 0x63, 0x12, 0x0a,
+	0x2e, 0x61, 0x70, 0x69, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0f, 0x2e, 0x61, 0x70, 0x69,
+	0x2e, 0x50, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x44, 0x65, 0x73, 0x63, 0x12, 0x38, 0x0a, 0x0b, 0x47,
+	0x65, 0x74, 0x42, 0x6f, 0x6f, 0x6b, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x13, 0x2e, 0x61, 0x70, 0x69,
+	0x2e, 0x47, 0x65, 0x74, 0x42, 0x6f, 0x6f, 0x6b, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71, 0x1a,
+	0x14, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x47, 0x65, 0x74, 0x42, 0x6f, 0x6f, 0x6b, 0x49, 0x6e, 0x66,
+	0x6f, 0x52, 0x65, 0x73, 0x70, 0x12, 0x31, 0x0a, 0x07, 0x47, 0x65, 0x74, 0x42, 0x6f, 0x6f, 0x6b,
+	0x12, 0x0f, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x47, 0x65, 0x74, 0x42, 0x6f, 0x6f, 0x6b, 0x52, 0x65,
+	0x71, 0x1a, 0x13, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x47, 0x65, 0x74, 0x43, 0x68, 0x61, 0x70, 0x74,
+	0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x30, 0x01, 0x12, 0x25, 0x0a, 0x09, 0x4b, 0x65, 0x65, 0x70,
+	0x61, 0x6c, 0x69, 0x76, 0x65, 0x12, 0x0a, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x45, 0x6d, 0x70, 0x74,
+	0x79, 0x1a, 0x0a, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x28, 0x01, 0x12,
+	0x28, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x4d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73, 0x74, 0x12, 0x0a,
+	0x2e, 0x61, 0x70, 0x69, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x0d, 0x2e, 0x61, 0x70, 0x69,
+	0x2e, 0x4d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73, 0x74, 0x12, 0x3b, 0x0a, 0x0c, 0x4c, 0x69, 0x73,
+	0x74, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x73, 0x12, 0x14, 0x2e, 0x61, 0x70, 0x69, 0x2e,
+	0x4c, 0x69, 0x73, 0x74, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65, 0x72, 0x73, 0x52, 0x65, 0x71, 0x1a,
+	0x15, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x43, 0x68, 0x61, 0x70, 0x74, 0x65,
+	0x72, 0x73, 0x52, 0x65, 0x73, 0x70, 0x12, 0x31, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x42, 0x6f, 0x6f,
+	0x6b, 0x4d, 0x65, 0x74, 0x61, 0x12, 0x13, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x47, 0x65, 0x74, 0x42,
+	0x6f, 0x6f, 0x6b, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71, 0x1a, 0x0d, 0x2e, 0x61, 0x70, 0x69,
+	0x2e, 0x42, 0x6f, 0x6f, 0x6b, 0x4d, 0x65, 0x74, 0x61, 0x42, 0x10, 0x5a, 0x0e, 0x67, 0x6f, 0x6c,
+	0x69, 0x74, 0x65, 0x62, 0x6f, 0x6f, 0x6b, 0x2f, 0x61, 0x70, 0x69, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
 }
 
 var (
@@ -619,37 +1053,52 @@ func file_api_proto_rawDescGZIP() []byte {
 	return file_api_proto_rawDescData
 }
 
-var file_api_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_api_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_api_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
 var file_api_proto_goTypes = []interface{}{
-	(*Empty)(nil),                 // 0: api.Empty
-	(*SearchReq)(nil),             // 1: api.SearchReq
-	(*SearchBookResp)(nil),        // 2: api.SearchBookResp
-	(*SearchResp)(nil),            // 3: api.SearchResp
-	(*PluginDesc)(nil),            // 4: api.PluginDesc
-	(*GetBookInfoReq)(nil),        // 5: api.GetBookInfoReq
-	(*GetBookInfoResp)(nil),       // 6: api.GetBookInfoResp
-	(*GetBookReq)(nil),            // 7: api.GetBookReq
-	(*GetChapterResp)(nil),        // 8: api.GetChapterResp
-	(*timestamppb.Timestamp)(nil), // 9: google.protobuf.Timestamp
+	(Capability)(0),               // 0: api.Capability
+	(*Empty)(nil),                 // 1: api.Empty
+	(*SearchReq)(nil),             // 2: api.SearchReq
+	(*SearchBookResp)(nil),        // 3: api.SearchBookResp
+	(*SearchResp)(nil),            // 4: api.SearchResp
+	(*PluginDesc)(nil),            // 5: api.PluginDesc
+	(*GetBookInfoReq)(nil),        // 6: api.GetBookInfoReq
+	(*GetBookInfoResp)(nil),       // 7: api.GetBookInfoResp
+	(*GetBookReq)(nil),            // 8: api.GetBookReq
+	(*Manifest)(nil),              // 9: api.Manifest
+	(*ListChaptersReq)(nil),       // 10: api.ListChaptersReq
+	(*ChapterInfo)(nil),           // 11: api.ChapterInfo
+	(*ListChaptersResp)(nil),      // 12: api.ListChaptersResp
+	(*BookMeta)(nil),              // 13: api.BookMeta
+	(*GetChapterResp)(nil),        // 14: api.GetChapterResp
+	(*timestamppb.Timestamp)(nil), // 15: google.protobuf.Timestamp
 }
 var file_api_proto_depIdxs = []int32{
-	9, // 0: api.SearchBookResp.LastUpdate:type_name -> google.protobuf.Timestamp
-	2, // 1: api.SearchResp.ResultList:type_name -> api.SearchBookResp
-	1, // 2: api.GoLitebookPlugin.Search:input_type -> api.SearchReq
-	0, // 3: api.GoLitebookPlugin.GetDesc:input_type -> api.Empty
-	5, // 4: api.GoLitebookPlugin.GetBookInfo:input_type -> api.GetBookInfoReq
-	7, // 5: api.GoLitebookPlugin.GetBook:input_type -> api.GetBookReq
-	0, // 6: api.GoLitebookPlugin.Keepalive:input_type -> api.Empty
-	3, // 7: api.GoLitebookPlugin.Search:output_type -> api.SearchResp
-	4, // 8: api.GoLitebookPlugin.GetDesc:output_type -> api.PluginDesc
-	6, // 9: api.GoLitebookPlugin.GetBookInfo:output_type -> api.GetBookInfoResp
-	8, // 10: api.GoLitebookPlugin.GetBook:output_type -> api.GetChapterResp
-	0, // 11: api.GoLitebookPlugin.Keepalive:output_type -> api.Empty
-	7, // [7:12] is the sub-list for method output_type
-	2, // [2:7] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	15, // 0: api.SearchBookResp.LastUpdate:type_name -> google.protobuf.Timestamp
+	3,  // 1: api.SearchResp.ResultList:type_name -> api.SearchBookResp
+	0,  // 2: api.Manifest.Capabilities:type_name -> api.Capability
+	11, // 3: api.ListChaptersResp.Chapters:type_name -> api.ChapterInfo
+	2,  // 4: api.GoLitebookPlugin.Search:input_type -> api.SearchReq
+	1,  // 5: api.GoLitebookPlugin.GetDesc:input_type -> api.Empty
+	6,  // 6: api.GoLitebookPlugin.GetBookInfo:input_type -> api.GetBookInfoReq
+	8,  // 7: api.GoLitebookPlugin.GetBook:input_type -> api.GetBookReq
+	1,  // 8: api.GoLitebookPlugin.Keepalive:input_type -> api.Empty
+	1,  // 9: api.GoLitebookPlugin.GetManifest:input_type -> api.Empty
+	10, // 10: api.GoLitebookPlugin.ListChapters:input_type -> api.ListChaptersReq
+	6,  // 11: api.GoLitebookPlugin.GetBookMeta:input_type -> api.GetBookInfoReq
+	4,  // 12: api.GoLitebookPlugin.Search:output_type -> api.SearchResp
+	5,  // 13: api.GoLitebookPlugin.GetDesc:output_type -> api.PluginDesc
+	7,  // 14: api.GoLitebookPlugin.GetBookInfo:output_type -> api.GetBookInfoResp
+	14, // 15: api.GoLitebookPlugin.GetBook:output_type -> api.GetChapterResp
+	1,  // 16: api.GoLitebookPlugin.Keepalive:output_type -> api.Empty
+	9,  // 17: api.GoLitebookPlugin.GetManifest:output_type -> api.Manifest
+	12, // 18: api.GoLitebookPlugin.ListChapters:output_type -> api.ListChaptersResp
+	13, // 19: api.GoLitebookPlugin.GetBookMeta:output_type -> api.BookMeta
+	12, // [12:20] is the sub-list for method output_type
+	4,  // [4:12] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_api_proto_init() }
@@ -755,6 +1204,66 @@ func file_api_proto_init() {
 			}
 		}
 		file_api_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Manifest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListChaptersReq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ChapterInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListChaptersResp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BookMeta); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*GetChapterResp); i {
 			case 0:
 				return &v.state
@@ -772,13 +1281,14 @@ func file_api_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_api_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   9,
+			NumEnums:      1,
+			NumMessages:   14,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_api_proto_goTypes,
 		DependencyIndexes: file_api_proto_depIdxs,
+		EnumInfos:         file_api_proto_enumTypes,
 		MessageInfos:      file_api_proto_msgTypes,
 	}.Build()
 	File_api_proto = out.File
@@ -22,6 +22,9 @@ type GoLitebookPluginClient interface {
 	GetBookInfo(ctx context.Context, in *GetBookInfoReq, opts ...grpc.CallOption) (*GetBookInfoResp, error)
 	GetBook(ctx context.Context, in *GetBookReq, opts ...grpc.CallOption) (GoLitebookPlugin_GetBookClient, error)
 	Keepalive(ctx context.Context, opts ...grpc.CallOption) (GoLitebookPlugin_KeepaliveClient, error)
+	GetManifest(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Manifest, error)
+	ListChapters(ctx context.Context, in *ListChaptersReq, opts ...grpc.CallOption) (*ListChaptersResp, error)
+	GetBookMeta(ctx context.Context, in *GetBookInfoReq, opts ...grpc.CallOption) (*BookMeta, error)
 }
 
 type goLitebookPluginClient struct {
@@ -125,6 +128,33 @@ func (x *goLitebookPluginKeepaliveClient) CloseAndRecv() (*Empty, error) {
 	return m, nil
 }
 
+func (c *goLitebookPluginClient) GetManifest(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Manifest, error) {
+	out := new(Manifest)
+	err := c.cc.Invoke(ctx, "/api.GoLitebookPlugin/GetManifest", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLitebookPluginClient) ListChapters(ctx context.Context, in *ListChaptersReq, opts ...grpc.CallOption) (*ListChaptersResp, error) {
+	out := new(ListChaptersResp)
+	err := c.cc.Invoke(ctx, "/api.GoLitebookPlugin/ListChapters", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goLitebookPluginClient) GetBookMeta(ctx context.Context, in *GetBookInfoReq, opts ...grpc.CallOption) (*BookMeta, error) {
+	out := new(BookMeta)
+	err := c.cc.Invoke(ctx, "/api.GoLitebookPlugin/GetBookMeta", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GoLitebookPluginServer is the server API for GoLitebookPlugin service.
 // All implementations must embed UnimplementedGoLitebookPluginServer
 // for forward compatibility
@@ -134,6 +164,9 @@ type GoLitebookPluginServer interface {
 	GetBookInfo(context.Context, *GetBookInfoReq) (*GetBookInfoResp, error)
 	GetBook(*GetBookReq, GoLitebookPlugin_GetBookServer) error
 	Keepalive(GoLitebookPlugin_KeepaliveServer) error
+	GetManifest(context.Context, *Empty) (*Manifest, error)
+	ListChapters(context.Context, *ListChaptersReq) (*ListChaptersResp, error)
+	GetBookMeta(context.Context, *GetBookInfoReq) (*BookMeta, error)
 	mustEmbedUnimplementedGoLitebookPluginServer()
 }
 
@@ -156,6 +189,15 @@ func (UnimplementedGoLitebookPluginServer) GetBook(*GetBookReq, GoLitebookPlugin
 func (UnimplementedGoLitebookPluginServer) Keepalive(GoLitebookPlugin_KeepaliveServer) error {
 	return status.Errorf(codes.Unimplemented, "method Keepalive not implemented")
 }
+func (UnimplementedGoLitebookPluginServer) GetManifest(context.Context, *Empty) (*Manifest, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetManifest not implemented")
+}
+func (UnimplementedGoLitebookPluginServer) ListChapters(context.Context, *ListChaptersReq) (*ListChaptersResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListChapters not implemented")
+}
+func (UnimplementedGoLitebookPluginServer) GetBookMeta(context.Context, *GetBookInfoReq) (*BookMeta, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBookMeta not implemented")
+}
 func (UnimplementedGoLitebookPluginServer) mustEmbedUnimplementedGoLitebookPluginServer() {}
 
 // UnsafeGoLitebookPluginServer may be embedded to opt out of forward compatibility for this service.
@@ -270,6 +312,60 @@ func (x *goLitebookPluginKeepaliveServer) Recv() (*Empty, error) {
 	return m, nil
 }
 
+func _GoLitebookPlugin_GetManifest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLitebookPluginServer).GetManifest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.GoLitebookPlugin/GetManifest",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLitebookPluginServer).GetManifest(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLitebookPlugin_ListChapters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListChaptersReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLitebookPluginServer).ListChapters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.GoLitebookPlugin/ListChapters",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLitebookPluginServer).ListChapters(ctx, req.(*ListChaptersReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoLitebookPlugin_GetBookMeta_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBookInfoReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoLitebookPluginServer).GetBookMeta(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.GoLitebookPlugin/GetBookMeta",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoLitebookPluginServer).GetBookMeta(ctx, req.(*GetBookInfoReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _GoLitebookPlugin_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "api.GoLitebookPlugin",
 	HandlerType: (*GoLitebookPluginServer)(nil),
@@ -286,6 +382,18 @@ var _GoLitebookPlugin_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetBookInfo",
 			Handler:    _GoLitebookPlugin_GetBookInfo_Handler,
 		},
+		{
+			MethodName: "GetManifest",
+			Handler:    _GoLitebookPlugin_GetManifest_Handler,
+		},
+		{
+			MethodName: "ListChapters",
+			Handler:    _GoLitebookPlugin_ListChapters_Handler,
+		},
+		{
+			MethodName: "GetBookMeta",
+			Handler:    _GoLitebookPlugin_GetBookMeta_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
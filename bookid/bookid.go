@@ -0,0 +1,163 @@
+// Package bookid identifies books by a fingerprint of their content,
+// so per-book settings are not shared by books with the same file name and survive renaming;
+// the path of a book is kept as a hint to find it after its content is changed, e.g. new chapters are downloaded
+package bookid
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hujun-open/golitebook/conf"
+)
+
+func getBooksFilePath() string {
+	return filepath.Join(conf.ConfDir(), "books")
+}
+
+// sampleSize is the number of bytes read from the head, middle and tail of a big book for its fingerprint
+const sampleSize = 64 * 1024
+
+// Fingerprint returns the fingerprint of the content in r with size bytes,
+// a big book is only sampled so it is fast
+func Fingerprint(r io.ReaderAt, size int64) (string, error) {
+	h := sha1.New()
+	fmt.Fprintf(h, "%d:", size)
+	offsets := []int64{0}
+	n := size
+	if size > 3*sampleSize {
+		offsets = []int64{0, size/2 - sampleSize/2, size - sampleSize}
+		n = sampleSize
+	}
+	buf := make([]byte, n)
+	for _, off := range offsets {
+		if _, err := r.ReadAt(buf, off); err != nil && err != io.EOF {
+			return "", err
+		}
+		h.Write(buf)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// FingerprintBytes returns the fingerprint of buf
+func FingerprintBytes(buf []byte) string {
+	r, _ := Fingerprint(bytes.NewReader(buf), int64(len(buf)))
+	return r
+}
+
+// FingerprintFile returns the fingerprint of file at path
+func FingerprintFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	return Fingerprint(f, fi.Size())
+}
+
+// Book is a known book
+type Book struct {
+	// Path is where the book is last opened
+	Path       string
+	Name       string
+	LastOpened time.Time
+}
+
+// Registry is the known books, key is the fingerprint
+type Registry struct {
+	books map[string]*Book
+	mux   *sync.RWMutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		books: make(map[string]*Book),
+		mux:   new(sync.RWMutex),
+	}
+}
+
+// Resolve returns the id of the book with fingerprint fp at path, which is fp, and prev,
+// the id its per-book settings were saved with if it is not fp:
+// an unknown fp at a known path means content of the book is changed, prev is the old fingerprint;
+// an unknown book could have settings saved by older version, prev is name, the base filename;
+// the book is recorded as opened, use Lookup to get the ids without recording
+func (reg *Registry) Resolve(fp, path, name string) (id, prev string) {
+	reg.mux.Lock()
+	defer reg.mux.Unlock()
+	id, prev = reg.lookup(fp, path, name)
+	if _, ok := reg.books[prev]; ok {
+		delete(reg.books, prev)
+	}
+	reg.books[fp] = &Book{Path: path, Name: name, LastOpened: time.Now()}
+	return id, prev
+}
+
+// Lookup returns the same ids as Resolve without changing reg
+func (reg *Registry) Lookup(fp, path, name string) (id, prev string) {
+	reg.mux.RLock()
+	defer reg.mux.RUnlock()
+	return reg.lookup(fp, path, name)
+}
+
+// lookup is Lookup, caller must hold reg.mux
+func (reg *Registry) lookup(fp, path, name string) (id, prev string) {
+	if _, ok := reg.books[fp]; ok {
+		return fp, ""
+	}
+	for oldfp, b := range reg.books {
+		if b.Path == path {
+			return fp, oldfp
+		}
+	}
+	return fp, name
+}
+
+// Get returns the book with id, false if it is unknown
+func (reg *Registry) Get(id string) (Book, bool) {
+	reg.mux.RLock()
+	defer reg.mux.RUnlock()
+	b, ok := reg.books[id]
+	if !ok {
+		return Book{}, false
+	}
+	return *b, true
+}
+
+func (reg *Registry) Save() error {
+	reg.mux.RLock()
+	defer reg.mux.RUnlock()
+	buf, err := json.MarshalIndent(reg.books, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(getBooksFilePath(), buf, 0644)
+}
+
+func (reg *Registry) Load() error {
+	buf, err := ioutil.ReadFile(getBooksFilePath())
+	if err != nil {
+		return err
+	}
+	reg.mux.Lock()
+	defer reg.mux.Unlock()
+	return json.Unmarshal(buf, &reg.books)
+}
+
+var Books *Registry
+
+func init() {
+	Books = NewRegistry()
+	Books.Load()
+}
@@ -0,0 +1,79 @@
+package bookid
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	small := []byte("第一章 开始\n内容\n")
+	if FingerprintBytes(small) != FingerprintBytes(append([]byte{}, small...)) {
+		t.Fatal("same content has different fingerprints")
+	}
+	if FingerprintBytes(small) == FingerprintBytes([]byte("第一章 开始\n内容!\n")) {
+		t.Fatal("different content has the same fingerprint")
+	}
+	big := bytes.Repeat([]byte("0123456789"), sampleSize)
+	fname := filepath.Join(t.TempDir(), "1.txt")
+	if err := ioutil.WriteFile(fname, big, 0644); err != nil {
+		t.Fatal(err)
+	}
+	fp, err := FingerprintFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp != FingerprintBytes(big) {
+		t.Fatal("fingerprint of file is different from the one of its content")
+	}
+	changed := append([]byte{}, big...)
+	changed[len(changed)-1] = 'x'
+	if FingerprintBytes(changed) == fp {
+		t.Fatal("changed tail is not in fingerprint")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	os.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+	reg := NewRegistry()
+	// new book may have settings saved by its name
+	if id, prev := reg.Resolve("fp1", "/a/1.txt", "1.txt"); id != "fp1" || prev != "1.txt" {
+		t.Fatalf("unexpected id %v and prev %v of new book", id, prev)
+	}
+	// same name in another folder is another book
+	if id, prev := reg.Resolve("fp2", "/b/1.txt", "1.txt"); id != "fp2" || prev != "1.txt" {
+		t.Fatalf("unexpected id %v and prev %v of another book", id, prev)
+	}
+	// renamed book is found by fingerprint
+	if id, prev := reg.Resolve("fp1", "/a/2.txt", "2.txt"); id != "fp1" || prev != "" {
+		t.Fatalf("unexpected id %v and prev %v of renamed book", id, prev)
+	}
+	// lookup doesn't record the book
+	if id, prev := reg.Lookup("fp3", "/a/2.txt", "2.txt"); id != "fp3" || prev != "fp1" {
+		t.Fatalf("unexpected id %v and prev %v of looked up book", id, prev)
+	}
+	if _, ok := reg.Get("fp3"); ok {
+		t.Fatal("looked up book is recorded")
+	}
+	// changed book is found by path
+	if id, prev := reg.Resolve("fp3", "/a/2.txt", "2.txt"); id != "fp3" || prev != "fp1" {
+		t.Fatalf("unexpected id %v and prev %v of changed book", id, prev)
+	}
+	if _, ok := reg.Get("fp1"); ok {
+		t.Fatal("old fingerprint is not removed")
+	}
+	os.MkdirAll(filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "litebook"), 0755)
+	if err := reg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewRegistry()
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := loaded.Get("fp3"); !ok || b.Path != "/a/2.txt" || b.Name != "2.txt" {
+		t.Fatalf("unexpected loaded book %+v", b)
+	}
+}
@@ -0,0 +1,196 @@
+// bookmark
+package bookmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hujun-open/golitebook/conf"
+	"github.com/hujun-open/golitebook/history"
+)
+
+func getBookmarkFilePath() string {
+	return filepath.Join(conf.ConfDir(), "bookmarks")
+}
+
+type Bookmark struct {
+	Book    string
+	Line    int
+	LinePos int
+	// Anchor is the text at the bookmark, see history.Position
+	Anchor  string `json:",omitempty"`
+	Note    string
+	Created time.Time
+}
+
+// Position returns the position of b, use its Locate to find b after lines of the book are changed
+func (b *Bookmark) Position() history.Position {
+	return history.Position{Line: b.Line, LinePos: b.LinePos, Anchor: b.Anchor}
+}
+
+// BookmarkList is list of bookmarks of a single book
+type BookmarkList []*Bookmark
+
+func (blist BookmarkList) Len() int {
+	return len(blist)
+}
+func (blist BookmarkList) Fields() []string {
+	return []string{"备注", "行", "创建时间"}
+}
+func (blist BookmarkList) Item(id int) []string {
+	if id < 0 || id >= blist.Len() {
+		return nil
+	}
+	return []string{
+		blist[id].Note,
+		fmt.Sprintf("%d", blist[id].Line+1),
+		blist[id].Created.Format("2006-01-02 15:04:05"),
+	}
+}
+func (blist BookmarkList) Sort(field int, ascend bool) {
+	less := func(i, j int) bool {
+		switch field {
+		case 0:
+			return blist[i].Note < blist[j].Note
+		case 2:
+			return blist[i].Created.Before(blist[j].Created)
+		}
+		if blist[i].Line != blist[j].Line {
+			return blist[i].Line < blist[j].Line
+		}
+		return blist[i].LinePos < blist[j].LinePos
+	}
+	sort.SliceStable(blist, func(i, j int) bool {
+		if ascend {
+			return less(i, j)
+		}
+		return less(j, i)
+	})
+}
+func (blist BookmarkList) Filter(kw string, i int) {
+}
+
+// BookmarkStore key is the id of book
+type BookmarkStore struct {
+	books map[string]BookmarkList
+	mux   *sync.RWMutex
+}
+
+func NewBookmarkStore() *BookmarkStore {
+	return &BookmarkStore{
+		books: make(map[string]BookmarkList),
+		mux:   new(sync.RWMutex),
+	}
+}
+
+// Add adds a new bookmark at pos and returns a copy of it, bookmarks of a book are kept in reading order
+func (store *BookmarkStore) Add(book string, pos history.Position, note string) *Bookmark {
+	store.mux.Lock()
+	defer store.mux.Unlock()
+	b := &Bookmark{
+		Book:    book,
+		Line:    pos.Line,
+		LinePos: pos.LinePos,
+		Anchor:  pos.Anchor,
+		Note:    note,
+		Created: time.Now(),
+	}
+	store.books[book] = append(store.books[book], b)
+	store.books[book].Sort(1, true)
+	r := *b
+	return &r
+}
+
+// Get returns copies of bookmarks of book, changes are made via the store
+func (store *BookmarkStore) Get(book string) BookmarkList {
+	store.mux.RLock()
+	defer store.mux.RUnlock()
+	r := make(BookmarkList, len(store.books[book]))
+	for i, b := range store.books[book] {
+		c := *b
+		r[i] = &c
+	}
+	return r
+}
+
+// find returns the index of stored bookmark b is a copy of, -1 if not found; caller must hold the lock
+func (store *BookmarkStore) find(b *Bookmark) int {
+	for i, sb := range store.books[b.Book] {
+		if sb.Line == b.Line && sb.LinePos == b.LinePos && sb.Created.Equal(b.Created) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Remove removes the stored bookmark b is a copy of
+func (store *BookmarkStore) Remove(b *Bookmark) {
+	store.mux.Lock()
+	defer store.mux.Unlock()
+	if i := store.find(b); i >= 0 {
+		blist := store.books[b.Book]
+		store.books[b.Book] = append(blist[:i], blist[i+1:]...)
+	}
+	if len(store.books[b.Book]) == 0 {
+		delete(store.books, b.Book)
+	}
+}
+
+// Rename changes note of the stored bookmark b is a copy of
+func (store *BookmarkStore) Rename(b *Bookmark, note string) {
+	store.mux.Lock()
+	defer store.mux.Unlock()
+	if i := store.find(b); i >= 0 {
+		store.books[b.Book][i].Note = note
+	}
+}
+
+// RenameBook moves the bookmarks of book old to book new, they are merged with the existing ones of new
+func (store *BookmarkStore) RenameBook(old, new string) {
+	store.mux.Lock()
+	defer store.mux.Unlock()
+	blist, ok := store.books[old]
+	if !ok || old == new {
+		return
+	}
+	for _, b := range blist {
+		b.Book = new
+		if store.find(b) < 0 {
+			store.books[new] = append(store.books[new], b)
+		}
+	}
+	store.books[new].Sort(1, true)
+	delete(store.books, old)
+}
+
+func (store *BookmarkStore) Save() error {
+	store.mux.RLock()
+	defer store.mux.RUnlock()
+	buf, err := json.MarshalIndent(store.books, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(getBookmarkFilePath(), buf, 0644)
+}
+
+func (store *BookmarkStore) Load() error {
+	buf, err := ioutil.ReadFile(getBookmarkFilePath())
+	if err != nil {
+		return err
+	}
+	store.mux.Lock()
+	defer store.mux.Unlock()
+	return json.Unmarshal(buf, &store.books)
+}
+
+var Bookmarks *BookmarkStore
+
+func init() {
+	Bookmarks = NewBookmarkStore()
+	Bookmarks.Load()
+}
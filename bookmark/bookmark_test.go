@@ -0,0 +1,75 @@
+// bookmark_test
+package bookmark
+
+import (
+	"testing"
+
+	"github.com/hujun-open/golitebook/history"
+	"github.com/hujun-open/golitebook/liteview"
+)
+
+func TestBookmarkStore(t *testing.T) {
+	store := NewBookmarkStore()
+	store.Add("book1", history.Position{Line: 20}, "second")
+	first := store.Add("book1", history.Position{Line: 10, LinePos: 5}, "first")
+	store.Add("book2", history.Position{Line: 1}, "other")
+	blist := store.Get("book1")
+	if len(blist) != 2 || *blist[0] != *first {
+		t.Fatalf("bookmarks of book1 not in reading order, %v", blist)
+	}
+	store.Rename(first, "renamed")
+	if store.Get("book1")[0].Note != "renamed" {
+		t.Fatal("failed to rename bookmark")
+	}
+	if first.Note != "first" {
+		t.Fatal("returned bookmark is not a copy")
+	}
+	store.Remove(first)
+	if blist = store.Get("book1"); len(blist) != 1 || blist[0].Note != "second" {
+		t.Fatalf("failed to remove bookmark, %v", blist)
+	}
+}
+
+func TestRenameBook(t *testing.T) {
+	store := NewBookmarkStore()
+	b := store.Add("1.txt", history.Position{Line: 10}, "mark")
+	store.Add("fp2", history.Position{Line: 1}, "other")
+	store.RenameBook("1.txt", "fp1")
+	if blist := store.Get("fp1"); len(blist) != 1 || blist[0].Note != b.Note || blist[0].Book != "fp1" {
+		t.Fatalf("bookmarks are not moved, %v", blist)
+	}
+	if len(store.Get("1.txt")) != 0 {
+		t.Fatal("bookmarks of old book are not removed")
+	}
+	// bookmarks are merged with the existing ones of new book
+	store.RenameBook("fp1", "fp2")
+	if blist := store.Get("fp2"); len(blist) != 2 || blist[0].Note != "other" || blist[1].Note != "mark" {
+		t.Fatalf("bookmarks are not merged, %v", blist)
+	}
+	if len(store.Get("fp1")) != 0 {
+		t.Fatal("bookmarks of old book are not removed")
+	}
+}
+
+func TestSortEqualNotes(t *testing.T) {
+	blist := BookmarkList{
+		{Note: "a", Line: 1},
+		{Note: "a", Line: 2},
+		{Note: "b", Line: 3},
+	}
+	blist.Sort(0, false)
+	if blist[0].Line != 3 || blist[1].Line != 1 || blist[2].Line != 2 {
+		t.Fatalf("expect bookmarks with equal notes keep their order, got %v", blist)
+	}
+}
+
+func TestBookmarkPosition(t *testing.T) {
+	store := NewBookmarkStore()
+	orig := liteview.ByteLines{[]byte("第一章"), []byte("他推开门，外面下着雨。")}
+	store.Add("book", history.NewPosition(orig, 1, 5), "rain")
+	// a line is inserted before the bookmark
+	changed := liteview.ByteLines{[]byte("第一章"), []byte(""), []byte("他推开门，外面下着雨。")}
+	if line, pos := store.Get("book")[0].Position().Locate(changed); line != 2 || pos != 5 {
+		t.Fatalf("expect bookmark at 2:5, got %d:%d", line, pos)
+	}
+}
@@ -0,0 +1,112 @@
+package bookmark
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/hujun-open/dvlist"
+)
+
+type GOTOBookmarkHandler func(b *Bookmark)
+
+// BookmarkDialog is the window managing bookmarks of current book
+type BookmarkDialog struct {
+	fyne.Window
+	book string
+	// bookName is shown in the title, book is the id of book
+	bookName string
+	marks    BookmarkList
+	list     *dvlist.DVList
+	h        GOTOBookmarkHandler
+}
+
+func NewBookmarkDialog(h GOTOBookmarkHandler) *BookmarkDialog {
+	r := new(BookmarkDialog)
+	r.Window = fyne.CurrentApp().NewWindow("书签")
+	r.SetCloseIntercept(func() { r.Hide() })
+	r.h = h
+	r.list, _ = dvlist.NewDVList(BookmarkList{}, dvlist.WithDoubleClickHandler(r.read))
+	buttonContainer := fyne.NewContainerWithLayout(layout.NewVBoxLayout(),
+		widget.NewSeparator(),
+		fyne.NewContainerWithLayout(layout.NewGridLayout(4),
+			widget.NewButton("跳转", r.onRead),
+			widget.NewButton("重命名", r.onRename),
+			widget.NewButton("删除", r.onDel),
+			widget.NewButton("关闭", r.Hide),
+		),
+	)
+	r.SetContent(fyne.NewContainerWithLayout(
+		layout.NewBorderLayout(nil, buttonContainer, nil, nil),
+		buttonContainer, r.list))
+	r.Resize(fyne.NewSize(600, 600))
+	r.Canvas().SetOnTypedKey(r.list.TypedKey)
+	return r
+}
+
+// Set loads bookmarks of book into the list, bookname is shown in the title
+func (bdiag *BookmarkDialog) Set(book, bookname string) {
+	bdiag.book = book
+	bdiag.bookName = bookname
+	bdiag.reload()
+}
+
+func (bdiag *BookmarkDialog) reload() {
+	bdiag.marks = Bookmarks.Get(bdiag.book)
+	bdiag.list.SetData(bdiag.marks)
+	bdiag.SetTitle(fmt.Sprintf("书签 - %v", bdiag.bookName))
+}
+
+func (bdiag *BookmarkDialog) read(i int) {
+	if i < 0 || i >= len(bdiag.marks) {
+		return
+	}
+	if bdiag.h != nil {
+		bdiag.h(bdiag.marks[i])
+	}
+	bdiag.Hide()
+}
+
+func (bdiag *BookmarkDialog) onRead() {
+	bdiag.read(bdiag.list.FirstSelected())
+}
+
+func (bdiag *BookmarkDialog) onRename() {
+	i := bdiag.list.FirstSelected()
+	if i < 0 || i >= len(bdiag.marks) {
+		return
+	}
+	b := bdiag.marks[i]
+	entry := widget.NewEntry()
+	entry.SetText(b.Note)
+	dialog.ShowForm("重命名书签", "确定", "取消",
+		[]*widget.FormItem{widget.NewFormItem("备注", entry)},
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+			Bookmarks.Rename(b, entry.Text)
+			bdiag.reload()
+		}, bdiag)
+}
+
+func (bdiag *BookmarkDialog) onDel() {
+	i := bdiag.list.FirstSelected()
+	if i < 0 || i >= len(bdiag.marks) {
+		return
+	}
+	b := bdiag.marks[i]
+	dialog.ShowConfirm("删除书签",
+		fmt.Sprintf("确定删除书签 %v?", b.Note),
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+			Bookmarks.Remove(b)
+			bdiag.reload()
+		},
+		bdiag,
+	)
+}
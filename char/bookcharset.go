@@ -0,0 +1,92 @@
+package char
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	"github.com/hujun-open/golitebook/conf"
+)
+
+func getBookCharsetFilePath() string {
+	return filepath.Join(conf.ConfDir(), "charsets")
+}
+
+// BookCharsetConf is the charsets chosen by user, key is the id of book,
+// value is the charset name; a book not in it uses detection
+type BookCharsetConf struct {
+	books map[string]string
+	mux   *sync.RWMutex
+}
+
+func NewBookCharsetConf() *BookCharsetConf {
+	return &BookCharsetConf{
+		books: make(map[string]string),
+		mux:   new(sync.RWMutex),
+	}
+}
+
+// Get returns the charset name of book, empty string means detecting
+func (bconf *BookCharsetConf) Get(book string) string {
+	bconf.mux.RLock()
+	defer bconf.mux.RUnlock()
+	return bconf.books[book]
+}
+
+// Set sets the charset of book, empty name means detecting
+func (bconf *BookCharsetConf) Set(book, name string) error {
+	if name != "" {
+		if _, ok := LookupCharset(name); !ok {
+			return fmt.Errorf("unsupported charset %v", name)
+		}
+	}
+	bconf.mux.Lock()
+	defer bconf.mux.Unlock()
+	if name == "" {
+		delete(bconf.books, book)
+	} else {
+		bconf.books[book] = name
+	}
+	return nil
+}
+
+// RenameBook moves the charset of book old to book new, unless new already has one
+func (bconf *BookCharsetConf) RenameBook(old, new string) {
+	bconf.mux.Lock()
+	defer bconf.mux.Unlock()
+	name, ok := bconf.books[old]
+	if _, exists := bconf.books[new]; !ok || exists {
+		return
+	}
+	bconf.books[new] = name
+	delete(bconf.books, old)
+}
+
+func (bconf *BookCharsetConf) Save() error {
+	bconf.mux.RLock()
+	defer bconf.mux.RUnlock()
+	buf, err := json.MarshalIndent(bconf.books, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(getBookCharsetFilePath(), buf, 0644)
+}
+
+func (bconf *BookCharsetConf) Load() error {
+	buf, err := ioutil.ReadFile(getBookCharsetFilePath())
+	if err != nil {
+		return err
+	}
+	bconf.mux.Lock()
+	defer bconf.mux.Unlock()
+	return json.Unmarshal(buf, &bconf.books)
+}
+
+var BookCharsets *BookCharsetConf
+
+func init() {
+	BookCharsets = NewBookCharsetConf()
+	BookCharsets.Load()
+}
@@ -3,14 +3,148 @@ package char
 
 import (
 	"bytes"
+	"fmt"
+	"sort"
 
 	"github.com/gogs/chardet"
 	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
 	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/encoding/traditionalchinese"
-	// "golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/encoding/unicode"
 )
 
+// Charset is a supported text encoding
+type Charset struct {
+	Name string
+	// Encoding is nil for UTF-8
+	Encoding encoding.Encoding
+}
+
+// LineSafe returns true if byte '\n' is never part of a multi-byte char,
+// so the text could be split into lines before decoding
+func (cs Charset) LineSafe() bool {
+	switch cs.Name {
+	case "UTF-16LE", "UTF-16BE":
+		return false
+	}
+	return true
+}
+
+// Charsets is all supported charsets, the first one is the default
+var Charsets = []Charset{
+	{"UTF-8", nil},
+	{"GB18030", simplifiedchinese.GB18030},
+	{"Big5", traditionalchinese.Big5},
+	{"UTF-16LE", unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)},
+	{"UTF-16BE", unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)},
+	{"Shift_JIS", japanese.ShiftJIS},
+	{"EUC-JP", japanese.EUCJP},
+	{"EUC-KR", korean.EUCKR},
+	{"Windows-1250", charmap.Windows1250},
+	{"Windows-1251", charmap.Windows1251},
+	{"Windows-1252", charmap.Windows1252},
+	{"Windows-1253", charmap.Windows1253},
+	{"Windows-1254", charmap.Windows1254},
+	{"Windows-1255", charmap.Windows1255},
+	{"Windows-1256", charmap.Windows1256},
+	{"Windows-1257", charmap.Windows1257},
+	{"Windows-1258", charmap.Windows1258},
+}
+
+// CharsetNames returns names of all supported charsets
+func CharsetNames() []string {
+	r := []string{}
+	for _, cs := range Charsets {
+		r = append(r, cs.Name)
+	}
+	return r
+}
+
+// LookupCharset returns the supported charset with name
+func LookupCharset(name string) (Charset, bool) {
+	for _, cs := range Charsets {
+		if cs.Name == name {
+			return cs, true
+		}
+	}
+	return Charset{}, false
+}
+
+// detectedNames maps charset name returned by chardet to name in Charsets
+var detectedNames = map[string]string{
+	"UTF-8":        "UTF-8",
+	"GB2312":       "GB18030",
+	"GBK":          "GB18030",
+	"GB18030":      "GB18030",
+	"Big5":         "Big5",
+	"UTF-16LE":     "UTF-16LE",
+	"UTF-16BE":     "UTF-16BE",
+	"Shift_JIS":    "Shift_JIS",
+	"EUC-JP":       "EUC-JP",
+	"EUC-KR":       "EUC-KR",
+	"windows-1250": "Windows-1250",
+	"windows-1251": "Windows-1251",
+	"windows-1252": "Windows-1252",
+	"windows-1253": "Windows-1253",
+	"windows-1254": "Windows-1254",
+	"windows-1255": "Windows-1255",
+	"windows-1256": "Windows-1256",
+	// Windows-125x is the superset of following
+	"ISO-8859-1":   "Windows-1252",
+	"ISO-8859-9":   "Windows-1254",
+	"ISO-8859-8":   "Windows-1255",
+	"ISO-8859-8-I": "Windows-1255",
+}
+
+var boms = []struct {
+	name string
+	bom  []byte
+}{
+	{"UTF-8", []byte{0xEF, 0xBB, 0xBF}},
+	{"UTF-16LE", []byte{0xFF, 0xFE}},
+	{"UTF-16BE", []byte{0xFE, 0xFF}},
+}
+
+// Detection is the result of charset detection
+type Detection struct {
+	Charset
+	// Confidence is from 0 to 100
+	Confidence int
+	// BOMLen is length of the byte order mark at the start of text
+	BOMLen int
+}
+
+func (dt Detection) String() string {
+	return fmt.Sprintf("%v (置信度 %d%%)", dt.Name, dt.Confidence)
+}
+
+// Decode returns buf without BOM in UTF-8
+func (dt Detection) Decode(buf []byte) ([]byte, error) {
+	buf = buf[dt.BOMLen:]
+	if dt.Encoding == nil {
+		return buf, nil
+	}
+	return dt.Encoding.NewDecoder().Bytes(buf)
+}
+
+// NewDetection returns the Detection of buf with charset name chosen by user
+func NewDetection(buf []byte, name string) (Detection, error) {
+	cs, ok := LookupCharset(name)
+	if !ok {
+		return Detection{}, fmt.Errorf("unsupported charset %v", name)
+	}
+	r := Detection{Charset: cs, Confidence: 100}
+	for _, b := range boms {
+		if b.name == name && bytes.HasPrefix(buf, b.bom) {
+			r.BOMLen = len(b.bom)
+		}
+	}
+	return r, nil
+}
+
 type DetChar struct {
 	*chardet.Detector
 }
@@ -21,35 +155,140 @@ func NewDetChar() *DetChar {
 	}
 }
 
-const maxBuftoDet = 100
+const (
+	// size of each sample to detect
+	sampleSize = 16 * 1024
+	// number of samples evenly taken from the text
+	sampleCount = 4
+)
 
-func (d *DetChar) ToByteList(buf []byte) ([][]byte, error) {
-	var e encoding.Encoding
-	newbuf := buf
-	if len(buf) > maxBuftoDet {
-		newbuf = buf[:maxBuftoDet]
+// samples returns up to sampleCount samples evenly taken from buf,
+// every sample except the first one starts after a line ending and ends at a line ending,
+// so no char is cut in half for line safe charsets
+func samples(buf []byte) [][]byte {
+	if len(buf) <= sampleSize*sampleCount {
+		return [][]byte{buf}
 	}
-	charsetname, err := d.DetectBest(newbuf)
-	isUTF8 := false
-	if err != nil {
-		isUTF8 = true
-	} else {
+	r := [][]byte{}
+	step := (len(buf) - sampleSize) / (sampleCount - 1)
+	for i := 0; i < sampleCount; i++ {
+		s := buf[i*step : i*step+sampleSize]
+		if i > 0 {
+			if j := bytes.IndexByte(s, '\n'); j >= 0 {
+				s = s[j+1:]
+			}
+		}
+		if j := bytes.LastIndexByte(s, '\n'); j >= 0 {
+			s = s[:j+1]
+		}
+		r = append(r, s)
+	}
+	return r
+}
 
-		switch charsetname.Charset {
-		case "GB2312", "GBK", "GB18030":
-			e = simplifiedchinese.GB18030
-		case "Big5":
-			e = traditionalchinese.Big5
-		default:
-			isUTF8 = true
+func isASCII(buf []byte) bool {
+	for _, b := range buf {
+		if b >= 0x80 {
+			return false
 		}
 	}
-	uft8buf := buf
-	if !isUTF8 {
-		uft8buf, err = e.NewDecoder().Bytes(buf)
+	return true
+}
+
+// utf16Order guesses the byte order of UTF-16 text without BOM by position of zero bytes,
+// which are the high bytes of ASCII chars like line ending;
+// it returns empty string if buf doesn't look like UTF-16
+func utf16Order(buf []byte) string {
+	var even, odd, leNewline, beNewline int
+	for i, b := range buf {
+		switch b {
+		case 0:
+			if i%2 == 0 {
+				even++
+			} else {
+				odd++
+			}
+		case '\n':
+			if i%2 == 0 && i+1 < len(buf) && buf[i+1] == 0 {
+				leNewline++
+			}
+			if i%2 == 1 && buf[i-1] == 0 {
+				beNewline++
+			}
+		}
+	}
+	half := len(buf) / 2
+	switch {
+	case leNewline > 0 && beNewline == 0, odd > half/3 && even < odd/10:
+		return "UTF-16LE"
+	case beNewline > 0 && leNewline == 0, even > half/3 && odd < even/10:
+		return "UTF-16BE"
+	}
+	return ""
+}
+
+// Detect returns the charset of buf, samples from start, middle and end of buf are used;
+// BOM takes precedence, UTF-8 is returned if no supported charset is detected
+func (d *DetChar) Detect(buf []byte) Detection {
+	for _, b := range boms {
+		if bytes.HasPrefix(buf, b.bom) {
+			cs, _ := LookupCharset(b.name)
+			return Detection{Charset: cs, Confidence: 100, BOMLen: len(b.bom)}
+		}
+	}
+	head := buf
+	if len(head) > sampleSize {
+		head = head[:sampleSize]
+	}
+	if name := utf16Order(head); name != "" {
+		cs, _ := LookupCharset(name)
+		return Detection{Charset: cs, Confidence: 80}
+	}
+	// scores key is charset name, value is sum of confidence of all samples
+	scores := make(map[string]int)
+	total := 0
+	for _, s := range samples(buf) {
+		if isASCII(s) {
+			// ASCII is valid in all supported charsets except UTF-16
+			continue
+		}
+		total++
+		results, err := d.DetectAll(s)
 		if err != nil {
-			return nil, err
+			continue
+		}
+		// results are sorted by confidence, the best supported one is the vote of this sample
+		for _, res := range results {
+			if name, ok := detectedNames[res.Charset]; ok {
+				scores[name] += res.Confidence
+				break
+			}
+		}
+	}
+	if total == 0 {
+		return Detection{Charset: Charsets[0], Confidence: 100}
+	}
+	names := []string{}
+	for name := range scores {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return Detection{Charset: Charsets[0]}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if scores[names[i]] != scores[names[j]] {
+			return scores[names[i]] > scores[names[j]]
 		}
+		return names[i] < names[j]
+	})
+	cs, _ := LookupCharset(names[0])
+	return Detection{Charset: cs, Confidence: scores[names[0]] / total}
+}
+
+func (d *DetChar) ToByteList(buf []byte) ([][]byte, error) {
+	uft8buf, err := d.Detect(buf).Decode(buf)
+	if err != nil {
+		return nil, err
 	}
 	return SplitLinesBytes(uft8buf), nil
 }
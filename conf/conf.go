@@ -28,6 +28,31 @@ type Config struct {
 	LastFile       string
 	Theme          *Look
 	BackgroundFile string
+	AutoUpdate     AutoUpdateConf
+	// KeyMap is the key bindings of actions, actions not in the config file use the default keys
+	KeyMap KeyMap
+}
+
+// AutoUpdateConf controls the background update of subscriptions
+type AutoUpdateConf struct {
+	// IntervalMinutes is the interval between two rounds of update, 0 disables auto update
+	IntervalMinutes int
+	// Concurrency is the max number of subscriptions updating at the same time
+	Concurrency int
+	// PluginIntervalSeconds is the min interval between starting two updates via the same plugin
+	PluginIntervalSeconds int
+	// PluginConcurrency is the max number of updates via the same plugin running at the same time,
+	// it also applies to updating all subscriptions manually
+	PluginConcurrency int
+}
+
+func defaultAutoUpdateConf() AutoUpdateConf {
+	return AutoUpdateConf{
+		IntervalMinutes:       0,
+		Concurrency:           3,
+		PluginIntervalSeconds: 2,
+		PluginConcurrency:     1,
+	}
 }
 
 func NewDefConfig() (cfg *Config, err error) {
@@ -35,6 +60,8 @@ func NewDefConfig() (cfg *Config, err error) {
 		LastFile:       "",
 		BackgroundFile: "",
 		LastWinSize:    fyne.NewSize(1000, 800),
+		AutoUpdate:     defaultAutoUpdateConf(),
+		KeyMap:         DefaultKeyMap(),
 	}
 	cfg.Theme, err = defaultLook()
 	return
@@ -0,0 +1,287 @@
+// keymap
+package conf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hujun-open/golitebook/liteview"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+// names of actions in KeyMap
+const (
+	ActOpenFile          = "OpenFile"
+	ActSearchAndDownload = "SearchAndDownload"
+	ActShowSubscriptions = "ShowSubscriptions"
+	ActFormatText        = "FormatText"
+	ActHelp              = "Help"
+	ActShowTOC           = "ShowTOC"
+	ActSwitchUnderline   = "SwitchUnderline"
+	ActFullScreen        = "FullScreen"
+	ActQuit              = "Quit"
+	ActSelectFontFile    = "SelectFontFile"
+	ActFind              = "Find"
+	ActFindNext          = "FindNext"
+	ActFindPrev          = "FindPrev"
+	ActAddBookmark       = "AddBookmark"
+	ActShowBookmarks     = "ShowBookmarks"
+	ActChapterPatterns   = "ChapterPatterns"
+	ActReopenWithCharset = "ReopenWithCharset"
+	ActShowPluginStatus  = "ShowPluginStatus"
+	ActShowAutoUpdate    = "ShowAutoUpdate"
+	ActSwitchColorScheme = "SwitchColorScheme"
+	ActCustomColors      = "CustomColors"
+	ActEditKeyMap        = "EditKeyMap"
+
+	// following actions are bound to keys without modifier
+	ActLineUp      = "LineUp"
+	ActLineDown    = "LineDown"
+	ActPageUp      = "PageUp"
+	ActPageDown    = "PageDown"
+	ActTop         = "Top"
+	ActBottom      = "Bottom"
+	ActFontBigger  = "FontBigger"
+	ActFontSmaller = "FontSmaller"
+)
+
+// navActions are actions handled by the reading view
+var navActions = map[string]liteview.NavAction{
+	ActLineUp:   liteview.NavLineUp,
+	ActLineDown: liteview.NavLineDown,
+	ActPageUp:   liteview.NavPageUp,
+	ActPageDown: liteview.NavPageDown,
+	ActTop:      liteview.NavTop,
+	ActBottom:   liteview.NavBottom,
+}
+
+// PlainKeyAction returns true if action is bound to keys without modifier
+func PlainKeyAction(action string) bool {
+	if _, ok := navActions[action]; ok {
+		return true
+	}
+	return action == ActFontBigger || action == ActFontSmaller
+}
+
+// KeyMap maps action names to key combos like "Ctrl+Shift+G", an action could have multiple combos
+type KeyMap map[string][]string
+
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		ActOpenFile:          {"Ctrl+O"},
+		ActSearchAndDownload: {"Alt+C"},
+		ActShowSubscriptions: {"Ctrl+Y"},
+		ActFormatText:        {"Ctrl+Alt+F"},
+		ActHelp:              {"Ctrl+H"},
+		ActShowTOC:           {"Ctrl+U"},
+		ActSwitchUnderline:   {"Ctrl+L"},
+		ActFullScreen:        {"Ctrl+P"},
+		ActQuit:              {"Ctrl+W"},
+		ActSelectFontFile:    {"Alt+Z"},
+		ActFind:              {"Ctrl+F"},
+		ActFindNext:          {"Ctrl+G"},
+		ActFindPrev:          {"Ctrl+Shift+G"},
+		ActAddBookmark:       {"Ctrl+B"},
+		ActShowBookmarks:     {"Alt+B"},
+		ActChapterPatterns:   {"Alt+U"},
+		ActReopenWithCharset: {"Alt+E"},
+		ActShowPluginStatus:  {"Alt+P"},
+		ActShowAutoUpdate:    {"Alt+A"},
+		ActSwitchColorScheme: {"Ctrl+T"},
+		ActCustomColors:      {"Alt+T"},
+		ActEditKeyMap:        {"Alt+K"},
+
+		ActLineUp:      {"Up", "K"},
+		ActLineDown:    {"Down", "J"},
+		ActPageUp:      {"PageUp", "Left"},
+		ActPageDown:    {"PageDown", "Right", "Space"},
+		ActTop:         {"Home"},
+		ActBottom:      {"End"},
+		ActFontBigger:  {"="},
+		ActFontSmaller: {"-"},
+	}
+}
+
+// KeyCombo is a key with modifiers
+type KeyCombo struct {
+	Key      fyne.KeyName
+	Modifier desktop.Modifier
+}
+
+var modifierNames = []struct {
+	name string
+	mod  desktop.Modifier
+}{
+	{"Ctrl", desktop.ControlModifier},
+	{"Alt", desktop.AltModifier},
+	{"Shift", desktop.ShiftModifier},
+	{"Super", desktop.SuperModifier},
+}
+
+// keyAliases are names easier to read than fyne key names
+var keyAliases = map[string]fyne.KeyName{
+	"PageUp":   fyne.KeyPageUp,
+	"PageDown": fyne.KeyPageDown,
+	"Enter":    fyne.KeyReturn,
+	"Esc":      fyne.KeyEscape,
+}
+
+var namedKeys = []fyne.KeyName{
+	fyne.KeyEscape, fyne.KeyReturn, fyne.KeyTab, fyne.KeyBackspace, fyne.KeyInsert, fyne.KeyDelete,
+	fyne.KeyRight, fyne.KeyLeft, fyne.KeyDown, fyne.KeyUp, fyne.KeyPageUp, fyne.KeyPageDown,
+	fyne.KeyHome, fyne.KeyEnd, fyne.KeySpace, fyne.KeyEnter,
+	fyne.KeyF1, fyne.KeyF2, fyne.KeyF3, fyne.KeyF4, fyne.KeyF5, fyne.KeyF6,
+	fyne.KeyF7, fyne.KeyF8, fyne.KeyF9, fyne.KeyF10, fyne.KeyF11, fyne.KeyF12,
+}
+
+// symbolKeys are keys named by a single char other than letters and digits
+const symbolKeys = "',-./\\[];=*+`"
+
+// parseKeyName returns the fyne key name of s, case insensitive
+func parseKeyName(s string) (fyne.KeyName, error) {
+	if len(s) == 1 {
+		c := strings.ToUpper(s)[0]
+		if (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || strings.IndexByte(symbolKeys, c) >= 0 {
+			return fyne.KeyName(c), nil
+		}
+	}
+	for alias, k := range keyAliases {
+		if strings.EqualFold(alias, s) {
+			return k, nil
+		}
+	}
+	for _, k := range namedKeys {
+		if strings.EqualFold(string(k), s) {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("unknown key %v", s)
+}
+
+// ParseKeyCombo parses s like "Ctrl+Alt+F", "PageDown" or "Ctrl++"
+func ParseKeyCombo(s string) (KeyCombo, error) {
+	var r KeyCombo
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return r, fmt.Errorf("empty key")
+	}
+	key := s
+	if i := strings.LastIndex(s[:len(s)-1], "+"); i >= 0 {
+		key = s[i+1:]
+		for _, m := range strings.Split(s[:i], "+") {
+			found := false
+			for _, mn := range modifierNames {
+				if strings.EqualFold(mn.name, strings.TrimSpace(m)) {
+					r.Modifier |= mn.mod
+					found = true
+					break
+				}
+			}
+			if !found {
+				return r, fmt.Errorf("unknown modifier %v in %v", m, s)
+			}
+		}
+	}
+	var err error
+	if r.Key, err = parseKeyName(strings.TrimSpace(key)); err != nil {
+		return r, fmt.Errorf("invalid key %v, %w", s, err)
+	}
+	return r, nil
+}
+
+func (kc KeyCombo) String() string {
+	r := ""
+	for _, mn := range modifierNames {
+		if kc.Modifier&mn.mod != 0 {
+			r += mn.name + "+"
+		}
+	}
+	switch kc.Key {
+	case fyne.KeyPageUp:
+		return r + "PageUp"
+	case fyne.KeyPageDown:
+		return r + "PageDown"
+	}
+	return r + string(kc.Key)
+}
+
+// Shortcut returns kc as a fyne shortcut
+func (kc KeyCombo) Shortcut() *desktop.CustomShortcut {
+	return &desktop.CustomShortcut{KeyName: kc.Key, Modifier: kc.Modifier}
+}
+
+// Combos returns the parsed key combos of action
+func (km KeyMap) Combos(action string) ([]KeyCombo, error) {
+	r := []KeyCombo{}
+	for _, s := range km[action] {
+		kc, err := ParseKeyCombo(s)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", action, err)
+		}
+		r = append(r, kc)
+	}
+	return r, nil
+}
+
+// Validate checks every action of km is known, every combo is valid and bound to one action only;
+// plain key actions must use keys without modifier, others must use Ctrl, Alt or Super
+func (km KeyMap) Validate() error {
+	actions := make([]string, 0, len(km))
+	for action := range km {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	defaults := DefaultKeyMap()
+	used := make(map[KeyCombo]string)
+	for _, action := range actions {
+		if _, ok := defaults[action]; !ok {
+			return fmt.Errorf("unknown action %v", action)
+		}
+		combos, err := km.Combos(action)
+		if err != nil {
+			return err
+		}
+		for _, kc := range combos {
+			if PlainKeyAction(action) {
+				if kc.Modifier != 0 {
+					return fmt.Errorf("%v: %v can't have modifier", action, kc)
+				}
+			} else if kc.Modifier&^desktop.ShiftModifier == 0 {
+				return fmt.Errorf("%v: %v needs Ctrl, Alt or Super", action, kc)
+			}
+			if other, ok := used[kc]; ok && other != action {
+				return fmt.Errorf("%v is bound to both %v and %v", kc, other, action)
+			}
+			used[kc] = action
+		}
+	}
+	return nil
+}
+
+// NavKeys returns keys of the navigation actions for the reading view
+func (km KeyMap) NavKeys() map[fyne.KeyName]liteview.NavAction {
+	r := make(map[fyne.KeyName]liteview.NavAction)
+	for action, nav := range navActions {
+		combos, _ := km.Combos(action)
+		for _, kc := range combos {
+			r[kc.Key] = nav
+		}
+	}
+	return r
+}
+
+// KeysStr returns the combos of action joined by ", "
+func (km KeyMap) KeysStr(action string) string {
+	combos, err := km.Combos(action)
+	if err != nil {
+		return strings.Join(km[action], ", ")
+	}
+	list := []string{}
+	for _, kc := range combos {
+		list = append(list, kc.String())
+	}
+	return strings.Join(list, ", ")
+}
@@ -0,0 +1,93 @@
+// keymap_test
+package conf
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hujun-open/golitebook/liteview"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+func TestParseKeyCombo(t *testing.T) {
+	testList := []struct {
+		s      string
+		expect KeyCombo
+		str    string
+		fail   bool
+	}{
+		{s: "Ctrl+O", expect: KeyCombo{Key: fyne.KeyO, Modifier: desktop.ControlModifier}, str: "Ctrl+O"},
+		{s: "alt+ctrl+f", expect: KeyCombo{Key: fyne.KeyF, Modifier: desktop.ControlModifier | desktop.AltModifier}, str: "Ctrl+Alt+F"},
+		{s: "pagedown", expect: KeyCombo{Key: fyne.KeyPageDown}, str: "PageDown"},
+		{s: "Ctrl++", expect: KeyCombo{Key: fyne.KeyPlus, Modifier: desktop.ControlModifier}, str: "Ctrl++"},
+		{s: "=", expect: KeyCombo{Key: fyne.KeyEqual}, str: "="},
+		{s: "Hyper+O", fail: true},
+		{s: "Ctrl+Nosuchkey", fail: true},
+		{s: "", fail: true},
+	}
+	for _, c := range testList {
+		kc, err := ParseKeyCombo(c.s)
+		if c.fail {
+			if err == nil {
+				t.Fatalf("%q is expected to fail, got %v", c.s, kc)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kc != c.expect || kc.String() != c.str {
+			t.Fatalf("%q: expect %v (%v), got %v (%v)", c.s, c.expect, c.str, kc, kc)
+		}
+	}
+}
+
+func TestKeyMapValidate(t *testing.T) {
+	if err := DefaultKeyMap().Validate(); err != nil {
+		t.Fatalf("default key map is invalid, %v", err)
+	}
+	testList := []struct {
+		change KeyMap
+		fail   bool
+	}{
+		{change: KeyMap{ActQuit: {"Ctrl+Q"}, ActPageDown: {"N", "Space"}}},
+		{change: KeyMap{ActQuit: {"ctrl+o"}}, fail: true},
+		{change: KeyMap{ActLineUp: {"Ctrl+K"}}, fail: true},
+		{change: KeyMap{ActFind: {"F"}}, fail: true},
+		{change: KeyMap{ActFind: {"Shift+F"}}, fail: true},
+		{change: KeyMap{ActTop: {"Space"}}, fail: true},
+		{change: KeyMap{"NoSuchAction": {"Ctrl+Q"}}, fail: true},
+	}
+	for _, c := range testList {
+		km := DefaultKeyMap()
+		for k, v := range c.change {
+			km[k] = v
+		}
+		err := km.Validate()
+		if c.fail != (err != nil) {
+			t.Fatalf("%v: expect failure %v, got %v", c.change, c.fail, err)
+		}
+	}
+	km := DefaultKeyMap()
+	km[ActPageDown] = []string{"N"}
+	nav := km.NavKeys()
+	if nav[fyne.KeyN] != liteview.NavPageDown {
+		t.Fatalf("N is not bound to page down")
+	}
+	if _, ok := nav[fyne.KeySpace]; ok {
+		t.Fatalf("space is still bound")
+	}
+}
+
+func TestKeyMapConfig(t *testing.T) {
+	// actions not in the config file keep the default keys
+	cfg := &Config{KeyMap: DefaultKeyMap()}
+	if err := json.Unmarshal([]byte(`{"KeyMap":{"Quit":["Ctrl+Q"]}}`), cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.KeyMap.KeysStr(ActQuit) != "Ctrl+Q" || cfg.KeyMap.KeysStr(ActOpenFile) != "Ctrl+O" {
+		t.Fatalf("unexpected key map %v", cfg.KeyMap)
+	}
+}
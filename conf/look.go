@@ -63,19 +63,28 @@ type Look struct {
 	font      fyne.Resource
 	fontPath  string
 	underLine int
+	// scheme is the name of color scheme, empty means colors of baseTheme
+	scheme string
+	custom ColorScheme
 }
 
 type lookConf struct {
-	FontPath  string
-	FontSize  float32
-	UnderLine int
+	FontPath     string
+	FontSize     float32
+	UnderLine    int
+	ColorScheme  string           `json:",omitempty"`
+	CustomColors *customColorConf `json:",omitempty"`
 }
 
 func (lk Look) MarshalJSON() ([]byte, error) {
 	lkcnf := lookConf{
-		FontPath:  lk.fontPath,
-		FontSize:  lk.fontSize,
-		UnderLine: lk.underLine,
+		FontPath:    lk.fontPath,
+		FontSize:    lk.fontSize,
+		UnderLine:   lk.underLine,
+		ColorScheme: lk.scheme,
+	}
+	if lk.custom.Text != nil {
+		lkcnf.CustomColors = newCustomColorConf(lk.custom)
 	}
 	return json.Marshal(lkcnf)
 }
@@ -98,6 +107,23 @@ func (lk *Look) UnmarshalJSON(b []byte) error {
 	lk.fontPath = lkcnf.FontPath
 	lk.fontSize = lkcnf.FontSize
 	lk.underLine = lkcnf.UnderLine
+	if lk.baseTheme == nil {
+		lk.baseTheme = theme.DarkTheme()
+	}
+	lk.custom = ColorScheme{}
+	if lkcnf.CustomColors != nil {
+		if lk.custom, err = lkcnf.CustomColors.scheme(); err != nil {
+			log.Printf("failed to load custom colors, %v", err)
+			lk.custom = ColorScheme{}
+		}
+	}
+	// config saved before color schemes are added is dark
+	lk.scheme = SchemeDark
+	if lkcnf.ColorScheme != "" {
+		if err := lk.SetColorScheme(lkcnf.ColorScheme); err != nil {
+			log.Printf("%v, using %v", err, SchemeDark)
+		}
+	}
 	return nil
 
 }
@@ -117,6 +143,7 @@ func DefaultLook() (*Look, error) {
 func defaultLook() (*Look, error) {
 	l := NewLookFromTheme(theme.DarkTheme())
 	l.underLine = int(liteview.UnderLineDash)
+	l.scheme = SchemeDark
 	var err error
 	l.font, l.fontPath, err = loadDefaultFont()
 	if err != nil {
@@ -134,7 +161,17 @@ func (l *Look) SetTextSize(s float32) {
 
 // following are methods implmenting fyne.Theme interface
 func (l *Look) Color(cname fyne.ThemeColorName, tvar fyne.ThemeVariant) color.Color {
-	return l.baseTheme.Color(cname, tvar)
+	if l.scheme == "" {
+		return l.baseTheme.Color(cname, tvar)
+	}
+	cs := l.colorScheme()
+	if c := cs.color(cname); c != nil {
+		return c
+	}
+	if cname == liteview.ColorNameUnderline {
+		return l.Color(theme.ColorNameForeground, tvar)
+	}
+	return cs.base(l.scheme).Color(cname, tvar)
 }
 func (l *Look) Font(fyne.TextStyle) fyne.Resource {
 	return l.font
@@ -163,3 +200,54 @@ func (l *Look) SetFont(r fyne.Resource, fpath string) {
 	l.font = r
 	l.fontPath = fpath
 }
+
+// ColorScheme returns the name of current color scheme
+func (l *Look) ColorScheme() string {
+	return l.scheme
+}
+
+// SetColorScheme switches to the color scheme name
+func (l *Look) SetColorScheme(name string) error {
+	if _, ok := builtinSchemes[name]; !ok && name != SchemeCustom {
+		return fmt.Errorf("unknown color scheme %v", name)
+	}
+	l.scheme = name
+	return nil
+}
+
+// NextColorScheme switches to the scheme after current one in SchemeNames, and returns its name
+func (l *Look) NextColorScheme() string {
+	next := 0
+	for i, name := range SchemeNames {
+		if name == l.scheme {
+			next = (i + 1) % len(SchemeNames)
+			break
+		}
+	}
+	l.scheme = SchemeNames[next]
+	return l.scheme
+}
+
+// CustomColors returns colors of the custom scheme
+func (l *Look) CustomColors() ColorScheme {
+	if l.custom.Text == nil {
+		return defaultCustomScheme
+	}
+	return l.custom
+}
+
+// SetCustomColors sets colors of the custom scheme, all colors of cs must be set
+func (l *Look) SetCustomColors(cs ColorScheme) error {
+	if cs.Text == nil || cs.Background == nil || cs.Underline == nil {
+		return fmt.Errorf("text, background and underline colors must be all set")
+	}
+	l.custom = cs
+	return nil
+}
+
+func (l *Look) colorScheme() ColorScheme {
+	if l.scheme == SchemeCustom {
+		return l.CustomColors()
+	}
+	return builtinSchemes[l.scheme]
+}
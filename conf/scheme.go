@@ -0,0 +1,148 @@
+// scheme
+package conf
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hujun-open/golitebook/liteview"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+// names of color schemes
+const (
+	SchemeDark         = "dark"
+	SchemeLight        = "light"
+	SchemeSepia        = "sepia"
+	SchemeHighContrast = "highcontrast"
+	SchemeCustom       = "custom"
+)
+
+// SchemeNames is the order of switching color schemes
+var SchemeNames = []string{SchemeDark, SchemeLight, SchemeSepia, SchemeHighContrast, SchemeCustom}
+
+// SchemeTitle returns the display name of color scheme name
+func SchemeTitle(name string) string {
+	switch name {
+	case SchemeDark:
+		return "深色"
+	case SchemeLight:
+		return "浅色"
+	case SchemeSepia:
+		return "护眼"
+	case SchemeHighContrast:
+		return "高对比度"
+	case SchemeCustom:
+		return "自定义"
+	}
+	return name
+}
+
+// ColorScheme is the colors of text, background and underline,
+// a nil color means using the one of the base theme
+type ColorScheme struct {
+	Text, Background, Underline color.Color
+}
+
+var builtinSchemes = map[string]ColorScheme{
+	SchemeDark:  {},
+	SchemeLight: {},
+	SchemeSepia: {
+		Text:       color.NRGBA{0x5b, 0x46, 0x36, 0xff},
+		Background: color.NRGBA{0xf4, 0xec, 0xd8, 0xff},
+		Underline:  color.NRGBA{0xc8, 0xb8, 0x9a, 0xff},
+	},
+	SchemeHighContrast: {
+		Text:       color.NRGBA{0xff, 0xff, 0xff, 0xff},
+		Background: color.NRGBA{0x00, 0x00, 0x00, 0xff},
+		Underline:  color.NRGBA{0xff, 0xff, 0x00, 0xff},
+	},
+}
+
+// defaultCustomScheme is used as custom scheme before user sets one
+var defaultCustomScheme = builtinSchemes[SchemeSepia]
+
+// dark returns true if cs is a dark scheme, judged by its background
+func (cs ColorScheme) dark() bool {
+	if cs.Background == nil {
+		return true
+	}
+	r, g, b, _ := cs.Background.RGBA()
+	// relative luminance, RGBA returns values in [0, 0xffff]
+	return 0.299*float64(r)+0.587*float64(g)+0.114*float64(b) < 0x8000
+}
+
+// base returns the theme providing colors not defined in cs
+func (cs ColorScheme) base(name string) fyne.Theme {
+	switch name {
+	case SchemeLight, SchemeSepia:
+		return theme.LightTheme()
+	case SchemeDark, SchemeHighContrast:
+		return theme.DarkTheme()
+	}
+	if cs.dark() {
+		return theme.DarkTheme()
+	}
+	return theme.LightTheme()
+}
+
+// color returns the color of cname in cs, nil if cs doesn't define it
+func (cs ColorScheme) color(cname fyne.ThemeColorName) color.Color {
+	switch cname {
+	case theme.ColorNameForeground:
+		return cs.Text
+	case theme.ColorNameBackground:
+		return cs.Background
+	case liteview.ColorNameUnderline:
+		return cs.Underline
+	}
+	return nil
+}
+
+// hexColor returns c as "#rrggbb"
+func hexColor(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B)
+}
+
+// parseHexColor parses color in format of "#rrggbb"
+func parseHexColor(s string) (color.Color, error) {
+	var r, g, b uint8
+	if len(s) != 7 {
+		return nil, fmt.Errorf("invalid color %v", s)
+	}
+	if _, err := fmt.Sscanf(s, "#%02x%02x%02x", &r, &g, &b); err != nil {
+		return nil, fmt.Errorf("invalid color %v, %w", s, err)
+	}
+	return color.NRGBA{R: r, G: g, B: b, A: 0xff}, nil
+}
+
+// customColorConf is the saved custom color scheme
+type customColorConf struct {
+	Text, Background, Underline string
+}
+
+func newCustomColorConf(cs ColorScheme) *customColorConf {
+	return &customColorConf{
+		Text:       hexColor(cs.Text),
+		Background: hexColor(cs.Background),
+		Underline:  hexColor(cs.Underline),
+	}
+}
+
+func (cc *customColorConf) scheme() (ColorScheme, error) {
+	var r ColorScheme
+	var err error
+	if r.Text, err = parseHexColor(cc.Text); err != nil {
+		return r, err
+	}
+	if r.Background, err = parseHexColor(cc.Background); err != nil {
+		return r, err
+	}
+	if r.Underline, err = parseHexColor(cc.Underline); err != nil {
+		return r, err
+	}
+	return r, nil
+}
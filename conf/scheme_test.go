@@ -0,0 +1,70 @@
+// scheme_test
+package conf
+
+import (
+	"encoding/json"
+	"image/color"
+	"testing"
+
+	"github.com/hujun-open/golitebook/liteview"
+
+	"fyne.io/fyne/v2/theme"
+)
+
+func TestColorScheme(t *testing.T) {
+	lk := NewLookFromTheme(theme.DarkTheme())
+	if err := lk.SetColorScheme("nosuchscheme"); err == nil {
+		t.Fatal("unknown scheme is accepted")
+	}
+	if err := lk.SetColorScheme(SchemeSepia); err != nil {
+		t.Fatal(err)
+	}
+	sepia := builtinSchemes[SchemeSepia]
+	if c := lk.Color(theme.ColorNameForeground, theme.VariantDark); c != sepia.Text {
+		t.Fatalf("expect sepia text color, got %v", c)
+	}
+	if c := lk.Color(liteview.ColorNameUnderline, theme.VariantDark); c != sepia.Underline {
+		t.Fatalf("expect sepia underline color, got %v", c)
+	}
+	if name := lk.NextColorScheme(); name != SchemeHighContrast {
+		t.Fatalf("expect %v after sepia, got %v", SchemeHighContrast, name)
+	}
+	if err := lk.SetCustomColors(ColorScheme{Text: color.Black}); err == nil {
+		t.Fatal("incomplete custom scheme is accepted")
+	}
+	custom := ColorScheme{
+		Text:       color.NRGBA{0x10, 0x20, 0x30, 0xff},
+		Background: color.NRGBA{0xee, 0xee, 0xdd, 0xff},
+		Underline:  color.NRGBA{0x80, 0x80, 0x80, 0xff},
+	}
+	if err := lk.SetCustomColors(custom); err != nil {
+		t.Fatal(err)
+	}
+	lk.SetColorScheme(SchemeCustom)
+	if name := lk.NextColorScheme(); name != SchemeDark {
+		t.Fatalf("expect %v after custom, got %v", SchemeDark, name)
+	}
+	lk.SetColorScheme(SchemeCustom)
+
+	buf, err := json.Marshal(lk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lkcnf lookConf
+	if err := json.Unmarshal(buf, &lkcnf); err != nil {
+		t.Fatal(err)
+	}
+	if lkcnf.ColorScheme != SchemeCustom || lkcnf.CustomColors == nil || lkcnf.CustomColors.Background != "#eeeedd" {
+		t.Fatalf("unexpected saved look %+v", lkcnf)
+	}
+	loaded, err := lkcnf.CustomColors.scheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded != custom {
+		t.Fatalf("expect custom colors %v, got %v", custom, loaded)
+	}
+	if custom.dark() || !builtinSchemes[SchemeHighContrast].dark() {
+		t.Fatal("wrong brightness of scheme")
+	}
+}
@@ -0,0 +1,402 @@
+// epub reads EPUB 2/3 books as text lines
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const containerPath = "META-INF/container.xml"
+
+// TOCEntry is a ToC entry of the book, Line is the line id in Book.Lines,
+// Level starts from 0
+type TOCEntry struct {
+	Name  string
+	Line  int
+	Level int
+}
+
+type Book struct {
+	Title  string
+	Author string
+	// Lines is the text of book, one paragraph per line
+	Lines [][]byte
+	TOC   []TOCEntry
+}
+
+type container struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type manifestItem struct {
+	ID         string `xml:"id,attr"`
+	Href       string `xml:"href,attr"`
+	MediaType  string `xml:"media-type,attr"`
+	Properties string `xml:"properties,attr"`
+}
+
+type opfPackage struct {
+	Title    []string       `xml:"metadata>title"`
+	Creator  []string       `xml:"metadata>creator"`
+	Manifest []manifestItem `xml:"manifest>item"`
+	Spine    struct {
+		TOC      string `xml:"toc,attr"`
+		ItemRefs []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+type ncxNavPoint struct {
+	Label    string        `xml:"navLabel>text"`
+	Content  ncxContent    `xml:"content"`
+	Children []ncxNavPoint `xml:"navPoint"`
+}
+
+type ncxContent struct {
+	Src string `xml:"src,attr"`
+}
+
+type ncx struct {
+	NavPoints []ncxNavPoint `xml:"navMap>navPoint"`
+}
+
+// navItem is an entry parsed from nav or NCX document, target is the full path in zip with optional #fragment
+type navItem struct {
+	name   string
+	target string
+	level  int
+}
+
+type reader struct {
+	files map[string]*zip.File
+}
+
+func (r *reader) read(name string) ([]byte, error) {
+	f, ok := r.files[name]
+	if !ok {
+		return nil, fmt.Errorf("%v not found in epub", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+// newDecoder returns a lenient decoder, isHTML should be true for XHTML document
+func newDecoder(buf []byte, isHTML bool) *xml.Decoder {
+	d := xml.NewDecoder(bytes.NewReader(buf))
+	d.Strict = false
+	if isHTML {
+		d.AutoClose = xml.HTMLAutoClose
+	}
+	d.Entity = xml.HTMLEntity
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return d
+}
+
+func unmarshal(buf []byte, v interface{}) error {
+	return newDecoder(buf, false).Decode(v)
+}
+
+// resolve returns full path in zip of href which is relative to base
+func resolve(base, href string) string {
+	frag := ""
+	if i := strings.Index(href, "#"); i >= 0 {
+		frag = href[i:]
+		href = href[:i]
+	}
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	if href == "" {
+		return base + frag
+	}
+	return path.Join(path.Dir(base), href) + frag
+}
+
+// Parse parses EPUB file content buf
+func Parse(buf []byte) (*Book, error) {
+	zr, err := zip.NewReader(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		return nil, err
+	}
+	r := &reader{files: make(map[string]*zip.File)}
+	for _, f := range zr.File {
+		r.files[f.Name] = f
+	}
+	cbuf, err := r.read(containerPath)
+	if err != nil {
+		return nil, err
+	}
+	var c container
+	if err = unmarshal(cbuf, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse %v, %w", containerPath, err)
+	}
+	if len(c.Rootfiles) == 0 {
+		return nil, fmt.Errorf("no rootfile in %v", containerPath)
+	}
+	opfPath := c.Rootfiles[0].FullPath
+	obuf, err := r.read(opfPath)
+	if err != nil {
+		return nil, err
+	}
+	var opf opfPackage
+	if err = unmarshal(obuf, &opf); err != nil {
+		return nil, fmt.Errorf("failed to parse %v, %w", opfPath, err)
+	}
+	book := new(Book)
+	if len(opf.Title) > 0 {
+		book.Title = strings.TrimSpace(opf.Title[0])
+	}
+	if len(opf.Creator) > 0 {
+		book.Author = strings.TrimSpace(opf.Creator[0])
+	}
+	items := make(map[string]manifestItem)
+	for _, item := range opf.Manifest {
+		items[item.ID] = item
+	}
+	// anchors key is full path with optional #fragment, value is line id
+	anchors := make(map[string]int)
+	for _, ref := range opf.Spine.ItemRefs {
+		item, ok := items[ref.IDRef]
+		if !ok {
+			continue
+		}
+		docPath := resolve(opfPath, item.Href)
+		dbuf, err := r.read(docPath)
+		if err != nil {
+			return nil, err
+		}
+		book.Lines = appendParagraphs(book.Lines, dbuf, docPath, anchors)
+	}
+	var navList []navItem
+	for _, item := range opf.Manifest {
+		if hasProperty(item.Properties, "nav") {
+			navList, err = r.parseNav(resolve(opfPath, item.Href))
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	if len(navList) == 0 {
+		if item, ok := items[opf.Spine.TOC]; ok {
+			navList, err = r.parseNCX(resolve(opfPath, item.Href))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, nav := range navList {
+		line, ok := anchors[nav.target]
+		if !ok {
+			// fragment not found, fallback to start of document
+			line, ok = anchors[strings.SplitN(nav.target, "#", 2)[0]]
+		}
+		if !ok {
+			continue
+		}
+		if line >= len(book.Lines) {
+			line = len(book.Lines) - 1
+		}
+		if line < 0 {
+			continue
+		}
+		book.TOC = append(book.TOC, TOCEntry{Name: nav.name, Line: line, Level: nav.level})
+	}
+	return book, nil
+}
+
+func hasProperty(props, p string) bool {
+	for _, s := range strings.Fields(props) {
+		if s == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *reader) parseNCX(ncxPath string) ([]navItem, error) {
+	buf, err := r.read(ncxPath)
+	if err != nil {
+		return nil, err
+	}
+	var n ncx
+	if err = unmarshal(buf, &n); err != nil {
+		return nil, fmt.Errorf("failed to parse %v, %w", ncxPath, err)
+	}
+	var rlist []navItem
+	var walk func(points []ncxNavPoint, level int)
+	walk = func(points []ncxNavPoint, level int) {
+		for _, p := range points {
+			rlist = append(rlist, navItem{
+				name:   collapseSpaces(p.Label),
+				target: resolve(ncxPath, p.Content.Src),
+				level:  level,
+			})
+			walk(p.Children, level+1)
+		}
+	}
+	walk(n.NavPoints, 0)
+	return rlist, nil
+}
+
+// parseNav parses the toc nav of EPUB3 navigation document
+func (r *reader) parseNav(navPath string) ([]navItem, error) {
+	buf, err := r.read(navPath)
+	if err != nil {
+		return nil, err
+	}
+	d := newDecoder(buf, true)
+	var rlist []navItem
+	inTOC := false
+	navDepth := 0
+	olDepth := 0
+	var cur *navItem
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %v, %w", navPath, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "nav":
+				if inTOC {
+					navDepth++
+				} else if attr(t, "type") == "toc" {
+					inTOC = true
+					navDepth = 1
+				}
+			case "ol":
+				if inTOC {
+					olDepth++
+				}
+			case "a":
+				if inTOC {
+					cur = &navItem{target: resolve(navPath, attr(t, "href")), level: olDepth - 1}
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "nav":
+				if inTOC {
+					navDepth--
+					if navDepth == 0 {
+						return rlist, nil
+					}
+				}
+			case "ol":
+				if inTOC {
+					olDepth--
+				}
+			case "a":
+				if cur != nil {
+					cur.name = collapseSpaces(cur.name)
+					rlist = append(rlist, *cur)
+					cur = nil
+				}
+			}
+		case xml.CharData:
+			if cur != nil {
+				cur.name += string(t)
+			}
+		}
+	}
+	return rlist, nil
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+var spaceRegex = regexp.MustCompile(`\s+`)
+
+func collapseSpaces(s string) string {
+	return strings.TrimSpace(spaceRegex.ReplaceAllString(s, " "))
+}
+
+// elements that start a new paragraph
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"blockquote": true, "section": true, "article": true, "pre": true,
+	"dt": true, "dd": true, "hr": true, "figcaption": true,
+}
+
+// elements whose text is not part of the book
+var skippedElements = map[string]bool{
+	"head": true, "script": true, "style": true,
+}
+
+// appendParagraphs strips XHTML document buf to paragraphs and append them to lines;
+// the line id of start of document and every element with id are recorded in anchors
+func appendParagraphs(lines [][]byte, buf []byte, docPath string, anchors map[string]int) [][]byte {
+	anchors[docPath] = len(lines)
+	d := newDecoder(buf, true)
+	var cur strings.Builder
+	skip := 0
+	flush := func() {
+		if s := collapseSpaces(cur.String()); s != "" {
+			lines = append(lines, []byte(s))
+		}
+		cur.Reset()
+	}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			// keep what has been parsed for malformed document
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skippedElements[t.Name.Local] {
+				skip++
+				continue
+			}
+			if blockElements[t.Name.Local] {
+				flush()
+			}
+			if id := attr(t, "id"); id != "" {
+				anchors[docPath+"#"+id] = len(lines)
+			}
+		case xml.EndElement:
+			if skippedElements[t.Name.Local] {
+				skip--
+				continue
+			}
+			if blockElements[t.Name.Local] {
+				flush()
+			}
+		case xml.CharData:
+			if skip == 0 {
+				cur.Write(t)
+			}
+		}
+	}
+	flush()
+	return lines
+}
@@ -0,0 +1,155 @@
+// epub_test
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func buildTestEPUB(t *testing.T, files map[string]string) []byte {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	buf := buildTestEPUB(t, map[string]string{
+		"META-INF/container.xml": `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`,
+		"OEBPS/content.opf": `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>测试</dc:title><dc:creator>作者</dc:creator></metadata>
+<manifest>
+<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+<item id="c1" href="text/c1.xhtml" media-type="application/xhtml+xml"/>
+<item id="c2" href="text/c2.xhtml" media-type="application/xhtml+xml"/>
+</manifest>
+<spine><itemref idref="c1"/><itemref idref="c2"/></spine>
+</package>`,
+		"OEBPS/nav.xhtml": `<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops"><body>
+<nav epub:type="toc"><ol>
+<li><a href="text/c1.xhtml">第一卷</a><ol>
+<li><a href="text/c1.xhtml#s2">第一章</a></li>
+<li><a href="text/c2.xhtml">第二章</a></li>
+</ol></li>
+</ol></nav></body></html>`,
+		"OEBPS/text/c1.xhtml": `<html><head><title>ignored</title></head><body>
+<h1>第一卷</h1><p>序</p>
+<h2 id="s2">第一章</h2><p>第一段&nbsp;内容</p><p>第二段<br/>换行</p></body></html>`,
+		"OEBPS/text/c2.xhtml": `<html><body><h2>第二章</h2><p>结束</p></body></html>`,
+	})
+	book, err := Parse(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book.Title != "测试" || book.Author != "作者" {
+		t.Fatalf("wrong metadata %v, %v", book.Title, book.Author)
+	}
+	wantLines := []string{"第一卷", "序", "第一章", "第一段 内容", "第二段", "换行", "第二章", "结束"}
+	if len(book.Lines) != len(wantLines) {
+		t.Fatalf("expect %d lines, got %q", len(wantLines), book.Lines)
+	}
+	for i, l := range wantLines {
+		if string(book.Lines[i]) != l {
+			t.Fatalf("line %d expect %q, got %q", i, l, book.Lines[i])
+		}
+	}
+	wantTOC := []TOCEntry{{"第一卷", 0, 0}, {"第一章", 2, 1}, {"第二章", 6, 1}}
+	if len(book.TOC) != len(wantTOC) {
+		t.Fatalf("expect toc %v, got %v", wantTOC, book.TOC)
+	}
+	for i := range wantTOC {
+		if book.TOC[i] != wantTOC[i] {
+			t.Fatalf("toc %d expect %v, got %v", i, wantTOC[i], book.TOC[i])
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	chapters := []Chapter{
+		{Title: "第一章 <开始>", Paragraphs: []string{"第一段", "A & B"}},
+		{Title: "第二章", Paragraphs: []string{"结束"}},
+	}
+	buf := new(bytes.Buffer)
+	err := Write(buf, Metadata{ID: "http://example.com/book", Title: "测试", Author: "作者"}, chapters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zr.File[0].Name != "mimetype" || zr.File[0].Method != zip.Store {
+		t.Fatal("mimetype is not the first uncompressed entry")
+	}
+	book, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book.Title != "测试" || book.Author != "作者" {
+		t.Fatalf("wrong metadata %v, %v", book.Title, book.Author)
+	}
+	wantLines := []string{"第一章 <开始>", "第一段", "A & B", "第二章", "结束"}
+	if len(book.Lines) != len(wantLines) {
+		t.Fatalf("expect %d lines, got %q", len(wantLines), book.Lines)
+	}
+	for i, l := range wantLines {
+		if string(book.Lines[i]) != l {
+			t.Fatalf("line %d expect %q, got %q", i, l, book.Lines[i])
+		}
+	}
+	if len(book.TOC) != 2 || book.TOC[1].Line != 3 {
+		t.Fatalf("wrong toc %v", book.TOC)
+	}
+}
+
+func TestWriteCover(t *testing.T) {
+	meta := Metadata{
+		ID:          "book",
+		Title:       "测试",
+		Description: "简介",
+		Subjects:    []string{"玄幻"},
+		Cover:       []byte("png"),
+		CoverType:   "image/png",
+	}
+	buf := new(bytes.Buffer)
+	if err := Write(buf, meta, []Chapter{{Title: "第一章", Paragraphs: []string{"内容"}}}); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, f := range zr.File {
+		if f.Name == "OEBPS/cover.png" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("cover image is not written")
+	}
+	opf := meta.opf(nil)
+	for _, want := range []string{`properties="cover-image"`, "<dc:description>简介</dc:description>", "<dc:subject>玄幻</dc:subject>"} {
+		if !strings.Contains(opf, want) {
+			t.Fatalf("expect %v in opf", want)
+		}
+	}
+	if book, err := Parse(buf.Bytes()); err != nil || len(book.Lines) != 2 {
+		t.Fatalf("failed to parse book with cover, %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Metadata is the metadata of exported book
+type Metadata struct {
+	// ID is unique identifier of the book, e.g. its URL
+	ID         string
+	Title      string
+	Author     string
+	Source     string
+	Publisher  string
+	Language   string
+	LastUpdate time.Time
+	// Description is the synopsis of the book
+	Description string
+	// Subjects are genre tags
+	Subjects []string
+	// Cover is the cover image, with MIME type CoverType, no cover if empty
+	Cover     []byte
+	CoverType string
+}
+
+// Chapter is a chapter of exported book, one XHTML document per chapter
+type Chapter struct {
+	Title      string
+	Paragraphs []string
+}
+
+func escape(s string) string {
+	buf := new(bytes.Buffer)
+	xml.EscapeText(buf, []byte(s))
+	return buf.String()
+}
+
+// coverFileName returns name of the cover image file by its MIME type
+func (meta Metadata) coverFileName() string {
+	ext := ".jpg"
+	switch meta.CoverType {
+	case "image/png":
+		ext = ".png"
+	case "image/gif":
+		ext = ".gif"
+	case "image/webp":
+		ext = ".webp"
+	}
+	return "cover" + ext
+}
+
+func (meta Metadata) coverType() string {
+	if meta.CoverType == "" {
+		return "image/jpeg"
+	}
+	return meta.CoverType
+}
+
+func chapterFileName(i int) string {
+	return fmt.Sprintf("chapter%04d.xhtml", i+1)
+}
+
+const containerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+func (meta Metadata) opf(chapters []Chapter) string {
+	b := new(strings.Builder)
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="bookid">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+`)
+	fmt.Fprintf(b, "    <dc:identifier id=\"bookid\">%v</dc:identifier>\n", escape(meta.ID))
+	fmt.Fprintf(b, "    <dc:title>%v</dc:title>\n", escape(meta.Title))
+	fmt.Fprintf(b, "    <dc:language>%v</dc:language>\n", escape(meta.lang()))
+	if meta.Author != "" {
+		fmt.Fprintf(b, "    <dc:creator>%v</dc:creator>\n", escape(meta.Author))
+	}
+	if meta.Source != "" {
+		fmt.Fprintf(b, "    <dc:source>%v</dc:source>\n", escape(meta.Source))
+	}
+	if meta.Publisher != "" {
+		fmt.Fprintf(b, "    <dc:publisher>%v</dc:publisher>\n", escape(meta.Publisher))
+	}
+	if meta.Description != "" {
+		fmt.Fprintf(b, "    <dc:description>%v</dc:description>\n", escape(meta.Description))
+	}
+	for _, subject := range meta.Subjects {
+		fmt.Fprintf(b, "    <dc:subject>%v</dc:subject>\n", escape(subject))
+	}
+	if len(meta.Cover) > 0 {
+		// for EPUB2 readers
+		b.WriteString("    <meta name=\"cover\" content=\"cover\"/>\n")
+	}
+	fmt.Fprintf(b, "    <meta property=\"dcterms:modified\">%v</meta>\n", meta.LastUpdate.UTC().Format("2006-01-02T15:04:05Z"))
+	b.WriteString(`  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+`)
+	if len(meta.Cover) > 0 {
+		fmt.Fprintf(b, "    <item id=\"cover\" href=\"%v\" media-type=\"%v\" properties=\"cover-image\"/>\n", meta.coverFileName(), escape(meta.coverType()))
+	}
+	for i := range chapters {
+		fmt.Fprintf(b, "    <item id=\"c%d\" href=\"%v\" media-type=\"application/xhtml+xml\"/>\n", i+1, chapterFileName(i))
+	}
+	b.WriteString("  </manifest>\n  <spine toc=\"ncx\">\n")
+	for i := range chapters {
+		fmt.Fprintf(b, "    <itemref idref=\"c%d\"/>\n", i+1)
+	}
+	b.WriteString("  </spine>\n</package>\n")
+	return b.String()
+}
+
+func xhtmlHead(lang, title string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="%v" lang="%v">
+<head>
+  <meta charset="UTF-8"/>
+  <title>%v</title>
+</head>
+<body>
+`, lang, lang, escape(title))
+}
+
+func (meta Metadata) nav(chapters []Chapter) string {
+	b := new(strings.Builder)
+	b.WriteString(xhtmlHead(meta.lang(), meta.Title))
+	b.WriteString("  <nav epub:type=\"toc\" id=\"toc\">\n")
+	fmt.Fprintf(b, "    <h1>%v</h1>\n    <ol>\n", escape(meta.Title))
+	for i, ch := range chapters {
+		fmt.Fprintf(b, "      <li><a href=\"%v\">%v</a></li>\n", chapterFileName(i), escape(ch.Title))
+	}
+	b.WriteString("    </ol>\n  </nav>\n</body>\n</html>\n")
+	return b.String()
+}
+
+// ncx returns the EPUB2 ToC, for older readers
+func (meta Metadata) ncx(chapters []Chapter) string {
+	b := new(strings.Builder)
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+  <head>
+`)
+	fmt.Fprintf(b, "    <meta name=\"dtb:uid\" content=\"%v\"/>\n", escape(meta.ID))
+	fmt.Fprintf(b, "  </head>\n  <docTitle><text>%v</text></docTitle>\n  <navMap>\n", escape(meta.Title))
+	for i, ch := range chapters {
+		fmt.Fprintf(b, "    <navPoint id=\"np%d\" playOrder=\"%d\"><navLabel><text>%v</text></navLabel><content src=\"%v\"/></navPoint>\n",
+			i+1, i+1, escape(ch.Title), chapterFileName(i))
+	}
+	b.WriteString("  </navMap>\n</ncx>\n")
+	return b.String()
+}
+
+func (meta Metadata) lang() string {
+	if meta.Language == "" {
+		return "zh"
+	}
+	return meta.Language
+}
+
+func (meta Metadata) chapter(ch Chapter) string {
+	b := new(strings.Builder)
+	b.WriteString(xhtmlHead(meta.lang(), ch.Title))
+	fmt.Fprintf(b, "  <h2>%v</h2>\n", escape(ch.Title))
+	for _, p := range ch.Paragraphs {
+		fmt.Fprintf(b, "  <p>%v</p>\n", escape(p))
+	}
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
+
+// Write writes an EPUB3 book to w
+func Write(w io.Writer, meta Metadata, chapters []Chapter) error {
+	zw := zip.NewWriter(w)
+	// mimetype must be the first entry and not compressed
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err = mw.Write([]byte("application/epub+zip")); err != nil {
+		return err
+	}
+	files := []struct {
+		name, content string
+	}{
+		{containerPath, containerXML},
+		{"OEBPS/content.opf", meta.opf(chapters)},
+		{"OEBPS/nav.xhtml", meta.nav(chapters)},
+		{"OEBPS/toc.ncx", meta.ncx(chapters)},
+	}
+	for i, ch := range chapters {
+		files = append(files, struct{ name, content string }{"OEBPS/" + chapterFileName(i), meta.chapter(ch)})
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(fw, f.content); err != nil {
+			return err
+		}
+	}
+	if len(meta.Cover) > 0 {
+		fw, err := zw.Create("OEBPS/" + meta.coverFileName())
+		if err != nil {
+			return err
+		}
+		if _, err = fw.Write(meta.Cover); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
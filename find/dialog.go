@@ -0,0 +1,243 @@
+package find
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/hujun-open/dvlist"
+	"github.com/hujun-open/golitebook/liteview"
+)
+
+// ResultHandler is called with every new batch of matches, and with nil after all matches are cleared
+type ResultHandler func(added MatchList)
+
+// JumpHandler is called when user select a match
+type JumpHandler func(m Match)
+
+type FindDialog struct {
+	fyne.Window
+	kwEntry     *widget.Entry
+	regexCheck  *widget.Check
+	statusLabel *widget.Label
+	list        *dvlist.DVList
+	// getVal returns the text to search
+	getVal func() liteview.Lines
+	// getPos returns the current reading postion
+	getPos  func() (int, int)
+	resultH ResultHandler
+	jumpH   JumpHandler
+	view    *matchView
+	// cur is the index of current match
+	cur       int
+	cancel    context.CancelFunc
+	searchGen uint64
+	mux       *sync.RWMutex
+}
+
+func NewFindDialog(getVal func() liteview.Lines, getPos func() (int, int), rh ResultHandler, jh JumpHandler) *FindDialog {
+	r := new(FindDialog)
+	r.Window = fyne.CurrentApp().NewWindow("书内搜索")
+	r.SetCloseIntercept(r.Hide)
+	r.getVal = getVal
+	r.getPos = getPos
+	r.resultH = rh
+	r.jumpH = jh
+	r.mux = new(sync.RWMutex)
+	r.cur = -1
+	r.view = newMatchView()
+	r.kwEntry = widget.NewEntry()
+	r.kwEntry.OnSubmitted = func(string) { r.search() }
+	r.regexCheck = widget.NewCheck("正则表达式", nil)
+	r.statusLabel = widget.NewLabel("")
+	r.list, _ = dvlist.NewDVList(r.view, dvlist.WithDoubleClickHandler(r.onDoubleClick))
+	inputContainer := fyne.NewContainerWithLayout(
+		layout.NewBorderLayout(nil, nil, nil, r.regexCheck),
+		r.regexCheck, r.kwEntry)
+	topContainer := fyne.NewContainerWithLayout(layout.NewVBoxLayout(),
+		inputContainer,
+		r.statusLabel,
+	)
+	buttonContainer := fyne.NewContainerWithLayout(layout.NewVBoxLayout(),
+		widget.NewSeparator(),
+		fyne.NewContainerWithLayout(layout.NewGridLayout(5),
+			widget.NewButton("搜索", r.search),
+			widget.NewButton("上一个", r.Prev),
+			widget.NewButton("下一个", r.Next),
+			widget.NewButton("停止", r.stop),
+			widget.NewButton("关闭", r.Hide),
+		),
+	)
+	r.SetContent(fyne.NewContainerWithLayout(
+		layout.NewBorderLayout(topContainer, buttonContainer, nil, nil),
+		topContainer, buttonContainer, r.list))
+	r.Resize(fyne.NewSize(600, 800))
+	return r
+}
+
+func (fdiag *FindDialog) Show() {
+	fdiag.Window.Show()
+	fdiag.Canvas().Focus(fdiag.kwEntry)
+}
+
+// Reset cancels ongoing search and clears all matches, it should be called when the text changes
+func (fdiag *FindDialog) Reset() {
+	fdiag.stop()
+	view := newMatchView()
+	fdiag.mux.Lock()
+	fdiag.view = view
+	fdiag.cur = -1
+	fdiag.searchGen++
+	fdiag.mux.Unlock()
+	fdiag.list.SetData(view)
+	fdiag.statusLabel.SetText("")
+	if fdiag.resultH != nil {
+		fdiag.resultH(nil)
+	}
+}
+
+func (fdiag *FindDialog) stop() {
+	fdiag.mux.Lock()
+	defer fdiag.mux.Unlock()
+	if fdiag.cancel != nil {
+		fdiag.cancel()
+		fdiag.cancel = nil
+	}
+}
+
+func (fdiag *FindDialog) search() {
+	q := Query{
+		Keyword: strings.TrimSpace(fdiag.kwEntry.Text),
+		IsRegex: fdiag.regexCheck.Checked,
+	}
+	if q.Keyword == "" {
+		return
+	}
+	if _, err := q.compile(); err != nil {
+		fdiag.statusLabel.SetText(fmt.Sprintf("无效的表达式, %v", err))
+		return
+	}
+	fdiag.Reset()
+	ctx, cancel := context.WithCancel(context.Background())
+	fdiag.mux.Lock()
+	fdiag.cancel = cancel
+	gen := fdiag.searchGen
+	fdiag.mux.Unlock()
+	fdiag.statusLabel.SetText("搜索中...")
+	go fdiag.run(ctx, cancel, gen, q, fdiag.getVal())
+}
+
+// run does the search in background, gen is used to drop results from a stale search
+func (fdiag *FindDialog) run(ctx context.Context, cancel context.CancelFunc, gen uint64, q Query, val liteview.Lines) {
+	defer cancel()
+	err := Search(ctx, val, q, func(batch MatchList) {
+		fdiag.mux.Lock()
+		if gen != fdiag.searchGen {
+			fdiag.mux.Unlock()
+			return
+		}
+		view := fdiag.view
+		fdiag.mux.Unlock()
+		// only the new batch is added, found matches are not copied or highlighted again
+		view.append(batch)
+		fdiag.list.SetData(view)
+		fdiag.statusLabel.SetText(fmt.Sprintf("搜索中... 已找到 %d 处", view.count()))
+		if fdiag.resultH != nil {
+			fdiag.resultH(batch)
+		}
+	})
+	fdiag.mux.RLock()
+	stale := gen != fdiag.searchGen
+	count := fdiag.view.count()
+	fdiag.mux.RUnlock()
+	if stale {
+		return
+	}
+	switch {
+	case err == context.Canceled:
+		return
+	case err != nil:
+		fdiag.statusLabel.SetText(err.Error())
+		return
+	case count >= MaxMatches:
+		fdiag.statusLabel.SetText(fmt.Sprintf("找到超过 %d 处, 只显示前 %d 处", MaxMatches, MaxMatches))
+	default:
+		fdiag.statusLabel.SetText(fmt.Sprintf("共找到 %d 处", count))
+	}
+}
+
+// currentView returns the matches of current search
+func (fdiag *FindDialog) currentView() *matchView {
+	fdiag.mux.RLock()
+	defer fdiag.mux.RUnlock()
+	return fdiag.view
+}
+
+func (fdiag *FindDialog) onDoubleClick(row int) {
+	if i := fdiag.currentView().index(row); i >= 0 {
+		fdiag.jump(i)
+	}
+}
+
+// jump selects match i and shows it in the book
+func (fdiag *FindDialog) jump(i int) {
+	view := fdiag.currentView()
+	if i < 0 || i >= view.count() {
+		return
+	}
+	fdiag.mux.Lock()
+	fdiag.cur = i
+	fdiag.mux.Unlock()
+	if row := view.row(i); row >= 0 {
+		fdiag.list.ScrollTo(row)
+		fdiag.list.SetSelection(row, true)
+	}
+	fdiag.statusLabel.SetText(fmt.Sprintf("%d/%d", i+1, view.count()))
+	if fdiag.jumpH != nil {
+		fdiag.jumpH(view.get(i))
+	}
+}
+
+// Next jumps to the next match; if no match is selected yet, jump to the first match after current reading postion
+func (fdiag *FindDialog) Next() {
+	view := fdiag.currentView()
+	n := view.count()
+	if n == 0 {
+		return
+	}
+	fdiag.mux.RLock()
+	i := fdiag.cur
+	fdiag.mux.RUnlock()
+	if i < 0 {
+		i = view.after(fdiag.getPos())
+		if i < 0 {
+			i = 0
+		}
+	} else {
+		i = (i + 1) % n
+	}
+	fdiag.jump(i)
+}
+
+// Prev jumps to the previous match; if no match is selected yet, jump to the last match before current reading postion
+func (fdiag *FindDialog) Prev() {
+	view := fdiag.currentView()
+	n := view.count()
+	if n == 0 {
+		return
+	}
+	fdiag.mux.RLock()
+	i := fdiag.cur
+	fdiag.mux.RUnlock()
+	if i < 0 {
+		i = view.after(fdiag.getPos())
+	}
+	if i <= 0 {
+		i = n
+	}
+	fdiag.jump(i - 1)
+}
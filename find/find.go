@@ -0,0 +1,324 @@
+// find is the in-book full-text search
+package find
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/hujun-open/golitebook/liteview"
+)
+
+const (
+	// number of runes before and after the match included in Match.Context
+	contextRunes = 15
+	// number of matches accumulated before handing a batch to the result handler
+	resultBatchSize = 500
+	// number of lines scanned between checks for cancellation
+	cancelCheckLines = 1000
+	// search stops after MaxMatches matches
+	MaxMatches = 100000
+)
+
+// Match is a single hit, Pos and Len are in runes
+type Match struct {
+	Line, Pos, Len int
+	Context        string
+}
+
+func (m Match) Range() liteview.TextRange {
+	return liteview.TextRange{Pos: m.Pos, Len: m.Len}
+}
+
+type MatchList []Match
+
+func (ml MatchList) Len() int {
+	return len(ml)
+}
+func (ml MatchList) Fields() []string {
+	return []string{"行", "内容"}
+}
+func (ml MatchList) Item(id int) []string {
+	if id < 0 || id >= ml.Len() {
+		return nil
+	}
+	return []string{fmt.Sprintf("%d", ml[id].Line+1), ml[id].Context}
+}
+func (ml MatchList) Sort(field int, ascend bool) {
+	less := func(i, j int) bool {
+		if ml[i].Line != ml[j].Line {
+			return ml[i].Line < ml[j].Line
+		}
+		return ml[i].Pos < ml[j].Pos
+	}
+	sort.SliceStable(ml, func(i, j int) bool {
+		if ascend {
+			return less(i, j)
+		}
+		return less(j, i)
+	})
+}
+
+// matchView is the matches shown in the list of the dialog, in reading order or reversed,
+// only the ones with a field contains the filter keyword are shown;
+// new matches are appended to it without rebuilding the shown ones
+type matchView struct {
+	mux     *sync.RWMutex
+	matches MatchList
+	// rows are indexes of shown matches in ascending order
+	rows []int
+	// filter keyword and field
+	kw         string
+	kwField    int
+	descending bool
+}
+
+func newMatchView() *matchView {
+	return &matchView{
+		mux:     new(sync.RWMutex),
+		matches: MatchList{},
+		rows:    []int{},
+		kwField: -1,
+	}
+}
+
+// shown returns true if match i contains the filter keyword, caller must hold v.mux
+func (v *matchView) shown(i int) bool {
+	kw := strings.ToLower(strings.TrimSpace(v.kw))
+	if kw == "" {
+		return true
+	}
+	for fi, f := range v.matches.Item(i) {
+		if (v.kwField == -1 || fi == v.kwField) && strings.Contains(strings.ToLower(f), kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// append adds a new batch of matches
+func (v *matchView) append(batch MatchList) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+	for _, m := range batch {
+		v.matches = append(v.matches, m)
+		if i := len(v.matches) - 1; v.shown(i) {
+			v.rows = append(v.rows, i)
+		}
+	}
+}
+
+// count returns number of all matches
+func (v *matchView) count() int {
+	v.mux.RLock()
+	defer v.mux.RUnlock()
+	return len(v.matches)
+}
+
+// get returns match i
+func (v *matchView) get(i int) Match {
+	v.mux.RLock()
+	defer v.mux.RUnlock()
+	return v.matches[i]
+}
+
+// after is MatchList.After of all matches
+func (v *matchView) after(lineid, linepos int) int {
+	v.mux.RLock()
+	defer v.mux.RUnlock()
+	return v.matches.After(lineid, linepos)
+}
+
+// index returns index of the match shown in row id, -1 if id is out of range
+func (v *matchView) index(id int) int {
+	v.mux.RLock()
+	defer v.mux.RUnlock()
+	return v.rowIndex(id)
+}
+
+// rowIndex is index, caller must hold v.mux
+func (v *matchView) rowIndex(id int) int {
+	if id < 0 || id >= len(v.rows) {
+		return -1
+	}
+	if v.descending {
+		id = len(v.rows) - 1 - id
+	}
+	return v.rows[id]
+}
+
+// row returns the row showing match i, -1 if it is not shown
+func (v *matchView) row(i int) int {
+	v.mux.RLock()
+	defer v.mux.RUnlock()
+	r := sort.SearchInts(v.rows, i)
+	if r >= len(v.rows) || v.rows[r] != i {
+		return -1
+	}
+	if v.descending {
+		r = len(v.rows) - 1 - r
+	}
+	return r
+}
+
+func (v *matchView) Len() int {
+	v.mux.RLock()
+	defer v.mux.RUnlock()
+	return len(v.rows)
+}
+
+func (v *matchView) Fields() []string {
+	return MatchList{}.Fields()
+}
+
+func (v *matchView) Item(id int) []string {
+	v.mux.RLock()
+	defer v.mux.RUnlock()
+	return v.matches.Item(v.rowIndex(id))
+}
+
+// Sort shows matches in reading order or reversed, matches are always sorted by position
+func (v *matchView) Sort(field int, ascend bool) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+	v.descending = !ascend
+}
+
+// Filter shows only matches that field i contains kw, i == -1 means any field;
+// an empty kw clears the filter
+func (v *matchView) Filter(kw string, i int) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+	v.kw = kw
+	v.kwField = i
+	v.rows = []int{}
+	for mi := range v.matches {
+		if v.shown(mi) {
+			v.rows = append(v.rows, mi)
+		}
+	}
+}
+
+// ByLine returns the matches as highlights for liteview.LiteView.SetHighlights and AddHighlights
+func (ml MatchList) ByLine() map[int][]liteview.TextRange {
+	r := make(map[int][]liteview.TextRange)
+	for _, m := range ml {
+		r[m.Line] = append(r[m.Line], m.Range())
+	}
+	return r
+}
+
+// After returns index of the first match at or after the postion specified by lineid and linepos,
+// return -1 if there is none
+func (ml MatchList) After(lineid, linepos int) int {
+	i := sort.Search(len(ml), func(i int) bool {
+		return ml[i].Line > lineid || (ml[i].Line == lineid && ml[i].Pos >= linepos)
+	})
+	if i >= len(ml) {
+		return -1
+	}
+	return i
+}
+
+type Query struct {
+	Keyword string
+	IsRegex bool
+}
+
+// matchFunc returns byte index pairs of all matches in line
+type matchFunc func(line []byte) [][]int
+
+func (q Query) compile() (matchFunc, error) {
+	if q.Keyword == "" {
+		return nil, fmt.Errorf("empty keyword")
+	}
+	if q.IsRegex {
+		re, err := regexp.Compile(q.Keyword)
+		if err != nil {
+			return nil, err
+		}
+		return func(line []byte) [][]int {
+			return re.FindAllIndex(line, -1)
+		}, nil
+	}
+	kw := []byte(q.Keyword)
+	return func(line []byte) [][]int {
+		var r [][]int
+		offset := 0
+		for {
+			i := bytes.Index(line[offset:], kw)
+			if i < 0 {
+				return r
+			}
+			r = append(r, []int{offset + i, offset + i + len(kw)})
+			offset += i + len(kw)
+		}
+	}, nil
+}
+
+// getContext returns the match line[start:end] with up to contextRunes runes around it
+func getContext(line []byte, start, end int) string {
+	cstart := start
+	for i := 0; i < contextRunes && cstart > 0; i++ {
+		_, size := utf8.DecodeLastRune(line[:cstart])
+		cstart -= size
+	}
+	cend := end
+	for i := 0; i < contextRunes && cend < len(line); i++ {
+		_, size := utf8.DecodeRune(line[cend:])
+		cend += size
+	}
+	return string(bytes.TrimSpace(line[cstart:cend]))
+}
+
+// Search scans val for q, h is called with every new batch of matches in order;
+// it returns ctx.Err() if ctx is cancelled before the scan finishes
+func Search(ctx context.Context, val liteview.Lines, q Query, h func(MatchList)) error {
+	match, err := q.compile()
+	if err != nil {
+		return err
+	}
+	batch := MatchList{}
+	total := 0
+	for lineid := 0; lineid < val.Len(); lineid++ {
+		if lineid%cancelCheckLines == 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+		}
+		line := val.Line(lineid)
+		for _, loc := range match(line) {
+			if loc[1] == loc[0] {
+				//skip empty match of regex
+				continue
+			}
+			pos := utf8.RuneCount(line[:loc[0]])
+			batch = append(batch, Match{
+				Line:    lineid,
+				Pos:     pos,
+				Len:     utf8.RuneCount(line[loc[0]:loc[1]]),
+				Context: getContext(line, loc[0], loc[1]),
+			})
+			total++
+			if total >= MaxMatches {
+				h(batch)
+				return nil
+			}
+		}
+		if len(batch) >= resultBatchSize {
+			h(batch)
+			batch = MatchList{}
+		}
+	}
+	if len(batch) > 0 {
+		h(batch)
+	}
+	return nil
+}
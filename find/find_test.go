@@ -0,0 +1,77 @@
+// find_test
+package find
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hujun-open/golitebook/liteview"
+)
+
+func TestSearch(t *testing.T) {
+	val := liteview.ByteLines{
+		[]byte("第一章 开始"),
+		[]byte("没有"),
+		[]byte("第二章 第二次开始"),
+	}
+	cases := []struct {
+		q    Query
+		want MatchList
+	}{
+		{
+			q: Query{Keyword: "开始"},
+			want: MatchList{
+				{Line: 0, Pos: 4, Len: 2},
+				{Line: 2, Pos: 7, Len: 2},
+			},
+		},
+		{
+			q: Query{Keyword: "第.", IsRegex: true},
+			want: MatchList{
+				{Line: 0, Pos: 0, Len: 2},
+				{Line: 2, Pos: 0, Len: 2},
+				{Line: 2, Pos: 4, Len: 2},
+			},
+		},
+	}
+	for _, c := range cases {
+		var got MatchList
+		err := Search(context.Background(), val, c.q, func(batch MatchList) {
+			got = append(got, batch...)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(c.want) {
+			t.Fatalf("query %+v, expect %d matches, got %d", c.q, len(c.want), len(got))
+		}
+		for i := range got {
+			if got[i].Line != c.want[i].Line || got[i].Pos != c.want[i].Pos || got[i].Len != c.want[i].Len {
+				t.Fatalf("query %+v, match %d expect %+v, got %+v", c.q, i, c.want[i], got[i])
+			}
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Search(ctx, val, Query{Keyword: "开始"}, func(MatchList) {}); err != context.Canceled {
+		t.Fatalf("expect cancelled, got %v", err)
+	}
+}
+
+func TestMatchView(t *testing.T) {
+	v := newMatchView()
+	v.append(MatchList{{Line: 0, Context: "开始"}, {Line: 1, Context: "结束"}})
+	v.Filter("开始", 1)
+	v.append(MatchList{{Line: 2, Context: "又开始"}})
+	if v.Len() != 2 || v.index(1) != 2 || v.row(1) != -1 {
+		t.Fatalf("expect matches 0 and 2 shown, got %d rows", v.Len())
+	}
+	v.Sort(0, false)
+	if v.Item(0)[1] != "又开始" || v.row(0) != 1 {
+		t.Fatalf("expect reversed rows, got %v", v.Item(0))
+	}
+	v.Filter("", -1)
+	if v.Len() != 3 || v.count() != 3 || v.index(0) != 2 {
+		t.Fatalf("expect all matches reversed, got %d rows", v.Len())
+	}
+}
@@ -2,26 +2,145 @@
 package history
 
 import (
+	"bytes"
 	"encoding/json"
 	"io/ioutil"
 	"path/filepath"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/hujun-open/golitebook/conf"
+	"github.com/hujun-open/golitebook/liteview"
 )
 
 func getHistoryFilePath() string {
 	return filepath.Join(conf.ConfDir(), "history")
 }
 
-// ReadingHistory key is the base filename of book, value is the starting line
-type ReadingHistory map[string]int
+// max number of runes in the anchor of a position
+const anchorLen = 16
 
-func (h ReadingHistory) Update(bookpath string, startline int) {
-	h[bookpath] = startline
+// max number of lines searched for the anchor before and after the saved line,
+// so that locating a position doesn't scan the whole book
+const anchorSearchLines = 2000
+
+// Position is the reading position of a book
+type Position struct {
+	Line, LinePos int
+	// Anchor is the text starting at the position, it is used to find the position
+	// after lines of the book are changed, e.g. reformatted
+	Anchor string `json:",omitempty"`
+}
+
+// UnmarshalJSON also accepts a line number, which is the position saved by older version
+func (p *Position) UnmarshalJSON(b []byte) error {
+	var line int
+	if err := json.Unmarshal(b, &line); err == nil {
+		*p = Position{Line: line}
+		return nil
+	}
+	type position Position
+	return json.Unmarshal(b, (*position)(p))
+}
+
+// NewPosition returns the position at rune linepos of line lineid in lines, with its anchor
+func NewPosition(lines liteview.Lines, lineid, linepos int) Position {
+	r := Position{Line: lineid, LinePos: linepos}
+	if lineid < 0 || lineid >= lines.Len() {
+		return r
+	}
+	runes := bytes.Runes(lines.Line(lineid))
+	if linepos < 0 || linepos >= len(runes) {
+		return r
+	}
+	// leading spaces are skipped since indent could be changed
+	runes = []rune(strings.TrimLeft(string(runes[linepos:]), " \t　"))
+	if len(runes) > anchorLen {
+		runes = runes[:anchorLen]
+	}
+	r.Anchor = string(runes)
+	return r
+}
+
+// anchorAt returns the rune position of anchor in line, search starts from rune position from
+func anchorAt(line []byte, anchor string, from int) (int, bool) {
+	start := 0
+	for i := 0; i < from && start < len(line); i++ {
+		_, size := utf8.DecodeRune(line[start:])
+		start += size
+	}
+	if i := bytes.Index(line[start:], []byte(anchor)); i >= 0 {
+		return from + utf8.RuneCount(line[start:start+i]), true
+	}
+	if i := bytes.Index(line, []byte(anchor)); i >= 0 {
+		return utf8.RuneCount(line[:i]), true
+	}
+	return 0, false
+}
+
+// Locate returns the line and rune position of p in lines;
+// if the text at p doesn't match its anchor, the anchor is searched from p.Line towards the beginning
+// and then towards the end, since lines are usually merged by reformatting;
+// at most anchorSearchLines lines are searched in each direction, p.Line is returned if the anchor is not found
+func (p Position) Locate(lines liteview.Lines) (int, int) {
+	n := lines.Len()
+	if n == 0 {
+		return 0, 0
+	}
+	line := p.Line
+	if line >= n {
+		line = n - 1
+	}
+	if line < 0 {
+		line = 0
+	}
+	if p.Anchor == "" {
+		if line != p.Line {
+			return line, 0
+		}
+		return line, p.LinePos
+	}
+	if pos, ok := anchorAt(lines.Line(line), p.Anchor, p.LinePos); ok {
+		return line, pos
+	}
+	for i := line - 1; i >= 0 && i >= line-anchorSearchLines; i-- {
+		if pos, ok := anchorAt(lines.Line(i), p.Anchor, 0); ok {
+			return i, pos
+		}
+	}
+	for i := line + 1; i < n && i <= line+anchorSearchLines; i++ {
+		if pos, ok := anchorAt(lines.Line(i), p.Anchor, 0); ok {
+			return i, pos
+		}
+	}
+	return line, 0
+}
+
+// ReadingHistory key is the id of book (see package bookid), value is the reading position
+type ReadingHistory map[string]Position
+
+func (h ReadingHistory) Update(bookpath string, pos Position) {
+	h[bookpath] = pos
+}
+
+// RenameBook moves the position of book old to book new, unless new already has one
+func (h ReadingHistory) RenameBook(old, new string) {
+	pos, ok := h[old]
+	if _, exists := h[new]; !ok || exists {
+		return
+	}
+	h[new] = pos
+	delete(h, old)
+}
+
+// GetPosition returns the reading position of book, the zero position if the book is not read before
+func (h ReadingHistory) GetPosition(bookpath string) Position {
+	return h[bookpath]
 }
+
 func (h ReadingHistory) GetStartLine(bookpath string) int {
-	if line, ok := h[bookpath]; ok {
-		return line
+	if pos, ok := h[bookpath]; ok {
+		return pos.Line
 	}
 	return 0
 }
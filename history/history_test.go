@@ -2,14 +2,100 @@
 package history
 
 import (
+	"encoding/json"
+	"os"
 	"testing"
+
+	"github.com/hujun-open/golitebook/liteview"
 )
 
 func TestHistory(t *testing.T) {
-	History.Update("book1", 10)
-	History.Update("book2", 10)
+	History.Update("book1", Position{Line: 10})
+	History.Update("book2", Position{Line: 10, LinePos: 3, Anchor: "abc"})
 	err := History.Save()
 	if err != nil {
 		t.Fatal(err)
 	}
 }
+
+func TestRenameBook(t *testing.T) {
+	h := ReadingHistory{"1.txt": Position{Line: 5}, "fp2": Position{Line: 7}}
+	h.RenameBook("1.txt", "fp1")
+	if _, ok := h["1.txt"]; ok || h.GetStartLine("fp1") != 5 {
+		t.Fatalf("position is not moved, %v", h)
+	}
+	h.RenameBook("fp1", "fp2")
+	if h.GetStartLine("fp2") != 7 || h.GetStartLine("fp1") != 5 {
+		t.Fatalf("existing position is overwritten, %v", h)
+	}
+}
+
+func TestLoadOldHistory(t *testing.T) {
+	h := make(ReadingHistory)
+	if err := json.Unmarshal([]byte(`{"book1": 12, "book2": {"Line": 3, "LinePos": 5, "Anchor": "abc"}}`), &h); err != nil {
+		t.Fatal(err)
+	}
+	if h.GetPosition("book1") != (Position{Line: 12}) || h.GetStartLine("book1") != 12 {
+		t.Fatalf("unexpected position of book1 %+v", h.GetPosition("book1"))
+	}
+	if h.GetPosition("book2") != (Position{Line: 3, LinePos: 5, Anchor: "abc"}) {
+		t.Fatalf("unexpected position of book2 %+v", h.GetPosition("book2"))
+	}
+}
+
+func TestLocate(t *testing.T) {
+	orig := liteview.ByteLines{
+		[]byte("第一章"),
+		[]byte("  他走进了房间，"),
+		[]byte("  看见桌上放着一封信。信上写着几个字：明天见。"),
+		[]byte("  第二段"),
+	}
+	pos := NewPosition(orig, 2, 12)
+	if pos.Anchor != "信上写着几个字：明天见。" {
+		t.Fatalf("unexpected anchor %q", pos.Anchor)
+	}
+	if line, linepos := pos.Locate(orig); line != 2 || linepos != 12 {
+		t.Fatalf("expect 2:12 in the same text, got %d:%d", line, linepos)
+	}
+	// lines are merged and re-indented
+	formatted := liteview.ByteLines{
+		[]byte("第一章"),
+		[]byte("    他走进了房间，看见桌上放着一封信。信上写着几个字：明天见。"),
+		[]byte("    第二段"),
+	}
+	if line, linepos := pos.Locate(formatted); line != 1 || linepos != 21 {
+		t.Fatalf("expect 1:21 in formatted text, got %d:%d", line, linepos)
+	}
+	// anchor is not found
+	if line, linepos := (Position{Line: 10, LinePos: 3, Anchor: "没有"}).Locate(formatted); line != 2 || linepos != 0 {
+		t.Fatalf("expect 2:0 for missing anchor, got %d:%d", line, linepos)
+	}
+	// position saved by older version
+	if line, linepos := (Position{Line: 1}).Locate(formatted); line != 1 || linepos != 0 {
+		t.Fatalf("expect 1:0 without anchor, got %d:%d", line, linepos)
+	}
+	// anchor far away from the saved line is not searched
+	far := make(liteview.ByteLines, anchorSearchLines+10)
+	for i := range far {
+		far[i] = []byte("正文")
+	}
+	far[len(far)-1] = []byte("信上写着几个字：明天见。")
+	if line, linepos := pos.Locate(far); line != 2 || linepos != 0 {
+		t.Fatalf("expect 2:0 for anchor out of search range, got %d:%d", line, linepos)
+	}
+	if line, _ := (Position{Line: len(far) - anchorSearchLines, Anchor: pos.Anchor}).Locate(far); line != len(far)-1 {
+		t.Fatalf("expect anchor in search range found, got %d", line)
+	}
+}
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "history")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	os.MkdirAll(dir+"/litebook", 0755)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,49 @@
+package liteview
+
+import (
+	"bytes"
+)
+
+// Lines is the text displayed by LiteView, it allows the text to be loaded lazily
+type Lines interface {
+	// Len returns the number of lines
+	Len() int
+	// Line returns line i without line ending, caller must not modify the returned slice
+	Line(i int) []byte
+}
+
+// ByteLines is Lines held in memory
+type ByteLines [][]byte
+
+func (bl ByteLines) Len() int {
+	return len(bl)
+}
+
+func (bl ByteLines) Line(i int) []byte {
+	return bl[i]
+}
+
+// indentedLines replaces leading spaces of every line of Lines with prefix
+type indentedLines struct {
+	Lines
+	prefix []byte
+}
+
+func (il indentedLines) Line(i int) []byte {
+	line := bytes.TrimLeft(il.Lines.Line(i), " ")
+	r := make([]byte, 0, len(il.prefix)+len(line))
+	r = append(r, il.prefix...)
+	return append(r, line...)
+}
+
+// ToBytes returns all lines of l in memory
+func ToBytes(l Lines) [][]byte {
+	if bl, ok := l.(ByteLines); ok {
+		return bl
+	}
+	r := make([][]byte, l.Len())
+	for i := range r {
+		r[i] = l.Line(i)
+	}
+	return r
+}
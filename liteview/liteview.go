@@ -33,8 +33,25 @@ const (
 	actScrollToPoSCental
 	actSetUnderline
 	actSetVal
+	actSetHighlight
 )
 
+// ColorNameUnderline is the theme color of underlines, text color is used if the theme doesn't define it
+const ColorNameUnderline fyne.ThemeColorName = "underline"
+
+// underlineColor returns ColorNameUnderline of current theme
+func underlineColor() color.Color {
+	settings := fyne.CurrentApp().Settings()
+	c := settings.Theme().Color(ColorNameUnderline, settings.ThemeVariant())
+	if c == nil {
+		return theme.TextColor()
+	}
+	if _, _, _, a := c.RGBA(); a == 0 {
+		return theme.TextColor()
+	}
+	return c
+}
+
 // getNumLeadingSpaces return number of spaces that has equal width as leadingCount Chinese chars
 func getNumLeadingSpaces(leadingCount int) int {
 	measureStr := ""
@@ -188,18 +205,19 @@ func (lvr *liteViewRender) Layout(layoutsize fyne.Size) {
 	if underLineMode == UnderLineDash {
 		dashImg = NewDashedLine(workingArea.Width,
 			DefaultDashlineHeight, DefaultDashlineWidth,
-			DefaultDashlineInterval, theme.TextColor())
+			DefaultDashlineInterval, underlineColor())
 	}
 L1:
-	for txtLine := lvr.curStartLine; txtLine < len(lvr.lv.Val()); txtLine++ {
+	for txtLine := lvr.curStartLine; txtLine < lvr.lv.Val().Len(); txtLine++ {
 		brokenlines := []*renderLine{}
-		brokenlines, lvr.curEndLinePos = breakLine(bytes.Runes(lvr.lv.Val()[txtLine]),
+		brokenlines, lvr.curEndLinePos = breakLine(bytes.Runes(lvr.lv.Val().Line(txtLine)),
 			txtLine, lvr.curEndLinePos, workingArea.Width, lvr.unitSize.Width, false)
 		for _, line := range brokenlines {
 			t := canvas.NewText(string(line.text), theme.TextColor())
 			txtLineStartPos := fyne.NewPos(lvr.lv.sidePadding,
 				lvr.lv.verticalPadding+float32(numFormatLines)*(lvr.unitSize.Height)+lvr.lv.lineVerticalPadding)
 			// log.Printf("line %d pos at Y %d", txtLine, txtLineStartPos.Y)
+			lvr.addHighlights(line, txtLineStartPos, fsize.Height)
 			t.Move(txtLineStartPos)
 			lvr.overallContainer.Add(t)
 
@@ -209,7 +227,7 @@ L1:
 				pos2 := fyne.NewPos(pos1.X+workingArea.Width, pos1.Y)
 				switch underLineMode {
 				case UnderLineSolid:
-					underLine := canvas.NewLine(theme.TextColor())
+					underLine := canvas.NewLine(underlineColor())
 					underLine.Position1 = pos1
 					underLine.Position2 = pos2
 					// log.Printf("line %d's underline pos at Y %d", txtLine, underLine.Position1.Y)
@@ -241,6 +259,41 @@ L1:
 
 }
 
+// addHighlights adds a rectangle behind every highlighted range of line,
+// pos is the top-left postion of the line's text, h is the height of text
+func (lvr *liteViewRender) addHighlights(line *renderLine, pos fyne.Position, h float32) {
+	lvr.lv.valMux.RLock()
+	hlist := lvr.lv.highlights[line.runeLine]
+	focusedLine, focused := lvr.lv.focusedLine, lvr.lv.focusedRange
+	lvr.lv.valMux.RUnlock()
+	lineEnd := line.runeLinePos + len(line.text)
+	for _, hl := range hlist {
+		start := hl.Pos
+		end := hl.Pos + hl.Len
+		if end <= line.runeLinePos || start >= lineEnd {
+			continue
+		}
+		if start < line.runeLinePos {
+			start = line.runeLinePos
+		}
+		if end > lineEnd {
+			end = lineEnd
+		}
+		start -= line.runeLinePos
+		end -= line.runeLinePos
+		c := theme.SelectionColor()
+		if line.runeLine == focusedLine && hl == focused {
+			c = theme.PrimaryColor()
+		}
+		x := fyne.MeasureText(string(line.text[:start]), theme.TextSize(), fyne.TextStyle{}).Width
+		w := fyne.MeasureText(string(line.text[start:end]), theme.TextSize(), fyne.TextStyle{}).Width
+		rect := canvas.NewRectangle(c)
+		rect.Resize(fyne.NewSize(w, h))
+		rect.Move(fyne.NewPos(pos.X+x, pos.Y))
+		lvr.overallContainer.Add(rect)
+	}
+}
+
 func (lvr *liteViewRender) BackgroundColor() color.Color {
 	return color.Transparent
 }
@@ -270,7 +323,7 @@ func (lvr *liteViewRender) lineUp() {
 		return
 	default:
 		if lvr.lineList[0].runeLinePos > 0 {
-			llist, _ := breakLine(bytes.Runes(lvr.lv.Val()[lvr.lineList[0].runeLine]),
+			llist, _ := breakLine(bytes.Runes(lvr.lv.Val().Line(lvr.lineList[0].runeLine)),
 				lvr.lineList[0].runeLine,
 				lvr.lineList[0].runeLinePos,
 				lvr.curSize.Width,
@@ -285,7 +338,7 @@ func (lvr *liteViewRender) lineUp() {
 				//reached the top
 				return
 			}
-			llist, _ := breakLine(bytes.Runes(lvr.lv.Val()[lvr.lineList[0].runeLine-1]),
+			llist, _ := breakLine(bytes.Runes(lvr.lv.Val().Line(lvr.lineList[0].runeLine-1)),
 				lvr.lineList[0].runeLine-1,
 				0,
 				lvr.curSize.Width,
@@ -314,8 +367,8 @@ func (lvr *liteViewRender) bottom() {
 			canvas.Refresh(lvr.lv)
 		}
 	}()
-	lastlineIndex := len(lvr.lv.Val()) - 1
-	lastlineLen := len(lvr.lv.Val()[lastlineIndex])
+	lastlineIndex := lvr.lv.Val().Len() - 1
+	lastlineLen := len(lvr.lv.Val().Line(lastlineIndex))
 	if lvr.lineList[len(lvr.lineList)-1].runeLine == lastlineIndex &&
 		lvr.lineList[len(lvr.lineList)-1].runeLinePos+
 			len(lvr.lineList[len(lvr.lineList)-1].text) == lastlineLen {
@@ -324,7 +377,7 @@ func (lvr *liteViewRender) bottom() {
 	}
 
 	lvr.curStartLine = lastlineIndex
-	llist, _ := breakLine(bytes.Runes(lvr.lv.Val()[lastlineIndex]),
+	llist, _ := breakLine(bytes.Runes(lvr.lv.Val().Line(lastlineIndex)),
 		lastlineIndex,
 		0,
 		lvr.curSize.Width,
@@ -378,7 +431,7 @@ func (lvr *liteViewRender) pageUp() {
 	allowedLines := lvr.calAllowedLines(lvr.curSize.Height)
 	i := 0
 	if lvr.lineList[0].runeLinePos > 0 {
-		llist, _ := breakLine(bytes.Runes(lvr.lv.Val()[lvr.lineList[0].runeLine]),
+		llist, _ := breakLine(bytes.Runes(lvr.lv.Val().Line(lvr.lineList[0].runeLine)),
 			lvr.lineList[0].runeLine,
 			lvr.lineList[0].runeLinePos,
 			lvr.curSize.Width,
@@ -401,7 +454,7 @@ func (lvr *liteViewRender) pageUp() {
 		return
 	}
 	for rline := lvr.lineList[0].runeLine - 1; rline >= 0; rline-- {
-		llist, _ := breakLine(bytes.Runes(lvr.lv.Val()[rline]),
+		llist, _ := breakLine(bytes.Runes(lvr.lv.Val().Line(rline)),
 			rline,
 			0,
 			lvr.curSize.Width,
@@ -500,7 +553,7 @@ func (lvr *liteViewRender) scrollToPos(center bool) {
 	allowedLines := lvr.calAllowedLines(lvr.curSize.Height) / 2
 	i := 0
 	if lvr.lv.StartLinePos > 0 {
-		llist, _ := breakLine(bytes.Runes(lvr.lv.Val()[lvr.lv.StartLine]),
+		llist, _ := breakLine(bytes.Runes(lvr.lv.Val().Line(lvr.lv.StartLine)),
 			lvr.lv.StartLine,
 			lvr.lv.StartLinePos,
 			lvr.curSize.Width,
@@ -523,7 +576,7 @@ func (lvr *liteViewRender) scrollToPos(center bool) {
 		return
 	}
 	for rline := lvr.lv.StartLine - 1; rline >= 0; rline-- {
-		llist, _ := breakLine(bytes.Runes(lvr.lv.Val()[rline]),
+		llist, _ := breakLine(bytes.Runes(lvr.lv.Val().Line(rline)),
 			rline,
 			0,
 			lvr.curSize.Width,
@@ -552,12 +605,8 @@ func (lvr *liteViewRender) addLeadingSpaces() {
 	for i := 0; i < count; i++ {
 		spaces = append(spaces, []byte(" ")...)
 	}
-
-	for i, line := range lvr.lv.lineList {
-		line = bytes.TrimLeft(line, " ")
-		line = append(spaces, line...)
-		lvr.lv.lineList[i] = line
-	}
+	// lines are indented when they are read, so only on-screen lines are copied
+	lvr.lv.lineList = indentedLines{Lines: lvr.lv.rawLines, prefix: spaces}
 }
 
 func (lvr *liteViewRender) Refresh() {
@@ -584,7 +633,7 @@ func (lvr *liteViewRender) Refresh() {
 			lvr.scrollToPos(false)
 		case actScrollToPoSCental:
 			lvr.scrollToPos(true)
-		case actSetUnderline:
+		case actSetUnderline, actSetHighlight:
 			lvr.Layout(lvr.lv.Size())
 			canvas.Refresh(lvr.lv)
 		case actSetVal:
@@ -611,6 +660,11 @@ const (
 	DefaultDashlineInterval = 2
 )
 
+// TextRange is a range of runes within a single line
+type TextRange struct {
+	Pos, Len int
+}
+
 const (
 	DefaultSidePadding         = 50
 	DefaultVerticalPadding     = 20
@@ -619,7 +673,9 @@ const (
 
 type LiteView struct {
 	widget.DisableableWidget
-	lineList   [][]byte
+	// rawLines is the text set by caller, lineList is rawLines with leading spaces added
+	rawLines   Lines
+	lineList   Lines
 	actionChan chan renderAction
 	valMux     *sync.RWMutex
 	// actMux                                            *sync.RWMutex
@@ -630,12 +686,20 @@ type LiteView struct {
 	parent                                            fyne.Window
 	verticalPadding, sidePadding, lineVerticalPadding float32
 	numberOfLeadingSpaces                             *uint32
+	// highlights key is the line id
+	highlights   map[int][]TextRange
+	focusedLine  int
+	focusedRange TextRange
+	// navKeys maps keys to navigation actions
+	navKeys map[fyne.KeyName]NavAction
 }
 
 func newLiteView(p fyne.Window) *LiteView {
 	lv := new(LiteView)
 	lv.ExtendBaseWidget(lv)
 	lv.valMux = new(sync.RWMutex)
+	lv.rawLines = ByteLines{}
+	lv.lineList = lv.rawLines
 	lv.actionChan = make(chan renderAction, 16)
 	lv.keyEvtHandler = lv.defaultKeyEvtHandler
 	lv.parent = p
@@ -646,6 +710,8 @@ func newLiteView(p fyne.Window) *LiteView {
 	atomic.StoreUint32(lv.underLine, uint32(UnderLineNone))
 	lv.numberOfLeadingSpaces = new(uint32)
 	atomic.StoreUint32(lv.numberOfLeadingSpaces, 0)
+	lv.focusedLine = -1
+	lv.navKeys = DefaultNavKeys()
 	return lv
 }
 
@@ -661,6 +727,11 @@ func WithValBytes(val [][]byte) Option {
 		lv.SetBytes(val)
 	}
 }
+func WithLines(l Lines) Option {
+	return func(lv *LiteView) {
+		lv.SetLines(l)
+	}
+}
 func WithLeadingSpaces(count int) Option {
 	return func(lv *LiteView) {
 		atomic.StoreUint32(lv.numberOfLeadingSpaces, uint32(count))
@@ -669,16 +740,16 @@ func WithLeadingSpaces(count int) Option {
 
 func WithStartingPos(lineid, linepos int) Option {
 	return func(lv *LiteView) {
-		if len(lv.Val()) > 0 {
+		if lv.Val().Len() > 0 {
 			targetline := lineid
-			if targetline >= len(lv.lineList) {
-				targetline = len(lv.lineList) - 1
+			if targetline >= lv.lineList.Len() {
+				targetline = lv.lineList.Len() - 1
 			}
 			if targetline < 0 {
 				targetline = 0
 			}
 			targetlinepos := linepos
-			if targetlinepos >= len(lv.lineList[targetline]) {
+			if targetlinepos >= len(lv.lineList.Line(targetline)) {
 				targetlinepos = 0
 			}
 
@@ -713,13 +784,25 @@ func (lv *LiteView) SetStr(val string) {
 	lv.SetBytes(rlist)
 }
 func (lv *LiteView) SetBytes(val [][]byte) {
+	lv.SetLines(ByteLines(val))
+}
+
+// SetLines sets the text to l, only lines to be displayed are read from l
+func (lv *LiteView) SetLines(l Lines) {
 	lv.valMux.Lock()
-	lv.lineList = val
+	lv.rawLines = l
+	lv.lineList = l
 	lv.valMux.Unlock()
 	lv.renderAct(actSetVal)
 }
 
-func (lv *LiteView) Val() [][]byte {
+// Reload re-renders current text, e.g. after the font changed
+func (lv *LiteView) Reload() {
+	lv.renderAct(actSetVal)
+}
+
+// Val returns the text with leading spaces added
+func (lv *LiteView) Val() Lines {
 	lv.valMux.RLock()
 	defer lv.valMux.RUnlock()
 	return lv.lineList
@@ -740,23 +823,65 @@ func (lv *LiteView) SetKeyEvtHandler(f func(*fyne.KeyEvent)) {
 	lv.keyEvtHandler = f
 }
 
+// NavAction is a navigation action triggered by a key
+type NavAction int
+
+const (
+	NavLineUp NavAction = iota
+	NavLineDown
+	NavPageUp
+	NavPageDown
+	NavTop
+	NavBottom
+)
+
+func (na NavAction) renderAction() renderAction {
+	switch na {
+	case NavLineUp:
+		return actScrollLineUp
+	case NavLineDown:
+		return actScrollLineDown
+	case NavPageUp:
+		return actScrollPageUp
+	case NavPageDown:
+		return actScrollPageDown
+	case NavTop:
+		return actScrollTop
+	}
+	return actScrollBottom
+}
+
+// DefaultNavKeys returns the default keys of navigation actions
+func DefaultNavKeys() map[fyne.KeyName]NavAction {
+	return map[fyne.KeyName]NavAction{
+		fyne.KeyUp:       NavLineUp,
+		fyne.KeyDown:     NavLineDown,
+		fyne.KeyLeft:     NavPageUp,
+		fyne.KeyPageUp:   NavPageUp,
+		fyne.KeyRight:    NavPageDown,
+		fyne.KeyPageDown: NavPageDown,
+		fyne.KeySpace:    NavPageDown,
+		fyne.KeyHome:     NavTop,
+		fyne.KeyEnd:      NavBottom,
+	}
+}
+
+// SetNavKeys replaces keys of navigation actions, other keys are passed to the key event handler
+func (lv *LiteView) SetNavKeys(keys map[fyne.KeyName]NavAction) {
+	lv.valMux.Lock()
+	defer lv.valMux.Unlock()
+	lv.navKeys = keys
+}
+
 func (lv *LiteView) TypedKey(evt *fyne.KeyEvent) {
-	switch evt.Name {
-	case fyne.KeyDown:
-		lv.renderAct(actScrollLineDown)
-	case fyne.KeyUp:
-		lv.renderAct(actScrollLineUp)
-	case fyne.KeyRight, fyne.KeyPageDown, fyne.KeySpace:
-		lv.renderAct(actScrollPageDown)
-	case fyne.KeyLeft, fyne.KeyPageUp:
-		lv.renderAct(actScrollPageUp)
-	case fyne.KeyHome:
-		lv.renderAct(actScrollTop)
-	case fyne.KeyEnd:
-		lv.renderAct(actScrollBottom)
-	default:
+	lv.valMux.RLock()
+	nav, ok := lv.navKeys[evt.Name]
+	lv.valMux.RUnlock()
+	if !ok {
 		lv.keyEvtHandler(evt)
+		return
 	}
+	lv.renderAct(nav.renderAction())
 }
 
 func (lv *LiteView) TypedRune(c rune) {
@@ -780,6 +905,39 @@ func (lv *LiteView) GetUnderLine() UnderLineMode {
 	return UnderLineMode(atomic.LoadUint32(lv.underLine))
 }
 
+// SetHighlights highlights the specified ranges, key of hl is the line id;
+// it replaces all existing highlights, a nil hl clears them
+func (lv *LiteView) SetHighlights(hl map[int][]TextRange) {
+	lv.valMux.Lock()
+	lv.highlights = hl
+	lv.focusedLine = -1
+	lv.focusedRange = TextRange{}
+	lv.valMux.Unlock()
+	lv.renderAct(actSetHighlight)
+}
+
+// AddHighlights adds the specified ranges to existing highlights, key of hl is the line id
+func (lv *LiteView) AddHighlights(hl map[int][]TextRange) {
+	lv.valMux.Lock()
+	if lv.highlights == nil {
+		lv.highlights = make(map[int][]TextRange)
+	}
+	for lineid, ranges := range hl {
+		lv.highlights[lineid] = append(lv.highlights[lineid], ranges...)
+	}
+	lv.valMux.Unlock()
+	lv.renderAct(actSetHighlight)
+}
+
+// SetFocusedHighlight makes range r in line lineid stand out from other highlights
+func (lv *LiteView) SetFocusedHighlight(lineid int, r TextRange) {
+	lv.valMux.Lock()
+	lv.focusedLine = lineid
+	lv.focusedRange = r
+	lv.valMux.Unlock()
+	lv.renderAct(actSetHighlight)
+}
+
 // func (lv *LiteView) Dragged(evt *fyne.DragEvent) {
 // 	log.Printf("point is %v, x is %d, y is %d", evt.PointEvent.Position, evt.DraggedX, evt.DraggedY)
 // }
@@ -792,14 +950,14 @@ func (lv *LiteView) GetUnderLine() UnderLineMode {
 // otherwise the specified postion starts from top of viewarea;
 // if the specified postion is invalid, do nothing
 func (lv *LiteView) JumpTo(lineid, linepos int, centralview bool) {
-	if lineid < 0 || lineid >= len(lv.Val()) {
+	if lineid < 0 || lineid >= lv.Val().Len() {
 		return
 	}
 	if linepos < 0 {
 		return
 	} else {
-		if len(lv.Val()[lineid]) != 0 {
-			if linepos >= len(lv.Val()[lineid]) {
+		if len(lv.Val().Line(lineid)) != 0 {
+			if linepos >= len(lv.Val().Line(lineid)) {
 				return
 			}
 		}
@@ -844,7 +1002,7 @@ func (lv *LiteView) MouseMoved(evt *desktop.MouseEvent) {
 func (lv *LiteView) MouseOut() {
 
 }
-func (lv *LiteView) GetVal() [][]byte {
+func (lv *LiteView) GetVal() Lines {
 	lv.valMux.RLock()
 	defer lv.valMux.RUnlock()
 	return lv.lineList
@@ -0,0 +1,67 @@
+package mainwindow
+
+import (
+	"image/color"
+	"log"
+
+	"github.com/hujun-open/golitebook/conf"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// applyLook applies the config theme to the app and redraws the text
+func (win *LBWindow) applyLook() {
+	fyne.CurrentApp().Settings().SetTheme(win.cfg.Theme)
+	win.lv.Reload()
+}
+
+func (win *LBWindow) switchColorScheme(fyne.Shortcut) {
+	name := win.cfg.Theme.NextColorScheme()
+	log.Printf("switch to color scheme %v", name)
+	win.applyLook()
+}
+
+// colorButton shows a color and lets user pick another one
+func colorButton(c *color.Color, parent fyne.Window) fyne.CanvasObject {
+	preview := canvas.NewRectangle(*c)
+	preview.SetMinSize(fyne.NewSize(60, 20))
+	btn := widget.NewButton("选择...", func() {
+		picker := dialog.NewColorPicker("选择颜色", "", func(nc color.Color) {
+			*c = nc
+			preview.FillColor = nc
+			preview.Refresh()
+		}, parent)
+		picker.Advanced = true
+		picker.Show()
+	})
+	return container.NewHBox(preview, btn)
+}
+
+// ShowCustomColors lets user choose text, background and underline colors of the custom scheme,
+// and switches to it
+func (win *LBWindow) ShowCustomColors(fyne.Shortcut) {
+	cs := win.cfg.Theme.CustomColors()
+	dialog.ShowForm("自定义配色", "应用", "取消",
+		[]*widget.FormItem{
+			widget.NewFormItem("当前配色", widget.NewLabel(conf.SchemeTitle(win.cfg.Theme.ColorScheme()))),
+			widget.NewFormItem("文字", colorButton(&cs.Text, win)),
+			widget.NewFormItem("背景", colorButton(&cs.Background, win)),
+			widget.NewFormItem("下划线", colorButton(&cs.Underline, win)),
+		},
+		func(confirm bool) {
+			defer win.Canvas().Focus(win.lv)
+			if !confirm {
+				return
+			}
+			if err := win.cfg.Theme.SetCustomColors(cs); err != nil {
+				dialog.ShowError(err, win)
+				return
+			}
+			win.cfg.Theme.SetColorScheme(conf.SchemeCustom)
+			win.applyLook()
+		}, win)
+}
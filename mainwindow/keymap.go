@@ -0,0 +1,80 @@
+package mainwindow
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hujun-open/golitebook/conf"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// plainKeyActs are actions bound to keys without modifier, in the order of editing
+var plainKeyActs = []struct {
+	name, title string
+}{
+	{conf.ActLineUp, "向上滚行"},
+	{conf.ActLineDown, "向下滚行"},
+	{conf.ActPageUp, "向上翻页"},
+	{conf.ActPageDown, "向下翻页"},
+	{conf.ActTop, "首页"},
+	{conf.ActBottom, "末页"},
+	{conf.ActFontBigger, "放大字体"},
+	{conf.ActFontSmaller, "缩小字体"},
+}
+
+// ShowKeyMapEditor lets user edit key bindings, combos of an action are separated by spaces;
+// the key map is applied only if it has no conflict
+func (win *LBWindow) ShowKeyMapEditor(fyne.Shortcut) {
+	entries := make(map[string]*widget.Entry)
+	items := []*widget.FormItem{}
+	addItem := func(name, title string) {
+		e := widget.NewEntry()
+		e.SetText(strings.Join(win.cfg.KeyMap[name], " "))
+		entries[name] = e
+		items = append(items, widget.NewFormItem(title, e))
+	}
+	acts := make([]int, 0, len(win.actMap))
+	for at := range win.actMap {
+		acts = append(acts, int(at))
+	}
+	sort.Ints(acts)
+	for _, at := range acts {
+		addItem(liteActType(at).Name(), liteActType(at).String())
+	}
+	for _, act := range plainKeyActs {
+		addItem(act.name, act.title)
+	}
+	resetBtn := widget.NewButton("恢复缺省", func() {
+		defaults := conf.DefaultKeyMap()
+		for name, e := range entries {
+			e.SetText(strings.Join(defaults[name], " "))
+		}
+	})
+	hint := widget.NewLabel("多个按键用空格分开，例如 Ctrl+O Alt+Shift+O；滚行、翻页和字体按键不能带 Ctrl/Alt/Shift")
+	hint.Wrapping = fyne.TextWrapWord
+	scroll := container.NewVScroll(widget.NewForm(items...))
+	scroll.SetMinSize(fyne.NewSize(480, 400))
+	diag := dialog.NewCustomConfirm("快捷键设置", "保存", "取消",
+		container.NewBorder(hint, resetBtn, nil, nil, scroll),
+		func(confirm bool) {
+			defer win.Canvas().Focus(win.lv)
+			if !confirm {
+				return
+			}
+			km := conf.KeyMap{}
+			for name, e := range entries {
+				km[name] = strings.Fields(e.Text)
+			}
+			if err := km.Validate(); err != nil {
+				dialog.ShowError(err, win)
+				return
+			}
+			win.cfg.KeyMap = km
+			win.bindKeys()
+		}, win)
+	diag.Show()
+}
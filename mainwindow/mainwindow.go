@@ -2,20 +2,27 @@
 package mainwindow
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/hujun-open/golitebook/bookid"
+	"github.com/hujun-open/golitebook/bookmark"
 	"github.com/hujun-open/golitebook/char"
 	"github.com/hujun-open/golitebook/conf"
+	"github.com/hujun-open/golitebook/epub"
+	"github.com/hujun-open/golitebook/find"
 	"github.com/hujun-open/golitebook/history"
 	"github.com/hujun-open/golitebook/plugin"
 	"github.com/hujun-open/golitebook/searchdown"
+	"github.com/hujun-open/golitebook/textfile"
 	"github.com/hujun-open/golitebook/toc"
 
 	"fyne.io/fyne/v2"
@@ -25,6 +32,7 @@ import (
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
 
 	"github.com/hujun-open/golitebook/liteview"
 
@@ -45,10 +53,26 @@ type LBWindow struct {
 	actMap             actionMap
 	helpWin            dialog.Dialog
 	tocWin             *toc.ToCDialog
+	findWin            *find.FindDialog
+	bookmarkWin        *bookmark.BookmarkDialog
+	chapterPatternWin  *toc.PatternDialog
 	openFileDiag       *dialog.FileDialog
 	selectFontFileDiag *dialog.FileDialog
-	currentBook        string
-	tocUnchanged       bool
+	// currentBook is the id of current book in per-book settings, see package bookid
+	currentBook string
+	// bookName is the file name of current book
+	bookName string
+	// bookFile is the text file of current book, nil if the text is in memory
+	bookFile *textfile.File
+	// bookURI is where current book is loaded from
+	bookURI fyne.URI
+	// bookCharset is the charset of current txt book
+	bookCharset  *char.Detection
+	tocUnchanged bool
+	// bookToC is the ToC comes with the book (e.g. EPUB), nil means detecting from text
+	bookToC toc.ChapterLocationList
+	// fontKeys are keys changing font size, value is true for increasing
+	fontKeys map[fyne.KeyName]bool
 }
 
 func NewLBWindow(myApp fyne.App, filename string) (*LBWindow, error) {
@@ -58,12 +82,7 @@ func NewLBWindow(myApp fyne.App, filename string) (*LBWindow, error) {
 	r.det = char.NewDetChar()
 
 	r.cfg = new(conf.Config)
-	r.actMap = make(map[liteActType]*liteAct)
-
-	r.loadDefaultKeyMap()
-	for _, s := range r.actMap {
-		r.Canvas().AddShortcut(s.skey, s.handler)
-	}
+	r.actMap = make(actionMap)
 	var err error
 	err = plugin.InitPlugins()
 	if err != nil {
@@ -77,12 +96,20 @@ func NewLBWindow(myApp fyne.App, filename string) (*LBWindow, error) {
 		}
 		log.Printf("using default config")
 	}
+	if err := r.cfg.KeyMap.Validate(); err != nil {
+		log.Printf("invalid key map, %v, using default keys", err)
+		r.cfg.KeyMap = conf.DefaultKeyMap()
+	}
 	myApp.Settings().SetTheme(r.cfg.Theme)
+	// downloader is created now to update subscriptions in background
+	r.downloader = searchdown.NewDownloader(r, r.loadFileFromPath, r.releaseFile)
+	r.downloader.StartAutoUpdate(r.cfg.AutoUpdate)
 	r.lv = liteview.NewLiteViewCustom(r,
 		liteview.WithUnderline(liteview.UnderLineMode(r.cfg.Theme.GetUnderline())),
 		liteview.WithLeadingSpaces(2),
 	)
 	r.lv.SetKeyEvtHandler(r.onKey)
+	r.bindKeys()
 	r.lv.SetPosEvtHandler(r.onChangePos)
 	r.userInitatedScroll = new(uint32)
 	atomic.StoreUint32(r.userInitatedScroll, 0)
@@ -106,8 +133,6 @@ func NewLBWindow(myApp fyne.App, filename string) (*LBWindow, error) {
 	if uri != nil {
 		r.loadFileFromURI(uri)
 	}
-	r.helpWin = dialog.NewInformation("帮助", r.getHelpStr(), r)
-	r.helpWin.Hide()
 	icon, _ := fyne.LoadResourceFromPath(filepath.Join(conf.GetExecDir(), "icon.png"))
 	r.Resize(r.cfg.LastWinSize)
 	r.SetMaster()
@@ -118,7 +143,7 @@ func NewLBWindow(myApp fyne.App, filename string) (*LBWindow, error) {
 
 type liteActType int
 
-//NOTE to add new function, add const actXXX and update loadDefaultKeyMap()
+//NOTE to add new function, add const actXXX, its name in conf.KeyMap and update actHandlers()
 const (
 	actOpenFile liteActType = iota
 	actSearchAndDownload
@@ -130,6 +155,18 @@ const (
 	actFullScreen
 	actQuit
 	actSelectFontFile
+	actFindInBook
+	actFindNext
+	actFindPrev
+	actAddBookmark
+	actShowBookmarks
+	actChapterPatterns
+	actReopenWithCharset
+	actShowPluginStatus
+	actShowAutoUpdate
+	actSwitchColorScheme
+	actCustomColors
+	actEditKeyMap
 )
 
 func (at liteActType) String() string {
@@ -154,12 +191,87 @@ func (at liteActType) String() string {
 		return "退出"
 	case actSelectFontFile:
 		return "选择字体文件"
+	case actFindInBook:
+		return "书内搜索"
+	case actFindNext:
+		return "下一个匹配"
+	case actFindPrev:
+		return "上一个匹配"
+	case actAddBookmark:
+		return "添加书签"
+	case actShowBookmarks:
+		return "书签管理"
+	case actChapterPatterns:
+		return "章节规则"
+	case actReopenWithCharset:
+		return "指定编码重新打开"
+	case actShowPluginStatus:
+		return "插件状态"
+	case actShowAutoUpdate:
+		return "自动更新"
+	case actSwitchColorScheme:
+		return "切换配色"
+	case actCustomColors:
+		return "自定义配色"
+	case actEditKeyMap:
+		return "快捷键设置"
 	}
 	return "未知"
 }
 
+// Name returns the action name in conf.KeyMap
+func (at liteActType) Name() string {
+	switch at {
+	case actOpenFile:
+		return conf.ActOpenFile
+	case actSearchAndDownload:
+		return conf.ActSearchAndDownload
+	case actShowSubscriptionWin:
+		return conf.ActShowSubscriptions
+	case actFormatTxt:
+		return conf.ActFormatText
+	case actHelp:
+		return conf.ActHelp
+	case actShowTOC:
+		return conf.ActShowTOC
+	case actShowUnderline:
+		return conf.ActSwitchUnderline
+	case actFullScreen:
+		return conf.ActFullScreen
+	case actQuit:
+		return conf.ActQuit
+	case actSelectFontFile:
+		return conf.ActSelectFontFile
+	case actFindInBook:
+		return conf.ActFind
+	case actFindNext:
+		return conf.ActFindNext
+	case actFindPrev:
+		return conf.ActFindPrev
+	case actAddBookmark:
+		return conf.ActAddBookmark
+	case actShowBookmarks:
+		return conf.ActShowBookmarks
+	case actChapterPatterns:
+		return conf.ActChapterPatterns
+	case actReopenWithCharset:
+		return conf.ActReopenWithCharset
+	case actShowPluginStatus:
+		return conf.ActShowPluginStatus
+	case actShowAutoUpdate:
+		return conf.ActShowAutoUpdate
+	case actSwitchColorScheme:
+		return conf.ActSwitchColorScheme
+	case actCustomColors:
+		return conf.ActCustomColors
+	case actEditKeyMap:
+		return conf.ActEditKeyMap
+	}
+	return ""
+}
+
 type liteAct struct {
-	skey    *desktop.CustomShortcut
+	skeys   []*desktop.CustomShortcut
 	handler func(fyne.Shortcut)
 }
 type actionMap map[liteActType]*liteAct
@@ -167,18 +279,14 @@ type actionMap map[liteActType]*liteAct
 func (actmap actionMap) String() string {
 	r := ""
 	skeystr := func(act *liteAct) string {
-		mod := ""
-		if act.skey.Modifier&desktop.ControlModifier != 0 {
-			mod += "Ctrl+"
-		}
-		if act.skey.Modifier&desktop.AltModifier != 0 {
-			mod += "Alt+"
+		if len(act.skeys) == 0 {
+			return "无"
 		}
-		if act.skey.Modifier&desktop.ShiftModifier != 0 {
-			mod += "Shift+"
+		list := []string{}
+		for _, s := range act.skeys {
+			list = append(list, conf.KeyCombo{Key: s.KeyName, Modifier: s.Modifier}.String())
 		}
-
-		return mod + string(act.skey.KeyName)
+		return strings.Join(list, ", ")
 	}
 	keys := make([]int, 0, len(actmap))
 	for k := range actmap {
@@ -193,13 +301,17 @@ func (actmap actionMap) String() string {
 }
 
 func (win *LBWindow) getHelpStr() string {
-	ctrlHelpStr := `
-滚行: Up, Down, J, K, 鼠标滚轮
-翻页: PageUp, PageDown, Left, Right, Space
-首页: Home
-末页: End
-放大缩小字体： =/-
-	`
+	km := win.cfg.KeyMap
+	ctrlHelpStr := fmt.Sprintf(`
+滚行: %v, %v, 鼠标滚轮
+翻页: %v, %v
+首页: %v
+末页: %v
+放大缩小字体： %v / %v
+	`, km.KeysStr(conf.ActLineUp), km.KeysStr(conf.ActLineDown),
+		km.KeysStr(conf.ActPageUp), km.KeysStr(conf.ActPageDown),
+		km.KeysStr(conf.ActTop), km.KeysStr(conf.ActBottom),
+		km.KeysStr(conf.ActFontBigger), km.KeysStr(conf.ActFontSmaller))
 	verStr := VERSION
 	if verStr == "" {
 		verStr = "internal"
@@ -207,104 +319,113 @@ func (win *LBWindow) getHelpStr() string {
 	return fmt.Sprintf("%v\n %v\n ver %v\n\n Hu Jun@2021\ngithub.com/hujun-open/golitebook", ctrlHelpStr, win.actMap.String(), verStr)
 }
 
-func (win *LBWindow) loadDefaultKeyMap() {
-	win.actMap = map[liteActType]*liteAct{
-		actOpenFile: &liteAct{
-			skey: &desktop.CustomShortcut{
-				KeyName:  fyne.KeyO,
-				Modifier: desktop.ControlModifier,
-			},
-			handler: win.openFileviaShortcut,
-		},
-		actSearchAndDownload: &liteAct{
-			skey: &desktop.CustomShortcut{
-				KeyName:  fyne.KeyC,
-				Modifier: desktop.AltModifier,
-			},
-			handler: win.SearchAndDownload,
-		},
-		actShowSubscriptionWin: &liteAct{
-			skey: &desktop.CustomShortcut{
-				KeyName:  fyne.KeyY,
-				Modifier: desktop.ControlModifier,
-			},
-			handler: win.ShowSubs,
-		},
-		actFormatTxt: &liteAct{
-			skey: &desktop.CustomShortcut{
-				KeyName:  fyne.KeyF,
-				Modifier: desktop.ControlModifier | desktop.AltModifier,
-			},
-			handler: win.FormatVal,
-		},
-		actHelp: &liteAct{
-			skey: &desktop.CustomShortcut{
-				KeyName:  fyne.KeyH,
-				Modifier: desktop.ControlModifier,
-			},
-			handler: win.ShowHelp,
-		},
-		actShowTOC: &liteAct{
-			skey: &desktop.CustomShortcut{
-				KeyName:  fyne.KeyU,
-				Modifier: desktop.ControlModifier,
-			},
-			handler: win.ShowTOC,
-		},
-		actShowUnderline: &liteAct{
-			skey: &desktop.CustomShortcut{
-				KeyName:  fyne.KeyL,
-				Modifier: desktop.ControlModifier,
-			},
-			handler: win.ShowUnderline,
-		},
-		actFullScreen: &liteAct{
-			skey: &desktop.CustomShortcut{
-				KeyName:  fyne.KeyP,
-				Modifier: desktop.ControlModifier,
-			},
-			handler: win.fullScreen,
-		},
-		actQuit: &liteAct{
-			skey: &desktop.CustomShortcut{
-				KeyName:  fyne.KeyW,
-				Modifier: desktop.ControlModifier,
-			},
-			handler: win.quit,
-		},
-		actSelectFontFile: &liteAct{
-			skey: &desktop.CustomShortcut{
-				KeyName:  fyne.KeyZ,
-				Modifier: desktop.AltModifier,
-			},
-			handler: win.showFontFileSelectionDiag,
-		},
+// actHandlers returns the handler of every action
+func (win *LBWindow) actHandlers() map[liteActType]func(fyne.Shortcut) {
+	return map[liteActType]func(fyne.Shortcut){
+		actOpenFile:            win.openFileviaShortcut,
+		actSearchAndDownload:   win.SearchAndDownload,
+		actShowSubscriptionWin: win.ShowSubs,
+		actFormatTxt:           win.FormatVal,
+		actHelp:                win.ShowHelp,
+		actShowTOC:             win.ShowTOC,
+		actShowUnderline:       win.ShowUnderline,
+		actFullScreen:          win.fullScreen,
+		actQuit:                win.quit,
+		actSelectFontFile:      win.showFontFileSelectionDiag,
+		actFindInBook:          win.ShowFind,
+		actFindNext:            win.findNext,
+		actFindPrev:            win.findPrev,
+		actAddBookmark:         win.addBookmark,
+		actShowBookmarks:       win.ShowBookmarks,
+		actChapterPatterns:     win.ShowChapterPatterns,
+		actReopenWithCharset:   win.ShowReopenWithCharset,
+		actShowPluginStatus:    win.ShowPluginStatus,
+		actShowAutoUpdate:      win.ShowAutoUpdate,
+		actSwitchColorScheme:   win.switchColorScheme,
+		actCustomColors:        win.ShowCustomColors,
+		actEditKeyMap:          win.ShowKeyMapEditor,
 	}
 }
-func (win *LBWindow) initFromValue(val [][]byte, bookname string) {
-	win.tocUnchanged = false
-	if win.currentBook != "" {
-		history.History.Update(win.currentBook, win.lv.StartLine)
+
+// bindKeys binds keys of win.cfg.KeyMap to actions, replacing the existing bindings;
+// win.cfg.KeyMap must be validated
+func (win *LBWindow) bindKeys() {
+	for _, act := range win.actMap {
+		for _, s := range act.skeys {
+			win.Canvas().RemoveShortcut(s)
+		}
+	}
+	win.actMap = make(actionMap)
+	for at, h := range win.actHandlers() {
+		act := &liteAct{handler: h}
+		combos, _ := win.cfg.KeyMap.Combos(at.Name())
+		for _, kc := range combos {
+			s := kc.Shortcut()
+			act.skeys = append(act.skeys, s)
+			win.Canvas().AddShortcut(s, act.handler)
+		}
+		win.actMap[at] = act
+	}
+	win.lv.SetNavKeys(win.cfg.KeyMap.NavKeys())
+	win.fontKeys = make(map[fyne.KeyName]bool)
+	for action, increase := range map[string]bool{conf.ActFontBigger: true, conf.ActFontSmaller: false} {
+		combos, _ := win.cfg.KeyMap.Combos(action)
+		for _, kc := range combos {
+			win.fontKeys[kc.Key] = increase
+		}
 	}
-	win.currentBook = bookname
-	win.lv.SetBytes(val)
-	win.lv.JumpTo(history.History.GetStartLine(bookname), 0, false)
+}
+
+// initFromValue shows val as book with id book and name bookname
+func (win *LBWindow) initFromValue(val liteview.Lines, book, bookname string) {
+	win.tocUnchanged = false
+	win.saveReadingPosition()
+	win.currentBook = book
+	win.bookName = bookname
+	if win.findWin != nil {
+		win.findWin.Reset()
+	}
+	win.lv.SetLines(val)
+	lineid, linepos := history.History.GetPosition(book).Locate(win.lv.GetVal())
+	win.lv.JumpTo(lineid, linepos, false)
 	win.setTitle(bookname)
 	win.Canvas().Focus(win.lv)
 }
 
-func (win *LBWindow) onClose() {
-	if win.currentBook != "" {
-		history.History.Update(win.currentBook, win.lv.StartLine)
+// saveReadingPosition saves the position of current book into history
+func (win *LBWindow) saveReadingPosition() {
+	if win.currentBook == "" {
+		return
 	}
+	lineid, linepos := win.lv.GetPos()
+	history.History.Update(win.currentBook, history.NewPosition(win.lv.GetVal(), lineid, linepos))
+}
+
+func (win *LBWindow) onClose() {
+	win.saveReadingPosition()
 	history.History.Save()
+	bookmark.Bookmarks.Save()
+	char.BookCharsets.Save()
+	toc.ChapterPatterns.Save()
+	bookid.Books.Save()
 	plugin.CurrentSubscriptions.Save()
 	if win.downloader != nil {
+		win.downloader.StopAutoUpdate()
 		win.downloader.CloseAllWindow()
 	}
 	if win.tocWin != nil {
 		win.tocWin.Close()
 	}
+	if win.findWin != nil {
+		win.findWin.Close()
+	}
+	if win.bookmarkWin != nil {
+		win.bookmarkWin.Close()
+	}
+	if win.chapterPatternWin != nil {
+		win.chapterPatternWin.Close()
+	}
+	win.setBookFile(nil)
 
 	os.MkdirAll(conf.ConfDir(), 0755)
 	win.cfg.LastWinSize = win.Canvas().Size()
@@ -319,7 +440,7 @@ func (win *LBWindow) onClose() {
 const FontSizeMin = 6
 
 func (win *LBWindow) changeFontSize(increase bool) {
-	t := conf.NewLookFromTheme(fyne.CurrentApp().Settings().Theme())
+	t := win.cfg.Theme
 	if increase {
 		t.SetTextSize(t.TextSize() + 1)
 	} else {
@@ -340,9 +461,10 @@ func (win *LBWindow) onScrollChange(newpos uint32) {
 	// 	atomic.StoreUint32(win.userInitatedScroll, 1)
 	// 	return
 	// }
-	newlineid := ((len(win.lv.Val()) * int(newpos)) / int(sbar.OffsetResolution))
-	if newlineid >= len(win.lv.Val()) {
-		newlineid = len(win.lv.Val()) - 1
+	lines := win.lv.Val().Len()
+	newlineid := ((lines * int(newpos)) / int(sbar.OffsetResolution))
+	if newlineid >= lines {
+		newlineid = lines - 1
 	}
 	// log.Printf("onScrollChange offset %d, jump to line %d", newpos, newlineid)
 	win.lv.JumpTo(newlineid, 0, false)
@@ -351,19 +473,15 @@ func (win *LBWindow) onScrollChange(newpos uint32) {
 
 func (win *LBWindow) onChangePos(lineid, linepos int) {
 	atomic.StoreUint32(win.userInitatedScroll, 0)
-	lines := uint32(len(win.lv.Val()))
+	lines := uint32(win.lv.Val().Len())
 	if lines > 0 {
 		win.scrollBar.SetOffset((uint32(lineid) * sbar.OffsetResolution) / lines)
 	}
 }
 
 func (win *LBWindow) onKey(evt *fyne.KeyEvent) {
-	switch evt.Name {
-	case fyne.KeyEqual:
-		win.changeFontSize(true)
-	case fyne.KeyMinus:
-		win.changeFontSize(false)
-
+	if increase, ok := win.fontKeys[evt.Name]; ok {
+		win.changeFontSize(increase)
 	}
 }
 
@@ -390,7 +508,7 @@ func (win *LBWindow) loadFont(furl fyne.URIReadCloser, err error) {
 	win.cfg.Theme.SetFont(res, furl.URI().String())
 	fyne.CurrentApp().Settings().SetTheme(win.cfg.Theme)
 	time.Sleep(100 * time.Millisecond) //this delay is needed, otherwise the font change won't take effect
-	win.lv.SetBytes(win.lv.GetVal())
+	win.lv.Reload()
 }
 
 func (win *LBWindow) loadFile(furl fyne.URIReadCloser, err error) {
@@ -408,25 +526,166 @@ func (win *LBWindow) loadFile(furl fyne.URIReadCloser, err error) {
 }
 
 func (win *LBWindow) loadFileFromURI(furl fyne.URI) error {
-	ureader, err := storage.OpenFileFromURI(furl)
+	var r liteview.Lines
+	var bookFile *textfile.File
+	var bookCharset *char.Detection
+	var bookToC toc.ChapterLocationList
+	fp, err := fingerprintURI(furl)
 	if err != nil {
 		return err
 	}
-	buf, err := ioutil.ReadAll(ureader)
-	if err != nil {
-		return err
-	}
-	r, err := win.det.ToByteList(buf)
-	if err != nil {
-		return err
-	}
-	win.initFromValue(r, furl.Name())
+	if strings.ToLower(furl.Extension()) == ".epub" {
+		buf, err := readURI(furl)
+		if err != nil {
+			return err
+		}
+		eb, err := epub.Parse(buf)
+		if err != nil {
+			return fmt.Errorf("failed to parse epub, %w", err)
+		}
+		r = liteview.ByteLines(eb.Lines)
+		bookToC = epubToC(eb.TOC)
+	} else {
+		bookFile, err = win.openText(furl, fp)
+		if err != nil {
+			return err
+		}
+		r = bookFile
+		dt := bookFile.Detection()
+		bookCharset = &dt
+		log.Printf("charset of %v is %v", furl.Name(), dt)
+	}
+	// the book is only recorded after it is loaded
+	book := win.identifyBook(fp, furl)
+	win.initFromValue(r, book, furl.Name())
+	win.setBookFile(bookFile)
+	win.bookURI = furl
+	win.bookCharset = bookCharset
+	win.bookToC = bookToC
 	win.Canvas().Focus(win.lv)
 	win.cfg.LastFile = furl.String()
 	// win.SetTitle(fmt.Sprintf("Litebook %v", furl.Name()))
 	return nil
 }
 
+func readURI(furl fyne.URI) ([]byte, error) {
+	ureader, err := storage.OpenFileFromURI(furl)
+	if err != nil {
+		return nil, err
+	}
+	defer ureader.Close()
+	return ioutil.ReadAll(ureader)
+}
+
+// fingerprintURI returns the fingerprint of the book at furl, local file is sampled without reading all of it
+func fingerprintURI(furl fyne.URI) (string, error) {
+	if furl.Scheme() == "file" {
+		return bookid.FingerprintFile(furl.Path())
+	}
+	buf, err := readURI(furl)
+	if err != nil {
+		return "", err
+	}
+	return bookid.FingerprintBytes(buf), nil
+}
+
+// identifyBook records the book with fingerprint fp at furl as opened and returns its id,
+// per-book settings saved with its previous id are moved to it
+func (win *LBWindow) identifyBook(fp string, furl fyne.URI) string {
+	book, prev := bookid.Books.Resolve(fp, furl.String(), furl.Name())
+	if prev != "" && prev != book {
+		log.Printf("move settings of book %v to %v", prev, book)
+		if prev == win.currentBook {
+			// current book is reopened with changed content, its position is saved before moving
+			win.saveReadingPosition()
+			win.currentBook = book
+		}
+		history.History.RenameBook(prev, book)
+		bookmark.Bookmarks.RenameBook(prev, book)
+		char.BookCharsets.RenameBook(prev, book)
+		toc.ChapterPatterns.RenameBook(prev, book)
+	}
+	return book
+}
+
+// openText opens the text book with fingerprint fp at furl with the charset chosen for it,
+// local file is memory-mapped so that only displayed lines are loaded
+func (win *LBWindow) openText(furl fyne.URI, fp string) (*textfile.File, error) {
+	book, prev := bookid.Books.Lookup(fp, furl.String(), furl.Name())
+	charset := char.BookCharsets.Get(book)
+	if charset == "" && prev != "" {
+		// settings are not moved to book until it is loaded
+		charset = char.BookCharsets.Get(prev)
+	}
+	if furl.Scheme() == "file" {
+		return textfile.Open(furl.Path(), win.det, charset)
+	}
+	buf, err := readURI(furl)
+	if err != nil {
+		return nil, err
+	}
+	return textfile.New(buf, win.det, charset)
+}
+
+// option of the charset selection that means detecting
+const charsetAuto = "自动检测"
+
+// ShowReopenWithCharset lets user choose the charset of current txt book, and reopens it;
+// the choice is remembered for the book
+func (win *LBWindow) ShowReopenWithCharset(fyne.Shortcut) {
+	if win.bookURI == nil || win.bookCharset == nil {
+		return
+	}
+	furl, book, bookname := win.bookURI, win.currentBook, win.bookName
+	sel := widget.NewSelect(append([]string{charsetAuto}, char.CharsetNames()...), nil)
+	if cur := char.BookCharsets.Get(book); cur != "" {
+		sel.SetSelected(cur)
+	} else {
+		sel.SetSelected(charsetAuto)
+	}
+	dialog.ShowForm("指定编码重新打开", "打开", "取消",
+		[]*widget.FormItem{
+			widget.NewFormItem("当前编码", widget.NewLabel(win.bookCharset.String())),
+			widget.NewFormItem("编码", sel),
+		},
+		func(confirm bool) {
+			defer win.Canvas().Focus(win.lv)
+			if !confirm {
+				return
+			}
+			charset := sel.Selected
+			if charset == charsetAuto {
+				charset = ""
+			}
+			if err := char.BookCharsets.Set(book, charset); err != nil {
+				dialog.ShowError(err, win)
+				return
+			}
+			if err := win.loadFileFromURI(furl); err != nil {
+				dialog.ShowError(fmt.Errorf("failed to reopen %v, %v", bookname, err), win)
+			}
+		}, win)
+}
+
+// setBookFile closes the file of previous book, f is nil if current book is not backed by a file
+func (win *LBWindow) setBookFile(f *textfile.File) {
+	if win.bookFile != nil && win.bookFile != f {
+		win.bookFile.Close()
+	}
+	win.bookFile = f
+}
+
+// releaseFile closes current book if it is the file at p, so that the file could be replaced;
+// reading position is saved first, since the lines are gone after the book is closed
+func (win *LBWindow) releaseFile(p string) {
+	if win.bookFile == nil || win.bookURI == nil || win.bookURI.Path() != p {
+		return
+	}
+	win.saveReadingPosition()
+	win.currentBook = ""
+	win.setBookFile(nil)
+}
+
 func (win *LBWindow) setTitle(bookname string) {
 	win.SetTitle(fmt.Sprintf("Litebook %v     ------ Ctrl-H 帮助", bookname))
 }
@@ -457,7 +716,7 @@ func (win *LBWindow) openFile() {
 
 func (win *LBWindow) SearchAndDownload(fyne.Shortcut) {
 	if win.downloader == nil {
-		win.downloader = searchdown.NewDownloader(win, win.loadFileFromPath)
+		win.downloader = searchdown.NewDownloader(win, win.loadFileFromPath, win.releaseFile)
 	}
 
 	win.downloader.ShowSearchDiag()
@@ -465,23 +724,40 @@ func (win *LBWindow) SearchAndDownload(fyne.Shortcut) {
 
 func (win *LBWindow) ShowSubs(fyne.Shortcut) {
 	if win.downloader == nil {
-		win.downloader = searchdown.NewDownloader(win, win.loadFileFromPath)
+		win.downloader = searchdown.NewDownloader(win, win.loadFileFromPath, win.releaseFile)
 
 	}
 
 	win.downloader.ShowSubsWin()
 }
 
+func (win *LBWindow) ShowPluginStatus(fyne.Shortcut) {
+	if win.downloader == nil {
+		win.downloader = searchdown.NewDownloader(win, win.loadFileFromPath, win.releaseFile)
+	}
+	win.downloader.ShowPluginStatusWin()
+}
+
+func (win *LBWindow) ShowAutoUpdate(fyne.Shortcut) {
+	win.downloader.ShowAutoUpdateWin()
+}
+
 func (win *LBWindow) FormatVal(fyne.Shortcut) {
 	diag := dialog.NewProgress("分段", "智能分段中...", win)
 	diag.Show()
-	newval := plugin.FormatTxt(win.lv.GetVal(),
+	newval := plugin.FormatTxt(liteview.ToBytes(win.lv.GetVal()),
 		plugin.DefaultFormatMinimalLineWidthInChars, diag.SetValue)
-	win.initFromValue(newval, win.currentBook)
+	win.initFromValue(liteview.ByteLines(newval), win.currentBook, win.bookName)
+	// formatted text is in memory
+	win.setBookFile(nil)
+	// line ids of book's own ToC are no longer valid after formatting
+	win.bookToC = nil
 	diag.Hide()
 
 }
 func (win *LBWindow) ShowHelp(fyne.Shortcut) {
+	// help is created each time since key bindings could be changed
+	win.helpWin = dialog.NewInformation("帮助", win.getHelpStr(), win)
 	win.helpWin.Show()
 }
 
@@ -491,16 +767,46 @@ func (win *LBWindow) jumptoChapter(name string, line int) {
 
 func (win *LBWindow) ShowTOC(fyne.Shortcut) {
 	if win.tocWin == nil {
-		win.tocWin = toc.NewToCDialog(win.lv.GetVal(), win, win.jumptoChapter)
+		win.tocWin = toc.NewToCDialog(win.lv.GetVal(), win.currentBook, win, win.jumptoChapter)
 	}
 	if !win.tocUnchanged {
-		win.tocWin.Set(win.lv.GetVal())
+		if win.bookToC != nil {
+			win.tocWin.SetChapters(win.bookToC)
+		} else {
+			win.tocWin.Set(win.lv.GetVal(), win.currentBook)
+		}
 		win.tocUnchanged = true
 	}
 	win.tocWin.SetSelection(win.lv.StartLine)
 	win.tocWin.Show()
 }
 
+// epubToC converts EPUB ToC to toc.ChapterLocationList,
+// a top level entry followed by deeper entries is a volume, all others are chapters
+func epubToC(entries []epub.TOCEntry) toc.ChapterLocationList {
+	r := toc.ChapterLocationList{}
+	for i, e := range entries {
+		level := toc.LevelChapter
+		if e.Level == 0 && i+1 < len(entries) && entries[i+1].Level > 0 {
+			level = toc.LevelVolume
+		}
+		r = append(r, toc.ChapterLocation{Name: e.Name, StartLine: e.Line, Level: level})
+	}
+	return r
+}
+
+func (win *LBWindow) ShowChapterPatterns(fyne.Shortcut) {
+	if win.chapterPatternWin == nil {
+		win.chapterPatternWin = toc.NewPatternDialog(win.lv.GetVal, win.onChapterPatternsSaved)
+	}
+	win.chapterPatternWin.Set(win.currentBook)
+	win.chapterPatternWin.Show()
+}
+
+func (win *LBWindow) onChapterPatternsSaved() {
+	win.tocUnchanged = false
+}
+
 func (win *LBWindow) ShowUnderline(fyne.Shortcut) {
 	curUnder := win.lv.GetUnderLine()
 	switch curUnder {
@@ -529,3 +835,88 @@ func (win *LBWindow) showFontFileSelectionDiag(fyne.Shortcut) {
 	}
 	win.selectFontFileDiag.Show()
 }
+
+func (win *LBWindow) ShowFind(fyne.Shortcut) {
+	if win.findWin == nil {
+		win.findWin = find.NewFindDialog(win.lv.GetVal, win.lv.GetPos, win.onFindResult, win.jumptoMatch)
+	}
+	win.findWin.Show()
+}
+
+// onFindResult highlights a new batch of matches, or clears the highlights if added is nil
+func (win *LBWindow) onFindResult(added find.MatchList) {
+	if added == nil {
+		win.lv.SetHighlights(nil)
+		return
+	}
+	win.lv.AddHighlights(added.ByLine())
+}
+
+func (win *LBWindow) jumptoMatch(m find.Match) {
+	win.lv.SetFocusedHighlight(m.Line, m.Range())
+	win.lv.JumpTo(m.Line, m.Pos, true)
+	win.Canvas().Focus(win.lv)
+}
+
+func (win *LBWindow) findNext(fyne.Shortcut) {
+	if win.findWin != nil {
+		win.findWin.Next()
+	}
+}
+
+func (win *LBWindow) findPrev(fyne.Shortcut) {
+	if win.findWin != nil {
+		win.findWin.Prev()
+	}
+}
+
+// length of default bookmark note in runes
+const bookmarkNoteLen = 20
+
+func (win *LBWindow) addBookmark(fyne.Shortcut) {
+	if win.currentBook == "" {
+		return
+	}
+	book := win.currentBook
+	lineid, linepos := win.lv.GetPos()
+	pos := history.NewPosition(win.lv.GetVal(), lineid, linepos)
+	note := ""
+	if val := win.lv.GetVal(); lineid < val.Len() {
+		runes := bytes.Runes(val.Line(lineid))
+		if linepos < len(runes) {
+			runes = runes[linepos:]
+		}
+		if len(runes) > bookmarkNoteLen {
+			runes = runes[:bookmarkNoteLen]
+		}
+		note = strings.TrimSpace(string(runes))
+	}
+	entry := widget.NewEntry()
+	entry.SetText(note)
+	dialog.ShowForm("添加书签", "添加", "取消",
+		[]*widget.FormItem{widget.NewFormItem("备注", entry)},
+		func(confirm bool) {
+			if confirm {
+				bookmark.Bookmarks.Add(book, pos, entry.Text)
+			}
+			win.Canvas().Focus(win.lv)
+		}, win)
+}
+
+func (win *LBWindow) ShowBookmarks(fyne.Shortcut) {
+	if win.bookmarkWin == nil {
+		win.bookmarkWin = bookmark.NewBookmarkDialog(win.jumptoBookmark)
+	}
+	win.bookmarkWin.Set(win.currentBook, win.bookName)
+	win.bookmarkWin.Show()
+}
+
+func (win *LBWindow) jumptoBookmark(b *bookmark.Bookmark) {
+	if b.Book != win.currentBook {
+		return
+	}
+	// lines could be changed since the bookmark is added, e.g. reformatted
+	lineid, linepos := b.Position().Locate(win.lv.GetVal())
+	win.lv.JumpTo(lineid, linepos, false)
+	win.Canvas().Focus(win.lv)
+}
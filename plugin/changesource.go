@@ -0,0 +1,227 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/hujun-open/golitebook/api"
+)
+
+// number of last stored chapters tried when mapping to a new source
+const mapChapterTries = 5
+
+// chapterNumberRE matches the chapter number before the title, like "第12章" or "第一百回"
+var chapterNumberRE = regexp.MustCompile(`^第[0-9０-９零〇一二三四五六七八九十百千万两]+[章节回卷]`)
+
+// chapterKeys returns the keys to match a chapter name across sources:
+// the normalized name, and the normalized title without chapter number if any,
+// since sites number chapters differently
+func chapterKeys(name string) []string {
+	r := []string{}
+	if k := NormalizeBookName(name); k != "" {
+		r = append(r, k)
+	}
+	if title := chapterNumberRE.ReplaceAllString(name, ""); title != name {
+		if k := NormalizeBookName(title); k != "" {
+			r = append(r, k)
+		}
+	}
+	return r
+}
+
+func sameChapter(a, b string) bool {
+	for _, ka := range chapterKeys(a) {
+		for _, kb := range chapterKeys(b) {
+			if ka == kb {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchOffset returns the store id minus the source id, by matching names of stored chapters in stored
+// (latest first) to names of the source chapters, key of names is the chapter id in the source;
+// the match closest to the stored id wins, the one with lower source id on a tie;
+// false is returned if no stored chapter is found in names
+func matchOffset(stored []Chapter, names map[int]string) (int, bool) {
+	ids := make([]int, 0, len(names))
+	for id := range names {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, ch := range stored {
+		found := false
+		best := 0
+		for _, id := range ids {
+			if !sameChapter(ch.Name, names[id]) {
+				continue
+			}
+			if offset := ch.ID - id; !found || abs(offset) < abs(best) {
+				best = offset
+				found = true
+			}
+		}
+		if found {
+			return best, true
+		}
+	}
+	return 0, false
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// sourceChapterNames returns chapter names of the book at indexURL via p, key is the chapter id in the source;
+// the chapter index is used if p supports it, otherwise names are got from the book stream,
+// which stops once a chapter named as last is received
+func sourceChapterNames(ctx context.Context, p *Plugin, client api.GoLitebookPluginClient, indexURL, last string) (map[int]string, error) {
+	r := make(map[int]string)
+	if p.Supports(api.Capability_CapListChapters) {
+		list, err := listChapters(ctx, client, indexURL)
+		if err != nil {
+			return nil, err
+		}
+		for _, ch := range list {
+			r[ch.ID] = ch.Name
+		}
+		return r, nil
+	}
+	sctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+	stream, err := client.GetBook(sctx, &api.GetBookReq{BookIndexURL: indexURL})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book stream, %w", err)
+	}
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return r, nil
+		}
+		if err != nil {
+			return r, fmt.Errorf("failed to get chapters, %w", err)
+		}
+		r[int(resp.ChapterId)] = resp.ChapterName
+		if sameChapter(resp.ChapterName, last) {
+			return r, nil
+		}
+	}
+}
+
+// offsetIn returns the chapter offset of sub in the book at indexURL via p, it is 0 if there is no stored chapter;
+// the last stored chapters are found in the book by name, it fails if none of them is found
+func (sub *Subscription) offsetIn(ctx context.Context, p *Plugin, client api.GoLitebookPluginClient, indexURL string) (int, error) {
+	sub.mux.RLock()
+	n := sub.startingChapter
+	sub.mux.RUnlock()
+	if n == 0 {
+		return 0, nil
+	}
+	store := sub.Store()
+	stored := []Chapter{}
+	for id := n - 1; id >= 0 && id >= n-mapChapterTries; id-- {
+		ch, err := store.Get(id)
+		if err != nil {
+			return 0, err
+		}
+		stored = append(stored, ch)
+	}
+	names, err := sourceChapterNames(ctx, p, client, indexURL, stored[0].Name)
+	if err != nil {
+		return 0, err
+	}
+	offset, ok := matchOffset(stored, names)
+	if !ok {
+		return 0, fmt.Errorf("已下载的章节 %v 在新来源中找不到", stored[0].Name)
+	}
+	return offset, nil
+}
+
+// ChangeSource makes src the source of sub, stored chapters are kept and later updates continue from src;
+// the last stored chapters are found in src by name to map the chapter ids,
+// it fails if none of them is found; fallback sources are removed since they belong to the old source
+func (sub *Subscription) ChangeSource(src Source) error {
+	_, err := sub.run(func(ctx context.Context) (int, int, error) {
+		if err := sub.syncStore(); err != nil {
+			return 0, 0, err
+		}
+		if err := sub.updatableVia(src); err != nil {
+			return 0, 0, err
+		}
+		p := LoadedPlugins[src.PluginName]
+		client, err := p.Client()
+		if err != nil {
+			return 0, 0, err
+		}
+		total, lastch, indexURL, err := p.GetBookInfoContext(ctx, src.BookURL)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get book info, %w", err)
+		}
+		offset, err := sub.offsetIn(ctx, p, client, indexURL)
+		if err != nil {
+			return 0, 0, err
+		}
+		sub.mux.Lock()
+		log.Printf("change source of %v from %v to %v, chapter offset %d", sub.bookName, sub.pluginName, src.PluginName, offset)
+		sub.pluginName = src.PluginName
+		sub.bookURL = src.BookURL
+		sub.chapterOffset = offset
+		sub.fallbacks = nil
+		sub.fallbackOffsets = nil
+		sub.totalChapter = total
+		sub.lastChapterName = lastch
+		sub.statusTxt = "已换源到 " + src.PluginName
+		sub.mux.Unlock()
+		return 0, 0, nil
+	})
+	return err
+}
+
+// SameBook returns true if sr is the book of sub, the author is ignored if either one is unknown
+func (sub *Subscription) SameBook(sr *SearchResult) bool {
+	sub.mux.RLock()
+	defer sub.mux.RUnlock()
+	if NormalizeBookName(sub.bookName) != NormalizeBookName(sr.BookName) {
+		return false
+	}
+	if sub.authorName == "" || sr.AuthorName == "" {
+		return true
+	}
+	return NormalizeBookName(sub.authorName) == NormalizeBookName(sr.AuthorName)
+}
+
+// FindSources searches the book of sub via all search capable plugins,
+// it returns the sources other than the current one, sorted by preference
+func (sub *Subscription) FindSources(ctx context.Context) SearchResultList {
+	sub.mux.RLock()
+	name, cur := sub.bookName, Source{PluginName: sub.pluginName, BookURL: sub.bookURL}
+	sub.mux.RUnlock()
+	mux := new(sync.Mutex)
+	mr := &MergedResult{}
+	SearchAll(ctx, LoadedPlugins.Plugins(), name, func(p *Plugin, results SearchResultList, err error) {
+		if err != nil {
+			log.Printf("failed to search %v via %v, %v", name, p.Name, err)
+			return
+		}
+		mux.Lock()
+		defer mux.Unlock()
+		for _, sr := range results {
+			if sub.SameBook(sr) && (Source{PluginName: sr.PluginName, BookURL: sr.BookPageURL}) != cur {
+				mr.Sources = append(mr.Sources, sr)
+			}
+		}
+	})
+	if len(mr.Sources) > 0 {
+		mr = mr.WithChapterCounts(mr.FetchChapterCounts(ctx))
+	}
+	return mr.Sources
+}
@@ -0,0 +1,132 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/hujun-open/golitebook/api"
+)
+
+func TestMatchOffset(t *testing.T) {
+	names := map[int]string{0: "作品相关", 1: "第1章 开始", 2: "第2章 结束", 3: "第3章 结束"}
+	stored := []Chapter{{ID: 1, Name: "第二章 结束"}, {ID: 0, Name: "第一章 开始"}}
+	// 第2章 is closer to stored id 1 than 第3章
+	if offset, ok := matchOffset(stored, names); !ok || offset != -1 {
+		t.Fatalf("expect offset -1, got %d, %v", offset, ok)
+	}
+	// the latest stored chapter is missing in the source
+	stored = []Chapter{{ID: 5, Name: "番外"}, {ID: 4, Name: "第一章：开始"}}
+	if offset, ok := matchOffset(stored, names); !ok || offset != 3 {
+		t.Fatalf("expect offset 3, got %d, %v", offset, ok)
+	}
+	if _, ok := matchOffset([]Chapter{{ID: 0, Name: "不存在"}}, names); ok {
+		t.Fatal("expect no match")
+	}
+	// source ids 2 and 4 are equally close to stored id 3, the lower one wins whatever the map order
+	stored = []Chapter{{ID: 3, Name: "结束"}}
+	names = map[int]string{2: "第2章 结束", 4: "第3章 结束"}
+	for i := 0; i < 20; i++ {
+		if offset, ok := matchOffset(stored, names); !ok || offset != 1 {
+			t.Fatalf("expect offset 1 on a tie, got %d, %v", offset, ok)
+		}
+	}
+}
+
+func TestChangeSource(t *testing.T) {
+	os.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+	os.Setenv(fakePluginEnv, "1")
+	defer os.Unsetenv(fakePluginEnv)
+	p, err := NewPlugin(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+	saved := LoadedPlugins
+	defer func() { LoadedPlugins = saved }()
+	LoadedPlugins = PluginList{"new": p, "dead": &Plugin{Name: "dead", mux: new(sync.RWMutex)}}
+
+	sub := NewSubscription("book", "author", "old", "dead", nil)
+	sub.SetFallbacks([]Source{{PluginName: "dead", BookURL: "other"}})
+	// downloaded from the old source without the "作品相关" chapter
+	if err := sub.Store().Put(&api.GetChapterResp{ChapterId: 0, ChapterName: "第一章 开始"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.ChangeSource(Source{PluginName: "new", BookURL: "newurl"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(sub.sources()) != 1 || sub.sources()[0].PluginName != "new" || sub.chapterOffset != -1 {
+		t.Fatalf("unexpected source %+v, offset %d", sub.sources(), sub.chapterOffset)
+	}
+	n, err := sub.Update()
+	if err != nil || n != 1 {
+		t.Fatalf("expect 1 new chapter, got %d, %v", n, err)
+	}
+	ch, err := sub.Store().Get(1)
+	if err != nil || ch.Name != "第2章 结束" {
+		t.Fatalf("expect 第2章 stored as chapter 1, got %+v, %v", ch, err)
+	}
+	if _, err := sub.Store().Get(2); err == nil {
+		t.Fatal("expect no chapter 2")
+	}
+}
+
+func TestFallbackOffset(t *testing.T) {
+	os.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+	os.Setenv(fakePluginEnv, "1")
+	defer os.Unsetenv(fakePluginEnv)
+	p, err := NewPlugin(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+	saved := LoadedPlugins
+	defer func() { LoadedPlugins = saved }()
+	LoadedPlugins = PluginList{"fallback": p, "dead": &Plugin{Name: "dead", mux: new(sync.RWMutex)}}
+
+	sub := NewSubscription("book", "author", "old", "dead", nil)
+	sub.SetFallbacks([]Source{{PluginName: "fallback", BookURL: "other"}})
+	// downloaded from the primary source without the "作品相关" chapter
+	if err := sub.Store().Put(&api.GetChapterResp{ChapterId: 0, ChapterName: "第一章 开始"}); err != nil {
+		t.Fatal(err)
+	}
+	n, err := sub.Update()
+	if err != nil || n != 1 {
+		t.Fatalf("expect 1 new chapter, got %d, %v", n, err)
+	}
+	if ch, err := sub.Store().Get(1); err != nil || ch.Name != "第2章 结束" {
+		t.Fatalf("expect 第2章 stored as chapter 1, got %+v, %v", ch, err)
+	}
+	if sub.sources()[0].PluginName != "dead" || sub.chapterOffset != 0 {
+		t.Fatalf("expect the primary source unchanged, got %+v, offset %d", sub.sources(), sub.chapterOffset)
+	}
+	// the offset is cached for later updates via the fallback
+	fallback := Source{PluginName: "fallback", BookURL: "other"}
+	if offset, ok := sub.fallbackOffsets[fallback]; !ok || offset != -1 {
+		t.Fatalf("expect offset -1 cached, got %d, %v", offset, ok)
+	}
+	sub.fallbackOffsets[fallback] = -2
+	if offset, err := sub.fallbackOffset(context.Background(), fallback, nil, ""); err != nil || offset != -2 {
+		t.Fatalf("expect cached offset used, got %d, %v", offset, err)
+	}
+	sub.SetFallbacks([]Source{fallback})
+	if sub.fallbackOffsets != nil {
+		t.Fatal("expect cached offsets cleared with fallbacks")
+	}
+
+	// fallback is refused if the stored chapters are not found in it
+	other := NewSubscription("other", "author", "old", "dead", nil)
+	other.SetFallbacks([]Source{{PluginName: "fallback", BookURL: "other"}})
+	if err := other.Store().Put(&api.GetChapterResp{ChapterId: 0, ChapterName: "不存在"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Update(); err == nil {
+		t.Fatal("expect the fallback refused")
+	}
+	if _, err := other.Store().Get(1); err == nil {
+		t.Fatal("expect nothing downloaded via the refused fallback")
+	}
+}
@@ -0,0 +1,127 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hujun-open/golitebook/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ChapterInfo is an entry of the chapter index of a book in a source
+type ChapterInfo struct {
+	ID   int
+	Name string
+	URL  string
+}
+
+type ChapterInfoList []ChapterInfo
+
+func (list ChapterInfoList) Len() int {
+	return len(list)
+}
+
+func (list ChapterInfoList) Fields() []string {
+	return []string{"序号", "章节", "URL"}
+}
+
+func (list ChapterInfoList) Item(id int) []string {
+	if id < 0 || id >= len(list) {
+		return nil
+	}
+	return []string{
+		fmt.Sprintf("%d", list[id].ID+1),
+		list[id].Name,
+		list[id].URL,
+	}
+}
+
+func (list ChapterInfoList) Sort(field int, ascend bool) {
+}
+
+func (list ChapterInfoList) Filter(kw string, i int) {
+}
+
+// listChapters gets the chapter index of the book at indexURL via client
+func listChapters(ctx context.Context, client api.GoLitebookPluginClient, indexURL string) (ChapterInfoList, error) {
+	lctx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+	resp, err := client.ListChapters(lctx, &api.ListChaptersReq{BookIndexURL: indexURL})
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return nil, fmt.Errorf("plugin doesn't support listing chapters")
+		}
+		return nil, fmt.Errorf("failed to list chapters, %w", err)
+	}
+	r := ChapterInfoList{}
+	for _, ch := range resp.Chapters {
+		r = append(r, ChapterInfo{ID: int(ch.ChapterId), Name: ch.ChapterName, URL: ch.ChapterURL})
+	}
+	return r, nil
+}
+
+// ListChapters returns the chapter index of the book at bookurl, ids are in the source
+func (p *Plugin) ListChapters(ctx context.Context, bookurl string) (ChapterInfoList, error) {
+	if !p.Supports(api.Capability_CapListChapters) {
+		return nil, fmt.Errorf("plugin %v doesn't support listing chapters", p.Name)
+	}
+	_, _, indexURL, err := p.GetBookInfoContext(ctx, bookurl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book info, %w", err)
+	}
+	client, err := p.Client()
+	if err != nil {
+		return nil, err
+	}
+	return listChapters(ctx, client, indexURL)
+}
+
+// DownloadRange downloads chapters with source id in [from, to) for a subscription without stored chapters,
+// chapter from becomes the first chapter of the book, and later updates continue after to;
+// it returns number of chapters downloaded
+func (sub *Subscription) DownloadRange(from, to int) (int, error) {
+	return sub.run(func(ctx context.Context) (int, int, error) {
+		if err := sub.syncStore(); err != nil {
+			return 0, 0, err
+		}
+		sub.mux.RLock()
+		downloaded := sub.startingChapter > 0
+		src := Source{PluginName: sub.pluginName, BookURL: sub.bookURL}
+		sub.mux.RUnlock()
+		if downloaded {
+			return 0, 0, fmt.Errorf("%v already has downloaded chapters", sub.BookName())
+		}
+		if from < 0 || from >= to {
+			return 0, 0, fmt.Errorf("invalid chapter range %d-%d", from+1, to)
+		}
+		p, ok := LoadedPlugins[src.PluginName]
+		if !ok {
+			return 0, 0, fmt.Errorf("plugin %v is not loaded", src.PluginName)
+		}
+		if from > 0 && !p.Supports(api.Capability_CapUpdateOnly) {
+			return 0, 0, fmt.Errorf("plugin %v doesn't support downloading from chapter %d", src.PluginName, from+1)
+		}
+		client, err := p.Client()
+		if err != nil {
+			return 0, 0, err
+		}
+		resp, err := sub.getBookInfo(ctx, client, src.BookURL)
+		if err != nil {
+			return 0, 0, err
+		}
+		sub.setBookInfo(resp)
+		if to > int(resp.TotalChapterCount) {
+			to = int(resp.TotalChapterCount)
+		}
+		sub.mux.Lock()
+		sub.chapterOffset = -from
+		sub.mux.Unlock()
+		sub.fetchMeta(ctx)
+		finished, err := sub.download(ctx, client, resp.BookIndexURL, from, to, -from)
+		if serr := sub.syncStore(); err == nil {
+			err = serr
+		}
+		return finished, to - from, err
+	})
+}
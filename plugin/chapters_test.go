@@ -0,0 +1,49 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestListChaptersAndDownloadRange(t *testing.T) {
+	os.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+	os.Setenv(fakePluginEnv, "manifest")
+	defer os.Unsetenv(fakePluginEnv)
+	p, err := NewPlugin(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+	saved := LoadedPlugins
+	defer func() { LoadedPlugins = saved }()
+	LoadedPlugins = PluginList{p.Name: p}
+
+	list, err := p.ListChapters(context.Background(), "book")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != len(fakeChapters) || list[1].Name != fakeChapters[1] || list[1].URL != "url/1" {
+		t.Fatalf("unexpected chapters %+v", list)
+	}
+
+	sub := NewSubscription("book", "author", "book", p.Name, nil)
+	// skip the first chapter
+	if n, err := sub.DownloadRange(1, 2); err != nil || n != 1 {
+		t.Fatalf("expect 1 chapter downloaded, got %d, %v", n, err)
+	}
+	if ch, err := sub.Store().Get(0); err != nil || ch.Name != fakeChapters[1] {
+		t.Fatalf("expect %v as the first chapter, got %+v, %v", fakeChapters[1], ch, err)
+	}
+	if _, err := sub.DownloadRange(0, 1); err == nil {
+		t.Fatal("expect failure for a book with downloaded chapters")
+	}
+	// update continues after the range
+	if n, err := sub.Update(); err != nil || n != 1 {
+		t.Fatalf("expect 1 new chapter, got %d, %v", n, err)
+	}
+	if ch, err := sub.Store().Get(1); err != nil || ch.Name != fakeChapters[2] {
+		t.Fatalf("expect %v as chapter 1, got %+v, %v", fakeChapters[2], ch, err)
+	}
+}
@@ -0,0 +1,86 @@
+package plugin
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/hujun-open/golitebook/epub"
+)
+
+// title of the chapter holding text before the first chapter title
+const prefaceTitle = "前言"
+
+// SplitChapters splits lines of a downloaded book into chapters by the pre-created chapter titles
+func SplitChapters(lines [][]byte) []epub.Chapter {
+	bookMarkRune := []rune(BookMarkChar)[0]
+	chapters := []epub.Chapter{}
+	cur := epub.Chapter{Title: prefaceTitle}
+	for _, line := range lines {
+		if IsPrecreatedChapterTitle(line, bookMarkRune) {
+			if cur.Title != prefaceTitle || len(cur.Paragraphs) > 0 {
+				chapters = append(chapters, cur)
+			}
+			title := strings.TrimPrefix(string(bytes.TrimSpace(line)), BookMarkChar)
+			cur = epub.Chapter{Title: strings.TrimSpace(title)}
+			continue
+		}
+		if p := bytes.TrimSpace(line); len(p) > 0 {
+			cur.Paragraphs = append(cur.Paragraphs, string(p))
+		}
+	}
+	if cur.Title != prefaceTitle || len(cur.Paragraphs) > 0 {
+		chapters = append(chapters, cur)
+	}
+	return chapters
+}
+
+// ExportEPUB writes the downloaded book as EPUB3 to w
+func (sub *Subscription) ExportEPUB(w io.Writer) error {
+	sub.mux.RLock()
+	meta := epub.Metadata{
+		ID:         sub.bookURL,
+		Title:      sub.bookName,
+		Author:     sub.authorName,
+		Source:     sub.bookURL,
+		Publisher:  sub.pluginName,
+		LastUpdate: sub.lastDownloadTime,
+	}
+	sub.mux.RUnlock()
+	m := sub.Meta()
+	meta.Description = m.Synopsis
+	meta.Subjects = m.Tags
+	meta.Cover = m.Cover
+	meta.CoverType = m.CoverType
+	store := sub.Store()
+	if err := store.migrate(); err != nil {
+		return err
+	}
+	stored, err := store.Chapters()
+	if err != nil {
+		return err
+	}
+	chapters := []epub.Chapter{}
+	for _, ch := range stored {
+		chapter := epub.Chapter{Title: ch.Name}
+		for _, line := range strings.Split(ch.Content, "\n") {
+			if p := strings.TrimSpace(line); p != "" {
+				chapter.Paragraphs = append(chapter.Paragraphs, p)
+			}
+		}
+		chapters = append(chapters, chapter)
+	}
+	return epub.Write(w, meta, chapters)
+}
+
+func (sub *Subscription) BookName() string {
+	sub.mux.RLock()
+	defer sub.mux.RUnlock()
+	return sub.bookName
+}
+
+func (sub *Subscription) BookURL() string {
+	sub.mux.RLock()
+	defer sub.mux.RUnlock()
+	return sub.bookURL
+}
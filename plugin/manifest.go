@@ -0,0 +1,130 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hujun-open/golitebook/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// APIVersion is the version of plugin API implemented by golitebook
+	APIVersion = 3
+	// MinAPIVersion is the oldest plugin API version golitebook could work with,
+	// plugin implements version 1 doesn't have GetManifest
+	MinAPIVersion = 1
+)
+
+// Manifest describes a plugin
+type Manifest struct {
+	Name    string
+	Version string
+	// Domains is the list of site domains supported by the plugin
+	Domains      []string
+	APIVersion   int
+	Capabilities []api.Capability
+}
+
+// legacyManifest returns the manifest of a plugin without GetManifest,
+// such plugin searches and downloads chapters after GetBookReq.CurrentChaptCount
+func legacyManifest(name string) *Manifest {
+	return &Manifest{
+		Name:         name,
+		APIVersion:   1,
+		Capabilities: []api.Capability{api.Capability_CapSearch, api.Capability_CapUpdateOnly},
+	}
+}
+
+func (m *Manifest) Supports(c api.Capability) bool {
+	for _, have := range m.Capabilities {
+		if have == c {
+			return true
+		}
+	}
+	return false
+}
+
+// compatible returns error if golitebook can't work with the plugin
+func (m *Manifest) compatible() error {
+	if m.APIVersion < MinAPIVersion || m.APIVersion > APIVersion {
+		return fmt.Errorf("plugin API version %d is not supported, supported versions are %d to %d", m.APIVersion, MinAPIVersion, APIVersion)
+	}
+	return nil
+}
+
+func capabilityName(c api.Capability) string {
+	switch c {
+	case api.Capability_CapSearch:
+		return "搜索"
+	case api.Capability_CapUpdateOnly:
+		return "增量更新"
+	case api.Capability_CapCover:
+		return "封面"
+	case api.Capability_CapAuth:
+		return "登录"
+	case api.Capability_CapListChapters:
+		return "章节目录"
+	}
+	return c.String()
+}
+
+// CapabilityStr returns names of all capabilities
+func (m *Manifest) CapabilityStr() string {
+	r := []string{}
+	for _, c := range m.Capabilities {
+		r = append(r, capabilityName(c))
+	}
+	return strings.Join(r, ",")
+}
+
+// getManifest gets the manifest via client,
+// the legacy manifest is returned if the plugin doesn't implement GetManifest
+func getManifest(name string, client api.GoLitebookPluginClient) (*Manifest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+	resp, err := client.GetManifest(ctx, &api.Empty{})
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			return legacyManifest(name), nil
+		}
+		return nil, err
+	}
+	r := &Manifest{
+		Name:         resp.Name,
+		Version:      resp.Version,
+		Domains:      resp.Domains,
+		APIVersion:   int(resp.APIVersion),
+		Capabilities: resp.Capabilities,
+	}
+	if r.Name == "" {
+		r.Name = name
+	}
+	return r, nil
+}
+
+// Manifest returns the manifest of plugin
+func (p *Plugin) Manifest() *Manifest {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+	return p.manifest
+}
+
+// Supports returns true if the plugin has capability c
+func (p *Plugin) Supports(c api.Capability) bool {
+	m := p.Manifest()
+	return m != nil && m.Supports(c)
+}
+
+// NameListWith returns names of loaded plugins with capability c
+func (list PluginList) NameListWith(c api.Capability) []string {
+	r := []string{}
+	for _, p := range list {
+		if p.Supports(c) {
+			r = append(r, p.Name)
+		}
+	}
+	return r
+}
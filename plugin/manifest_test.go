@@ -0,0 +1,46 @@
+package plugin
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hujun-open/golitebook/api"
+)
+
+func TestManifest(t *testing.T) {
+	defer os.Unsetenv(fakePluginEnv)
+	os.Setenv(fakePluginEnv, "1")
+	p, err := NewPlugin(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+	// plugin without GetManifest
+	if m := p.Manifest(); m.APIVersion != 1 || !p.Supports(api.Capability_CapSearch) || p.Supports(api.Capability_CapCover) {
+		t.Fatalf("unexpected legacy manifest %+v", m)
+	}
+
+	os.Setenv(fakePluginEnv, "manifest")
+	p2, err := NewPlugin(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p2.Stop()
+	m := p2.Manifest()
+	if m.Name != "manifest" || m.Version != "1.0" || m.APIVersion != APIVersion || len(m.Domains) != 1 {
+		t.Fatalf("unexpected manifest %+v", m)
+	}
+	if !p2.Supports(api.Capability_CapCover) || p2.Supports(api.Capability_CapSearch) {
+		t.Fatalf("unexpected capabilities %v", m.Capabilities)
+	}
+	list := PluginList{p.Name: p}
+	if names := list.NameListWith(api.Capability_CapSearch); len(names) != 1 {
+		t.Fatalf("expect 1 plugin could search, got %v", names)
+	}
+
+	os.Setenv(fakePluginEnv, "incompatible")
+	if p3, err := NewPlugin(os.Args[0]); err == nil {
+		p3.Stop()
+		t.Fatal("expect incompatible plugin failed to load")
+	}
+}
@@ -0,0 +1,338 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// NormalizeBookName returns the name used to find the same book from different sites,
+// width and case are folded, spaces and punctuations are removed
+func NormalizeBookName(name string) string {
+	name = width.Fold.String(name)
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func resultKey(sr *SearchResult) string {
+	return NormalizeBookName(sr.BookName) + "\x00" + NormalizeBookName(sr.AuthorName)
+}
+
+// MergedResult is the same book found from one or more sources
+type MergedResult struct {
+	BookName   string
+	AuthorName string
+	// Sources are sorted by preference, the first one is the best
+	Sources SearchResultList
+}
+
+// Best returns the preferred source
+func (mr *MergedResult) Best() *SearchResult {
+	return mr.Sources[0]
+}
+
+// sortSources sorts sources by chapter count then last update, newest first
+func (mr *MergedResult) sortSources() {
+	sort.SliceStable(mr.Sources, func(i, j int) bool {
+		a, b := mr.Sources[i], mr.Sources[j]
+		if a.ChapterCount != b.ChapterCount {
+			return a.ChapterCount > b.ChapterCount
+		}
+		return a.LastUpdate.After(b.LastUpdate)
+	})
+}
+
+// MergeResults groups results of the same book by normalized book name and author,
+// groups are in the order of their first result
+func MergeResults(list SearchResultList) MergedResultList {
+	r := MergedResultList{}
+	groups := make(map[string]*MergedResult)
+	for _, sr := range list {
+		key := resultKey(sr)
+		mr, ok := groups[key]
+		if !ok {
+			mr = &MergedResult{BookName: sr.BookName, AuthorName: sr.AuthorName}
+			groups[key] = mr
+			r = append(r, mr)
+		}
+		mr.Sources = append(mr.Sources, sr)
+	}
+	for _, mr := range r {
+		mr.sortSources()
+	}
+	return r
+}
+
+type MergedResultList []*MergedResult
+
+func (list MergedResultList) Len() int {
+	return len(list)
+}
+
+func (list MergedResultList) Fields() []string {
+	return []string{"书名", "作者", "来源数", "来源", "最后更新"}
+}
+
+func (list MergedResultList) Item(id int) []string {
+	if id < 0 || id >= len(list) {
+		return nil
+	}
+	mr := list[id]
+	names := []string{}
+	for _, sr := range mr.Sources {
+		names = append(names, sr.PluginName)
+	}
+	return []string{
+		mr.BookName,
+		mr.AuthorName,
+		fmt.Sprintf("%d", len(mr.Sources)),
+		strings.Join(names, ","),
+		mr.lastUpdate().Format("2006-01-02"),
+	}
+}
+
+// lastUpdate returns the latest update time of all sources
+func (mr *MergedResult) lastUpdate() time.Time {
+	last := mr.Sources[0].LastUpdate
+	for _, sr := range mr.Sources {
+		if sr.LastUpdate.After(last) {
+			last = sr.LastUpdate
+		}
+	}
+	return last
+}
+
+func (list MergedResultList) Sort(field int, ascend bool) {
+	less := func(i, j int) bool {
+		a, b := list[i], list[j]
+		switch field {
+		case 1:
+			return a.AuthorName < b.AuthorName
+		case 2:
+			return len(a.Sources) < len(b.Sources)
+		case 3:
+			return list.Item(i)[3] < list.Item(j)[3]
+		case 4:
+			return a.lastUpdate().Before(b.lastUpdate())
+		}
+		return a.BookName < b.BookName
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if ascend {
+			return less(i, j)
+		}
+		return less(j, i)
+	})
+}
+
+// MergedResultView is the books in list match the filter, in the order of the last sort;
+// the filter and the sort are kept when list is replaced, ids of Item and At are index of view
+type MergedResultView struct {
+	list MergedResultList
+	view MergedResultList
+	// filter keyword and field
+	kw      string
+	kwField int
+	// sortField is -1 if not sorted
+	sortField int
+	ascend    bool
+	mux       *sync.RWMutex
+}
+
+func NewMergedResultView() *MergedResultView {
+	return &MergedResultView{
+		list:      MergedResultList{},
+		view:      MergedResultList{},
+		kwField:   -1,
+		sortField: -1,
+		mux:       new(sync.RWMutex),
+	}
+}
+
+// refreshView sorts list and rebuilds view from it, caller must hold v.mux
+func (v *MergedResultView) refreshView() {
+	if v.sortField >= 0 {
+		v.list.Sort(v.sortField, v.ascend)
+	}
+	v.view = MergedResultList{}
+	for id, mr := range v.list {
+		if matchItem(v.list.Item(id), v.kw, v.kwField) {
+			v.view = append(v.view, mr)
+		}
+	}
+}
+
+// SetList replaces the books with a copy of list
+func (v *MergedResultView) SetList(list MergedResultList) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+	v.list = append(MergedResultList{}, list...)
+	v.refreshView()
+}
+
+func (v *MergedResultView) Len() int {
+	v.mux.RLock()
+	defer v.mux.RUnlock()
+	return len(v.view)
+}
+
+func (v *MergedResultView) Fields() []string {
+	return MergedResultList{}.Fields()
+}
+
+func (v *MergedResultView) Item(id int) []string {
+	v.mux.RLock()
+	defer v.mux.RUnlock()
+	return v.view.Item(id)
+}
+
+// At returns the book with index id in view, nil if id is out of range
+func (v *MergedResultView) At(id int) *MergedResult {
+	v.mux.RLock()
+	defer v.mux.RUnlock()
+	if id < 0 || id >= len(v.view) {
+		return nil
+	}
+	return v.view[id]
+}
+
+// Replace replaces book old with mr at the same place, the order is updated by next sort
+func (v *MergedResultView) Replace(old, mr *MergedResult) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+	for _, list := range []MergedResultList{v.list, v.view} {
+		for i := range list {
+			if list[i] == old {
+				list[i] = mr
+			}
+		}
+	}
+}
+
+// Sort sorts all books by field, the order is kept for books set later
+func (v *MergedResultView) Sort(field int, ascend bool) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+	v.sortField = field
+	v.ascend = ascend
+	v.refreshView()
+}
+
+// Filter shows only books that field i contains kw, i == -1 means any field;
+// an empty kw clears the filter
+func (v *MergedResultView) Filter(kw string, i int) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+	v.kw = kw
+	v.kwField = i
+	v.refreshView()
+}
+
+// SourceList is the sources of a merged result
+type SourceList SearchResultList
+
+func (list SourceList) Len() int {
+	return len(list)
+}
+
+func (list SourceList) Fields() []string {
+	return []string{"插件", "章节数", "最后更新", "大小", "URL"}
+}
+
+func (list SourceList) Item(id int) []string {
+	if id < 0 || id >= len(list) {
+		return nil
+	}
+	count := "?"
+	if list[id].ChapterCount > 0 {
+		count = fmt.Sprintf("%d", list[id].ChapterCount)
+	}
+	return []string{
+		list[id].PluginName,
+		count,
+		list[id].LastUpdate.Format("2006-01-02"),
+		list[id].BookSize,
+		list[id].BookPageURL,
+	}
+}
+
+func (list SourceList) Sort(field int, ascend bool) {
+	less := func(i, j int) bool {
+		a, b := list[i], list[j]
+		switch field {
+		case 1:
+			return a.ChapterCount < b.ChapterCount
+		case 2:
+			return a.LastUpdate.Before(b.LastUpdate)
+		case 3:
+			return ParseSize(a.BookSize) < ParseSize(b.BookSize)
+		case 4:
+			return a.BookPageURL < b.BookPageURL
+		}
+		return a.PluginName < b.PluginName
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if ascend {
+			return less(i, j)
+		}
+		return less(j, i)
+	})
+}
+
+func (list SourceList) Filter(kw string, i int) {
+}
+
+// FetchChapterCounts gets chapter count of all sources of mr concurrently,
+// the counts are in the order of mr.Sources, 0 means unknown;
+// use WithChapterCounts to save them
+func (mr *MergedResult) FetchChapterCounts(ctx context.Context) []int {
+	counts := make([]int, len(mr.Sources))
+	wg := new(sync.WaitGroup)
+	for i, sr := range mr.Sources {
+		if sr.ChapterCount > 0 {
+			counts[i] = sr.ChapterCount
+			continue
+		}
+		p, ok := LoadedPlugins[sr.PluginName]
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, p *Plugin, url string) {
+			defer wg.Done()
+			total, _, _, err := p.GetBookInfoContext(ctx, url)
+			if err == nil {
+				counts[i] = total
+			}
+		}(i, p, sr.BookPageURL)
+	}
+	wg.Wait()
+	return counts
+}
+
+// WithChapterCounts returns a copy of mr with counts returned by FetchChapterCounts, its sources are sorted again;
+// mr and its sources are not changed, since they could be shown while the counts are being got
+func (mr *MergedResult) WithChapterCounts(counts []int) *MergedResult {
+	r := &MergedResult{BookName: mr.BookName, AuthorName: mr.AuthorName}
+	for i, sr := range mr.Sources {
+		src := *sr
+		if i < len(counts) {
+			src.ChapterCount = counts[i]
+		}
+		r.Sources = append(r.Sources, &src)
+	}
+	r.sortSources()
+	return r
+}
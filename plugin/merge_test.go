@@ -0,0 +1,72 @@
+package plugin
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hujun-open/golitebook/api"
+)
+
+func TestNormalizeBookName(t *testing.T) {
+	cases := map[string]string{
+		"斗破苍穹":      "斗破苍穹",
+		"斗破 苍穹":     "斗破苍穹",
+		"《斗破苍穹》":    "斗破苍穹",
+		"ＡＢＣ：传说":    "abc传说",
+		"abc - 传说!": "abc传说",
+	}
+	for in, expect := range cases {
+		if got := NormalizeBookName(in); got != expect {
+			t.Errorf("NormalizeBookName(%q) = %q, expect %q", in, got, expect)
+		}
+	}
+}
+
+func TestMergeResults(t *testing.T) {
+	now := time.Now()
+	list := SearchResultList{
+		{BookName: "书一", AuthorName: "甲", PluginName: "a", LastUpdate: now.Add(-time.Hour)},
+		{BookName: "书二", AuthorName: "乙", PluginName: "a"},
+		{BookName: "《书一》", AuthorName: "甲 ", PluginName: "b", LastUpdate: now},
+		{BookName: "书一", AuthorName: "丙", PluginName: "c"},
+	}
+	merged := MergeResults(list)
+	if len(merged) != 3 {
+		t.Fatalf("expect 3 books, got %d", len(merged))
+	}
+	if merged[0].BookName != "书一" || len(merged[0].Sources) != 2 {
+		t.Fatalf("expect 书一 with 2 sources, got %+v", merged[0])
+	}
+	if merged[0].Best().PluginName != "b" {
+		t.Fatalf("expect newest source b first, got %v", merged[0].Best().PluginName)
+	}
+	counted := merged[0].WithChapterCounts([]int{0, 10})
+	if counted.Best().PluginName != "a" || counted.Best().ChapterCount != 10 {
+		t.Fatalf("expect source a with more chapters first, got %+v", counted.Best())
+	}
+	// the shown result is not changed
+	if merged[0].Best().PluginName != "b" || list[0].ChapterCount != 0 {
+		t.Fatalf("expect original result unchanged, got %+v", merged[0].Best())
+	}
+}
+
+func TestFallbackUpdatable(t *testing.T) {
+	saved := LoadedPlugins
+	defer func() { LoadedPlugins = saved }()
+	LoadedPlugins = PluginList{
+		"search": &Plugin{Name: "search", mux: new(sync.RWMutex),
+			manifest: &Manifest{Capabilities: []api.Capability{api.Capability_CapSearch}}},
+		"update": &Plugin{Name: "update", mux: new(sync.RWMutex),
+			manifest: legacyManifest("update")},
+	}
+	sub := NewSubscription("book", "author", "url", "search", nil)
+	sub.startingChapter = 10
+	if err := sub.Updatable(); err == nil {
+		t.Fatal("expect not updatable without fallbacks")
+	}
+	sub.SetFallbacks([]Source{{PluginName: "missing"}, {PluginName: "update", BookURL: "url2"}})
+	if err := sub.Updatable(); err != nil {
+		t.Fatalf("expect updatable via fallback, got %v", err)
+	}
+}
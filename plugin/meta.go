@@ -0,0 +1,161 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hujun-open/golitebook/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BookMeta is the metadata of a book
+type BookMeta struct {
+	// Cover is the cover image with MIME type CoverType, empty if the plugin doesn't provide it
+	Cover     []byte   `json:"-"`
+	CoverType string   `json:",omitempty"`
+	Synopsis  string   `json:",omitempty"`
+	Tags      []string `json:",omitempty"`
+	WordCount int64    `json:",omitempty"`
+}
+
+// Empty returns true if no metadata is available
+func (m *BookMeta) Empty() bool {
+	return len(m.Cover) == 0 && m.CoverType == "" && m.Synopsis == "" && len(m.Tags) == 0 && m.WordCount == 0
+}
+
+func (m *BookMeta) TagStr() string {
+	return strings.Join(m.Tags, ",")
+}
+
+// WordCountStr returns the word count in 万字 if it is large
+func (m *BookMeta) WordCountStr() string {
+	switch {
+	case m.WordCount <= 0:
+		return ""
+	case m.WordCount < 10000:
+		return fmt.Sprintf("%d字", m.WordCount)
+	}
+	return fmt.Sprintf("%.1f万字", float64(m.WordCount)/10000)
+}
+
+// metaAPIVersion is the plugin API version added GetBookMeta
+const metaAPIVersion = 3
+
+// hasMeta returns false if the plugin doesn't implement GetBookMeta,
+// either known by its API version or by an Unimplemented error returned before
+func (p *Plugin) hasMeta() bool {
+	m := p.Manifest()
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+	return m != nil && m.APIVersion >= metaAPIVersion && !p.noMeta
+}
+
+// GetBookMeta returns metadata of the book at bookurl,
+// an empty BookMeta is returned without calling the plugin if it doesn't implement GetBookMeta
+func (p *Plugin) GetBookMeta(pctx context.Context, bookurl string) (*BookMeta, error) {
+	if !p.hasMeta() {
+		return &BookMeta{}, nil
+	}
+	client, err := p.Client()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(pctx, rpcTimeout)
+	defer cancel()
+	resp, err := client.GetBookMeta(ctx, &api.GetBookInfoReq{BookPageURL: bookurl})
+	if err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			p.mux.Lock()
+			p.noMeta = true
+			p.mux.Unlock()
+			return &BookMeta{}, nil
+		}
+		return nil, fmt.Errorf("failed to get book metadata, %w", err)
+	}
+	r := &BookMeta{
+		CoverType: resp.CoverType,
+		Synopsis:  resp.Synopsis,
+		Tags:      resp.Tags,
+		WordCount: int64(resp.WordCount),
+	}
+	if p.Supports(api.Capability_CapCover) {
+		r.Cover = resp.Cover
+	}
+	return r, nil
+}
+
+const coverFileName = "cover"
+
+// PutCover saves the cover image of the book
+func (bs *BookStore) PutCover(buf []byte) error {
+	if err := os.MkdirAll(bs.dir, 0755); err != nil {
+		return err
+	}
+	fname := filepath.Join(bs.dir, coverFileName)
+	tmp := fname + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fname)
+}
+
+// Cover returns the saved cover image, nil if there is none
+func (bs *BookStore) Cover() []byte {
+	buf, err := ioutil.ReadFile(filepath.Join(bs.dir, coverFileName))
+	if err != nil {
+		return nil
+	}
+	return buf
+}
+
+// Meta returns metadata of sub, the cover is loaded from its store
+func (sub *Subscription) Meta() BookMeta {
+	sub.mux.RLock()
+	r := sub.meta
+	r.Tags = append([]string{}, sub.meta.Tags...)
+	sub.mux.RUnlock()
+	r.Cover = sub.Store().Cover()
+	return r
+}
+
+// SetMeta saves m as metadata of sub, the cover is saved into its store
+func (sub *Subscription) SetMeta(m BookMeta) error {
+	if len(m.Cover) > 0 {
+		if err := sub.Store().PutCover(m.Cover); err != nil {
+			return err
+		}
+	}
+	m.Cover = nil
+	sub.mux.Lock()
+	defer sub.mux.Unlock()
+	sub.meta = m
+	return nil
+}
+
+// fetchMeta gets metadata of sub via its plugin if it doesn't have any, failure is only logged
+func (sub *Subscription) fetchMeta(ctx context.Context) {
+	sub.mux.RLock()
+	missing := sub.meta.Empty()
+	name, url := sub.pluginName, sub.bookURL
+	sub.mux.RUnlock()
+	if !missing {
+		return
+	}
+	p, ok := LoadedPlugins[name]
+	if !ok {
+		return
+	}
+	m, err := p.GetBookMeta(ctx, url)
+	if err == nil {
+		err = sub.SetMeta(*m)
+	}
+	if err != nil {
+		log.Printf("failed to get metadata of %v, %v", sub.BookName(), err)
+	}
+}
@@ -0,0 +1,61 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestBookMeta(t *testing.T) {
+	os.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+	os.Setenv(fakePluginEnv, "1")
+	legacy, err := NewPlugin(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer legacy.Stop()
+	os.Setenv(fakePluginEnv, "manifest")
+	defer os.Unsetenv(fakePluginEnv)
+	p, err := NewPlugin(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	// plugin of older API version isn't asked for metadata
+	m, err := legacy.GetBookMeta(context.Background(), "book")
+	if err != nil || !m.Empty() {
+		t.Fatalf("expect empty metadata from legacy plugin, got %+v, %v", m, err)
+	}
+	m, err = p.GetBookMeta(context.Background(), "book")
+	if err != nil || m.Synopsis != "synopsis" {
+		t.Fatalf("unexpected metadata %+v, %v", m, err)
+	}
+	if m.WordCountStr() != "12.3万字" || m.TagStr() != "玄幻" {
+		t.Fatalf("unexpected word count %v or tags %v", m.WordCountStr(), m.TagStr())
+	}
+
+	saved := LoadedPlugins
+	defer func() { LoadedPlugins = saved }()
+	LoadedPlugins = PluginList{p.Name: p}
+	sub := NewSubscription("book", "author", "book", p.Name, nil)
+	if _, err := sub.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if m := sub.Meta(); string(m.Cover) != "cover" || m.CoverType != "image/png" || m.WordCount != 123456 {
+		t.Fatalf("unexpected metadata of subscription %+v", m)
+	}
+	buf, err := json.Marshal(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := new(Subscription)
+	if err := json.Unmarshal(buf, loaded); err != nil {
+		t.Fatal(err)
+	}
+	if m := loaded.Meta(); m.Synopsis != "synopsis" || string(m.Cover) != "cover" {
+		t.Fatalf("metadata is not saved, got %+v", m)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -12,12 +13,11 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strings"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/hujun-open/golitebook/api"
-	"github.com/hujun-open/golitebook/char"
 	"github.com/hujun-open/golitebook/conf"
 
 	// "fyne.io/fyne/dialog"
@@ -39,8 +39,6 @@ func GetLocalSavedFilePath(bookname string) string {
 	return filepath.Join(GetLocalSavePath(), bookname+".txt")
 }
 
-const defaultStartingPort = 30000
-
 type SearchResult struct {
 	PluginName  string
 	BookName    string
@@ -49,6 +47,8 @@ type SearchResult struct {
 	AuthorName  string
 	Status      string
 	LastUpdate  time.Time
+	// ChapterCount is 0 if unknown, it is not returned by search
+	ChapterCount int
 }
 
 type SearchResultList []*SearchResult
@@ -77,18 +77,56 @@ func (srl SearchResultList) Item(id int) []string {
 		srl[id].LastUpdate.Format("2006-01-02"),
 	}
 }
-func (srl SearchResultList) Sort(field int, ascend bool) {
-	//TODO
-}
 
-func (srl SearchResultList) Filter(kw string, i int) {
+// Sort sorts by field, size is compared in bytes and last update by time
+func (srl SearchResultList) Sort(field int, ascend bool) {
+	less := func(i, j int) bool {
+		a, b := srl[i], srl[j]
+		switch field {
+		case 1:
+			return a.BookPageURL < b.BookPageURL
+		case 2:
+			return ParseSize(a.BookSize) < ParseSize(b.BookSize)
+		case 3:
+			return a.AuthorName < b.AuthorName
+		case 4:
+			return a.Status < b.Status
+		case 5:
+			return a.LastUpdate.Before(b.LastUpdate)
+		}
+		return a.BookName < b.BookName
+	}
+	sort.SliceStable(srl, func(i, j int) bool {
+		if ascend {
+			return less(i, j)
+		}
+		return less(j, i)
+	})
 }
 
 type Plugin struct {
-	Name   string
-	Cmd    *exec.Cmd
-	Port   int
-	Client api.GoLitebookPluginClient
+	Name string
+	// path is the plugin executable
+	path string
+	mux  *sync.RWMutex
+	cmd  *exec.Cmd
+	port int
+	conn *grpc.ClientConn
+	// client is replaced after the plugin is restarted, use Client() to get it
+	client   api.GoLitebookPluginClient
+	manifest *Manifest
+	state    PluginState
+	restarts int
+	lastErr  error
+	backoff  time.Duration
+	// exited is closed when current plugin process exits
+	exited chan struct{}
+	// wake triggers a health check immediately
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce *sync.Once
+	// noMeta is true if the plugin returned Unimplemented for GetBookMeta
+	noMeta bool
 }
 
 const (
@@ -96,40 +134,34 @@ const (
 	downloadTimeout = 30 * time.Minute
 )
 
-func NewPlugin(p string, port int) (*Plugin, error) {
-	logfpath := filepath.Join(conf.ConfDir(), filepath.Base(p)+".log")
-	cmd := exec.Command(p, "-p", fmt.Sprintf("%d", port), "-logf", logfpath)
-	err := cmd.Start()
-	if err != nil {
+// NewPlugin starts the plugin executable p, and supervises it;
+// error is returned if the plugin API version is not compatible
+func NewPlugin(p string) (*Plugin, error) {
+	r := &Plugin{
+		Name:     filepath.Base(p),
+		path:     p,
+		mux:      new(sync.RWMutex),
+		state:    PluginStarting,
+		backoff:  minRestartBackoff,
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		stopOnce: new(sync.Once),
+	}
+	if err := r.start(); err != nil {
 		return nil, err
 	}
-	time.Sleep(time.Second)
-	conn, err := grpc.Dial(fmt.Sprintf("127.0.0.1:%d", port), grpc.WithInsecure(), grpc.WithBlock())
-	if err != nil {
-		return nil, fmt.Errorf("failed to create API connection to plugin, %v", err)
-	}
-	return &Plugin{Cmd: cmd, Port: port, Name: filepath.Base(p), Client: api.NewGoLitebookPluginClient(conn)}, nil
-
+	go r.supervise()
+	return r, nil
 }
 
-func (p *Plugin) keepalive() {
-	stream, err := p.Client.Keepalive(context.Background())
+func (p *Plugin) GetDesc() (string, error) {
+	client, err := p.Client()
 	if err != nil {
-		return
-	}
-	req := new(api.Empty)
-	for {
-		err = stream.Send(req)
-		if err != nil {
-			return
-		}
-		time.Sleep(10 * time.Second)
+		return "", err
 	}
-}
-
-func (p *Plugin) GetDesc() (string, error) {
-	ctx, _ := context.WithDeadline(context.Background(), time.Now().Add(rpcTimeout))
-	resp, err := p.Client.GetDesc(ctx, &api.Empty{})
+	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
+	defer cancel()
+	resp, err := client.GetDesc(ctx, &api.Empty{})
 	if err != nil {
 		return "", err
 	}
@@ -137,8 +169,18 @@ func (p *Plugin) GetDesc() (string, error) {
 }
 
 func (p *Plugin) SearchBook(kw string) (SearchResultList, error) {
-	ctx, _ := context.WithDeadline(context.Background(), time.Now().Add(rpcTimeout))
-	resp, err := p.Client.Search(ctx, &api.SearchReq{Keyword: kw})
+	return p.SearchBookContext(context.Background(), kw)
+}
+
+// SearchBookContext searches kw, the search stops when pctx is cancelled
+func (p *Plugin) SearchBookContext(pctx context.Context, kw string) (SearchResultList, error) {
+	client, err := p.Client()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(pctx, rpcTimeout)
+	defer cancel()
+	resp, err := client.Search(ctx, &api.SearchReq{Keyword: kw})
 	if err != nil {
 		return nil, err
 	}
@@ -160,11 +202,21 @@ func (p *Plugin) SearchBook(kw string) (SearchResultList, error) {
 
 // GetBookInfo returns total chapter count and name of last chapter
 func (p *Plugin) GetBookInfo(bookurl string) (total int, lastch, indexurl string, err error) {
+	return p.GetBookInfoContext(context.Background(), bookurl)
+}
+
+// GetBookInfoContext is GetBookInfo that stops when pctx is cancelled
+func (p *Plugin) GetBookInfoContext(pctx context.Context, bookurl string) (total int, lastch, indexurl string, err error) {
 	req := new(api.GetBookInfoReq)
 	req.BookPageURL = bookurl
 	var resp *api.GetBookInfoResp
-	ctx, _ := context.WithDeadline(context.Background(), time.Now().Add(rpcTimeout))
-	resp, err = p.Client.GetBookInfo(ctx, req)
+	client, err := p.Client()
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(pctx, rpcTimeout)
+	defer cancel()
+	resp, err = client.GetBookInfo(ctx, req)
 	if err != nil {
 		return
 	}
@@ -176,22 +228,26 @@ func (p *Plugin) GetBookInfo(bookurl string) (total int, lastch, indexurl string
 
 type PluginList map[string]*Plugin
 
-// load plugins from dir p
+// load plugins from dir p, a plugin failed to load is recorded in failedPlugins instead of being returned
 func newPluginList(p string) (PluginList, error) {
 	list := make(map[string]*Plugin)
 	fileList, err := ioutil.ReadDir(p)
 	if err != nil {
 		return nil, err
 	}
-	nextPort := defaultStartingPort
+	failedPlugins = PluginStatusList{}
 	for _, f := range fileList {
+		if f.IsDir() {
+			continue
+		}
 		fullpath := filepath.Join(p, f.Name())
-		p, err := NewPlugin(fullpath, nextPort)
+		p, err := NewPlugin(fullpath)
 		if err != nil {
-			return nil, err
+			log.Printf("failed to load plugin %v, %v", fullpath, err)
+			failedPlugins = append(failedPlugins, PluginStatus{Name: f.Name(), State: PluginFailed, LastError: err.Error()})
+			continue
 		}
 		list[p.Name] = p
-		nextPort++
 	}
 	return list, nil
 }
@@ -204,9 +260,10 @@ func (list PluginList) NameList() []string {
 	return r
 }
 
+// KillAll stops all plugins
 func (list PluginList) KillAll() {
 	for _, p := range list {
-		p.Cmd.Process.Kill()
+		p.Stop()
 	}
 }
 
@@ -249,20 +306,39 @@ const (
 // key is the bookname
 type SubscriptionList struct {
 	list []*Subscription
-	mux  *sync.RWMutex
+	// view is the subscriptions in list match the filter, ids of Item, At, Update and Remove are index of view
+	view []*Subscription
+	// filter keyword and field
+	kw      string
+	kwField int
+	mux     *sync.RWMutex
 }
 
 func NewSubscriptionList() *SubscriptionList {
 	return &SubscriptionList{
-		list: []*Subscription{},
-		mux:  new(sync.RWMutex),
+		list:    []*Subscription{},
+		view:    []*Subscription{},
+		kwField: -1,
+		mux:     new(sync.RWMutex),
 	}
 }
 
 var CurrentSubscriptions *SubscriptionList
 
+// refreshView rebuilds view from list, caller must hold slist.mux
+func (slist *SubscriptionList) refreshView() {
+	slist.view = []*Subscription{}
+	for _, sub := range slist.list {
+		if matchItem(sub.item(), slist.kw, slist.kwField) {
+			slist.view = append(slist.view, sub)
+		}
+	}
+}
+
 func (slist *SubscriptionList) Len() int {
-	return len(slist.list)
+	slist.mux.RLock()
+	defer slist.mux.RUnlock()
+	return len(slist.view)
 }
 func (slist *SubscriptionList) Fields() []string {
 	return []string{
@@ -272,54 +348,106 @@ func (slist *SubscriptionList) Fields() []string {
 		"最新章节",
 		"已有章节数",
 		"最后下载时间",
+		"字数",
 	}
 }
 func (slist *SubscriptionList) Item(id int) []string {
 	slist.mux.RLock()
 	defer slist.mux.RUnlock()
-	slist.list[id].mux.RLock()
-	defer slist.list[id].mux.RUnlock()
-	return []string{
-		slist.list[id].bookName,
-		slist.list[id].statusTxt,
-		slist.list[id].bookURL,
-		slist.list[id].lastChapterName,
-		fmt.Sprintf("%d", slist.list[id].totalChapter),
-		slist.list[id].lastDownloadTime.Format("2006-01-02 15:04:05"),
+	if id < 0 || id >= len(slist.view) {
+		return nil
+	}
+	return slist.view[id].item()
+}
+
+// At returns the subscription of Item(id)
+func (slist *SubscriptionList) At(id int) *Subscription {
+	slist.mux.RLock()
+	defer slist.mux.RUnlock()
+	if id < 0 || id >= len(slist.view) {
+		return nil
 	}
+	return slist.view[id]
 }
 
+// Sort sorts all subscriptions by field, the order is saved
 func (slist *SubscriptionList) Sort(field int, ascend bool) {
+	slist.mux.Lock()
+	defer slist.mux.Unlock()
+	keys := make(map[*Subscription]subscriptionSortKey)
+	for _, sub := range slist.list {
+		keys[sub] = sub.sortKey()
+	}
+	less := func(i, j int) bool {
+		a, b := keys[slist.list[i]], keys[slist.list[j]]
+		switch field {
+		case 1:
+			return a.status < b.status
+		case 2:
+			return a.url < b.url
+		case 3:
+			return a.lastChapter < b.lastChapter
+		case 4:
+			return a.total < b.total
+		case 5:
+			return a.lastDownload.Before(b.lastDownload)
+		case 6:
+			return a.words < b.words
+		}
+		return a.name < b.name
+	}
+	sort.SliceStable(slist.list, func(i, j int) bool {
+		if ascend {
+			return less(i, j)
+		}
+		return less(j, i)
+	})
+	slist.refreshView()
 }
 
+// Filter shows only subscriptions that field i contains kw, i == -1 means any field;
+// an empty kw clears the filter
 func (slist *SubscriptionList) Filter(kw string, i int) {
+	slist.mux.Lock()
+	defer slist.mux.Unlock()
+	slist.kw = kw
+	slist.kwField = i
+	slist.refreshView()
 }
 
 func (slist *SubscriptionList) Update(id int, newval *Subscription) {
 	slist.mux.Lock()
 	defer slist.mux.Unlock()
-	if id < 0 || id >= len(slist.list) {
+	if id < 0 || id >= len(slist.view) {
 		return
 	}
-	slist.list[id] = newval
+	for i := range slist.list {
+		if slist.list[i] == slist.view[id] {
+			slist.list[i] = newval
+		}
+	}
+	slist.refreshView()
 }
 func (slist *SubscriptionList) Remove(id int) {
 	slist.mux.Lock()
 	defer slist.mux.Unlock()
-	if id < 0 || id >= len(slist.list) {
+	if id < 0 || id >= len(slist.view) {
 		return
 	}
-	if id < len(slist.list)-1 {
-		copy(slist.list[id:], slist.list[id+1:])
-		// slist.list[len(slist.list)-1] = nil
+	for i := range slist.list {
+		if slist.list[i] == slist.view[id] {
+			slist.list = append(slist.list[:i], slist.list[i+1:]...)
+			break
+		}
 	}
-	slist.list = slist.list[:len(slist.list)-1]
+	slist.refreshView()
 }
 
 func (slist *SubscriptionList) Append(val *Subscription) {
 	slist.mux.Lock()
 	defer slist.mux.Unlock()
 	slist.list = append(slist.list, val)
+	slist.refreshView()
 }
 
 func (slist *SubscriptionList) Get() []*Subscription {
@@ -334,6 +462,8 @@ func (slist *SubscriptionList) Get() []*Subscription {
 }
 
 func (slist *SubscriptionList) Save() error {
+	slist.mux.RLock()
+	defer slist.mux.RUnlock()
 	os.MkdirAll(GetLocalSavePath(), 0755)
 	buf, err := json.MarshalIndent(slist.list, "", "  ")
 	if err != nil {
@@ -347,17 +477,17 @@ func (slist *SubscriptionList) Load() error {
 	if err != nil {
 		return err
 	}
+	slist.mux.Lock()
+	defer slist.mux.Unlock()
 	err = json.Unmarshal(buf, &slist.list)
 	if err != nil {
 		return err
 	}
-	for i, subs := range CurrentSubscriptions.Get() {
+	for _, subs := range slist.list {
 		subs.finished = DownloadResultNotStarted
 		subs.statusTxt = ""
-		CurrentSubscriptions.Update(i, subs)
-		subs.mux = new(sync.RWMutex)
-		subs.client = LoadedPlugins[subs.pluginName].Client
 	}
+	slist.refreshView()
 	return nil
 }
 
@@ -371,35 +501,54 @@ type Subscription struct {
 	//get called after every chapter is downloaded
 	progressHandler func(url string, finished, total int)
 	pluginName      string
-	client          api.GoLitebookPluginClient
 	finished        uint32
 	statusTxt       string
 	mux             *sync.RWMutex
-	//ResultStr only meanful when Update() is called
-	resultByteList   [][]byte
+	// cancel stops the running Update
+	cancel           context.CancelFunc
 	lastChapterName  string
 	lastDownloadTime time.Time
+	authorName       string
+	// fallbacks are used when the plugin fails
+	fallbacks []Source
+	// fallbackOffsets caches chapter offsets of fallback sources, see fallbackOffset
+	fallbackOffsets map[Source]int
+	// chapterOffset is the store id minus the chapter id in the source,
+	// it is not 0 if the source is changed to one with different chapters before
+	chapterOffset int
+	// meta is the metadata without cover, which is saved in the store
+	meta BookMeta
 }
 
 type subscriptionJSONType struct {
 	BookName         string
+	AuthorName       string
 	BookURL          string
 	StartingChapter  int
 	TotalChapter     int
 	PluginName       string
 	LastChapterName  string
 	LastDownloadTime time.Time
+	Fallbacks        []Source  `json:",omitempty"`
+	ChapterOffset    int       `json:",omitempty"`
+	Meta             *BookMeta `json:",omitempty"`
 }
 
 func (sub *Subscription) MarshalJSON() ([]byte, error) {
 	output := subscriptionJSONType{
 		BookName:         sub.bookName,
+		AuthorName:       sub.authorName,
 		BookURL:          sub.bookURL,
 		StartingChapter:  sub.startingChapter,
 		TotalChapter:     sub.totalChapter,
 		PluginName:       sub.pluginName,
 		LastChapterName:  sub.lastChapterName,
 		LastDownloadTime: sub.lastDownloadTime,
+		Fallbacks:        sub.fallbacks,
+		ChapterOffset:    sub.chapterOffset,
+	}
+	if !sub.meta.Empty() {
+		output.Meta = &sub.meta
 	}
 	return json.MarshalIndent(output, "", "  ")
 }
@@ -410,19 +559,24 @@ func (sub *Subscription) UnmarshalJSON(buf []byte) error {
 		return err
 	}
 	sub.bookName = out.BookName
+	sub.authorName = out.AuthorName
 	sub.bookURL = out.BookURL
 	sub.startingChapter = out.StartingChapter
 	sub.totalChapter = out.TotalChapter
 	sub.pluginName = out.PluginName
 	sub.lastChapterName = out.LastChapterName
 	sub.lastDownloadTime = out.LastDownloadTime
+	sub.fallbacks = out.Fallbacks
+	sub.chapterOffset = out.ChapterOffset
+	if out.Meta != nil {
+		sub.meta = *out.Meta
+	}
 	sub.finished = DownloadResultNotStarted
 	sub.mux = new(sync.RWMutex)
 
-	if p, ok := LoadedPlugins[sub.pluginName]; ok {
-		sub.client = p.Client
-	} else {
-		return fmt.Errorf("failed to load plugin %v", sub.pluginName)
+	if _, ok := LoadedPlugins[sub.pluginName]; !ok {
+		// keep the subscription, it can't be updated until its plugin is loaded
+		log.Printf("plugin %v of subscription %v is not loaded", sub.pluginName, sub.bookName)
 	}
 
 	sub.statusTxt = ""
@@ -430,25 +584,62 @@ func (sub *Subscription) UnmarshalJSON(buf []byte) error {
 }
 
 // NewSubscription create a new subscription, download whole book, via book url
-func NewSubscription(name, bookurl string, pluginName string, h func(string, int, int)) *Subscription {
+func NewSubscription(name, author, bookurl string, pluginName string, h func(string, int, int)) *Subscription {
 	return &Subscription{
 		bookName:         name,
+		authorName:       author,
 		bookURL:          bookurl,
 		startingChapter:  0,
 		progressHandler:  h,
 		pluginName:       pluginName,
-		client:           LoadedPlugins[pluginName].Client,
 		finished:         DownloadResultNotStarted,
 		lastDownloadTime: time.Now(),
 		mux:              new(sync.RWMutex),
 	}
 }
+
 func (task *Subscription) SetHandler(h func(string, int, int)) {
 	task.mux.Lock()
 	defer task.mux.Unlock()
 	task.progressHandler = h
 }
 
+// item returns the fields shown in SubscriptionList
+func (sub *Subscription) item() []string {
+	sub.mux.RLock()
+	defer sub.mux.RUnlock()
+	return []string{
+		sub.bookName,
+		sub.statusTxt,
+		sub.bookURL,
+		sub.lastChapterName,
+		fmt.Sprintf("%d", sub.totalChapter),
+		sub.lastDownloadTime.Format("2006-01-02 15:04:05"),
+		sub.meta.WordCountStr(),
+	}
+}
+
+type subscriptionSortKey struct {
+	name, status, url, lastChapter string
+	total                          int
+	lastDownload                   time.Time
+	words                          int64
+}
+
+func (sub *Subscription) sortKey() subscriptionSortKey {
+	sub.mux.RLock()
+	defer sub.mux.RUnlock()
+	return subscriptionSortKey{
+		name:         sub.bookName,
+		status:       sub.statusTxt,
+		url:          sub.bookURL,
+		lastChapter:  sub.lastChapterName,
+		total:        sub.totalChapter,
+		lastDownload: sub.lastDownloadTime,
+		words:        sub.meta.WordCount,
+	}
+}
+
 func (task *Subscription) SetStatusTxt(t string) {
 	task.mux.Lock()
 	defer task.mux.Unlock()
@@ -461,93 +652,216 @@ func (task *Subscription) Status() uint32 {
 	return task.finished
 }
 
-// Update use sub.StartingChapter and getbookinfo() to update the book to the latest
-func (sub *Subscription) Update() {
-	failed := true
-	defer func() {
-		sub.mux.Lock()
-		defer sub.mux.Unlock()
-		if failed {
-			sub.finished = DownloadResultFailed
-		} else {
-			sub.finished = DownloadResultFinished
-			sub.lastDownloadTime = time.Now()
-		}
+// ErrUpdating is returned when a download task is started while sub is already running one
+var ErrUpdating = errors.New("already updating")
 
-	}()
+// setWaiting shows sub is waiting to be updated, unless it is already running a download task
+func (sub *Subscription) setWaiting() {
 	sub.mux.Lock()
-	sub.finished = DownloadResultWorking
+	defer sub.mux.Unlock()
+	if sub.finished != DownloadResultWorking {
+		sub.statusTxt = "等待更新"
+	}
+}
 
+// clearWaiting clears the status set by setWaiting
+func (sub *Subscription) clearWaiting() {
+	sub.mux.Lock()
+	defer sub.mux.Unlock()
+	if sub.statusTxt == "等待更新" {
+		sub.statusTxt = ""
+	}
+}
+
+// run runs f as a cancellable download task of sub and sets the result status,
+// f returns number of chapters downloaded and to download;
+// it returns number of chapters downloaded, or ErrUpdating if sub is already running a task
+func (sub *Subscription) run(f func(ctx context.Context) (int, int, error)) (int, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub.mux.Lock()
+	if sub.finished == DownloadResultWorking {
+		sub.mux.Unlock()
+		return 0, ErrUpdating
+	}
+	sub.finished = DownloadResultWorking
+	sub.cancel = cancel
 	sub.mux.Unlock()
-	// get book info
-	req := new(api.GetBookInfoReq)
-	req.BookPageURL = sub.bookURL
-	var resp *api.GetBookInfoResp
-	ctx, _ := context.WithDeadline(context.Background(), time.Now().Add(rpcTimeout))
-	resp, err := sub.client.GetBookInfo(ctx, req)
+	finished, total, err := f(ctx)
+	sub.mux.Lock()
+	defer sub.mux.Unlock()
+	sub.cancel = nil
 	if err != nil {
-		// dialog.ShowError(err, nil)
-		log.Printf("failed to get book info, %v", err)
-		return
+		log.Printf("failed to download %v, %v", sub.bookName, err)
+		sub.finished = DownloadResultFailed
+		if ctx.Err() == context.Canceled {
+			sub.statusTxt = fmt.Sprintf("已停止 %d/%d", finished, total)
+		} else {
+			sub.statusTxt = "下载失败：" + err.Error()
+		}
+		return finished, err
 	}
+	sub.finished = DownloadResultFinished
+	sub.lastDownloadTime = time.Now()
+	return finished, nil
+}
+
+func (sub *Subscription) getBookInfo(ctx context.Context, client api.GoLitebookPluginClient, bookurl string) (*api.GetBookInfoResp, error) {
+	ictx, icancel := context.WithTimeout(ctx, rpcTimeout)
+	defer icancel()
+	resp, err := client.GetBookInfo(ictx, &api.GetBookInfoReq{BookPageURL: bookurl})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book info, %w", err)
+	}
+	return resp, nil
+}
+
+// setBookInfo shows the chapter count and last chapter in resp, which is the book info of the source used
+func (sub *Subscription) setBookInfo(resp *api.GetBookInfoResp) {
 	sub.mux.Lock()
+	defer sub.mux.Unlock()
 	sub.totalChapter = int(resp.TotalChapterCount)
 	sub.lastChapterName = resp.LastChapterName
+}
+
+// download downloads chapters with source id in [from, to) into the chapter store as store id source id+offset,
+// every chapter is stored as soon as it arrives; it returns number of chapters downloaded
+func (sub *Subscription) download(ctx context.Context, client api.GoLitebookPluginClient, indexURL string, from, to, offset int) (int, error) {
+	sub.mux.RLock()
 	handler := sub.progressHandler
-	sub.mux.Unlock()
-	if sub.totalChapter-sub.startingChapter == 0 {
-		handler(sub.bookURL, 0, 0)
-		failed = false
-		return
+	sub.mux.RUnlock()
+	if handler == nil {
+		handler = func(string, int, int) {}
 	}
 	getReq := new(api.GetBookReq)
-	getReq.BookIndexURL = resp.BookIndexURL
-	getReq.CurrentChaptCount = uint32(sub.startingChapter)
-	ctx, _ = context.WithDeadline(context.Background(), time.Now().Add(downloadTimeout))
-	stream, err := sub.client.GetBook(ctx, getReq)
+	getReq.BookIndexURL = indexURL
+	getReq.CurrentChaptCount = uint32(from)
+	getReq.UpdateOnly = from > 0
+	dctx, dcancel := context.WithTimeout(ctx, downloadTimeout)
+	defer dcancel()
+	stream, err := client.GetBook(dctx, getReq)
 	if err != nil {
-		log.Printf("failed to get book stream, %v", err)
-		// dialog.ShowError(fmt.Errorf("failed to download, %v", err), nil)
-		return
+		return 0, fmt.Errorf("failed to get book stream, %w", err)
 	}
-	resultList := make([]string, sub.totalChapter-sub.startingChapter)
+	store := sub.Store()
 	finished := 0
-	for {
-		handler(sub.bookURL, finished, sub.totalChapter-sub.startingChapter)
+	for finished < to-from {
+		handler(sub.bookURL, finished, to-from)
 		resp, err := stream.Recv()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			// dialog.ShowError(fmt.Errorf("downloading error, %v", err), nil)
-			log.Printf("downloading error, %v", err)
-			return
+			return finished, fmt.Errorf("downloading error, %w", err)
+		}
+		if int(resp.ChapterId) < from || int(resp.ChapterId) >= to {
+			continue
+		}
+		if int(resp.ChapterId)+offset < 0 {
+			// before the first stored chapter
+			continue
+		}
+		resp.ChapterId = uint32(int(resp.ChapterId) + offset)
+		if err := store.Put(resp); err != nil {
+			return finished, fmt.Errorf("failed to save chapter %d, %w", resp.ChapterId, err)
 		}
-		resultList[int(resp.ChapterId)-sub.startingChapter] = "\n" + BookMarkChar + resp.ChapterName + "\n" + resp.ChapterContent
 		finished++
 	}
+	handler(sub.bookURL, finished, to-from)
+	return finished, nil
+}
 
-	filename := GetLocalSavedFilePath(sub.bookName)
-	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Printf("failed to open file to save, %v", err)
-		// dialog.ShowError(err, nil)
-		return
-	}
-	defer f.Close()
+// Update downloads chapters after the last contiguous stored chapter to update the book to the latest;
+// a failed or cancelled update keeps the chapters already downloaded;
+// it returns number of new chapters
+func (sub *Subscription) Update() (int, error) {
+	return sub.run(func(ctx context.Context) (int, int, error) {
+		if err := sub.Updatable(); err != nil {
+			return 0, 0, err
+		}
+		if err := sub.syncStore(); err != nil {
+			return 0, 0, err
+		}
+		// fallback sources are tried if the plugin of sub fails
+		client, resp, offset, err := sub.connect(ctx)
+		if err != nil {
+			return 0, 0, err
+		}
+		sub.fetchMeta(ctx)
+		sub.mux.RLock()
+		start := sub.startingChapter - offset
+		handler := sub.progressHandler
+		sub.mux.RUnlock()
+		if start < 0 {
+			start = 0
+		}
+		total := int(resp.TotalChapterCount) - start
+		if total <= 0 {
+			if handler != nil {
+				handler(sub.bookURL, 0, 0)
+			}
+			return 0, 0, nil
+		}
+		finished, err := sub.download(ctx, client, resp.BookIndexURL, start, int(resp.TotalChapterCount), offset)
+		if serr := sub.syncStore(); err == nil {
+			err = serr
+		}
+		return finished, total, err
+	})
+}
 
-	sub.mux.Lock()
-	sub.resultByteList = char.SplitLinesBytes([]byte(strings.Join(resultList, "\n\n")))
-	sub.resultByteList = FormatTxt(sub.resultByteList, DefaultFormatMinimalLineWidthInChars, nil)
-	sub.mux.Unlock()
-	if _, err := f.Write(bytes.Join(sub.resultByteList, []byte("\n"))); err != nil {
-		log.Printf("failed to write save file, %v", err)
-		// dialog.ShowError(err, nil)
-		return
+// Redownload downloads chapters with store id in [from, to) again, replacing the stored ones
+func (sub *Subscription) Redownload(from, to int) (int, error) {
+	return sub.run(func(ctx context.Context) (int, int, error) {
+		if from < 0 || from >= to {
+			return 0, 0, fmt.Errorf("invalid chapter range %d-%d", from+1, to)
+		}
+		// convert to ids in the source
+		sub.mux.RLock()
+		offset := sub.chapterOffset
+		sub.mux.RUnlock()
+		from, to = from-offset, to-offset
+		if from < 0 {
+			from = 0
+		}
+		p, ok := LoadedPlugins[sub.pluginName]
+		if !ok {
+			return 0, 0, fmt.Errorf("plugin %v is not loaded", sub.pluginName)
+		}
+		if from > 0 && !p.Supports(api.Capability_CapUpdateOnly) {
+			return 0, 0, fmt.Errorf("plugin %v doesn't support downloading from chapter %d", sub.pluginName, from+1)
+		}
+		client, err := p.Client()
+		if err != nil {
+			return 0, 0, err
+		}
+		resp, err := sub.getBookInfo(ctx, client, sub.bookURL)
+		if err != nil {
+			return 0, 0, err
+		}
+		sub.setBookInfo(resp)
+		if to > int(resp.TotalChapterCount) {
+			to = int(resp.TotalChapterCount)
+		}
+		if from >= to {
+			return 0, 0, fmt.Errorf("no chapter to download in the source")
+		}
+		finished, err := sub.download(ctx, client, resp.BookIndexURL, from, to, offset)
+		if serr := sub.syncStore(); err == nil {
+			err = serr
+		}
+		return finished, to - from, err
+	})
+}
+
+// Cancel stops the running update, chapters already downloaded are kept
+func (sub *Subscription) Cancel() {
+	sub.mux.RLock()
+	cancel := sub.cancel
+	sub.mux.RUnlock()
+	if cancel != nil {
+		cancel()
 	}
-	sub.mux.Lock()
-	sub.startingChapter += finished
-	sub.mux.Unlock()
 }
 
 const (
@@ -0,0 +1,72 @@
+package plugin
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// HandshakePrefix starts the line a plugin could print to stdout after its gRPC server is listening,
+// followed by the actual port, e.g. "GOLITEBOOK_PLUGIN_PORT=30001";
+// it is needed when the plugin listens on a different port than the one specified by "-p", e.g. port 0
+const HandshakePrefix = "GOLITEBOOK_PLUGIN_PORT="
+
+// allocPort returns a free local TCP port
+func allocPort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// handshakeWriter is the stdout of plugin, it sends the port in the first handshake line to ports
+type handshakeWriter struct {
+	mux   *sync.Mutex
+	buf   []byte
+	done  bool
+	ports chan int
+}
+
+func newHandshakeWriter() *handshakeWriter {
+	return &handshakeWriter{
+		mux:   new(sync.Mutex),
+		ports: make(chan int, 1),
+	}
+}
+
+func (hw *handshakeWriter) Write(p []byte) (int, error) {
+	hw.mux.Lock()
+	defer hw.mux.Unlock()
+	if hw.done {
+		return len(p), nil
+	}
+	hw.buf = append(hw.buf, p...)
+	for {
+		i := bytes.IndexByte(hw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimSpace(string(hw.buf[:i]))
+		hw.buf = hw.buf[i+1:]
+		if !strings.HasPrefix(line, HandshakePrefix) {
+			continue
+		}
+		port, err := strconv.Atoi(strings.TrimPrefix(line, HandshakePrefix))
+		if err != nil || port <= 0 || port > 65535 {
+			continue
+		}
+		hw.ports <- port
+		hw.done = true
+		hw.buf = nil
+		break
+	}
+	if len(hw.buf) > 4096 {
+		// not a handshake line, drop it
+		hw.buf = nil
+	}
+	return len(p), nil
+}
@@ -0,0 +1,344 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hujun-open/golitebook/conf"
+)
+
+// UpdateResult is the result of updating a subscription
+type UpdateResult struct {
+	BookName    string
+	NewChapters int
+	Err         error
+	Time        time.Time
+}
+
+type UpdateResultList []UpdateResult
+
+func (list UpdateResultList) Len() int {
+	return len(list)
+}
+
+func (list UpdateResultList) Fields() []string {
+	return []string{"书名", "新章节", "结果", "时间"}
+}
+
+func (list UpdateResultList) Item(id int) []string {
+	if id < 0 || id >= len(list) {
+		return nil
+	}
+	result := "成功"
+	if list[id].Err != nil {
+		result = list[id].Err.Error()
+	}
+	return []string{
+		list[id].BookName,
+		fmt.Sprintf("%d", list[id].NewChapters),
+		result,
+		list[id].Time.Format("2006-01-02 15:04:05"),
+	}
+}
+
+func (list UpdateResultList) Sort(field int, ascend bool) {
+}
+
+func (list UpdateResultList) Filter(kw string, i int) {
+}
+
+// Updated returns the results with new chapters
+func (list UpdateResultList) Updated() UpdateResultList {
+	r := UpdateResultList{}
+	for _, res := range list {
+		if res.Err == nil && res.NewChapters > 0 {
+			r = append(r, res)
+		}
+	}
+	return r
+}
+
+// Failed returns the failed results
+func (list UpdateResultList) Failed() UpdateResultList {
+	r := UpdateResultList{}
+	for _, res := range list {
+		if res.Err != nil {
+			r = append(r, res)
+		}
+	}
+	return r
+}
+
+// pluginLimiter limits the number of concurrent updates via the same plugin,
+// and enforces a min interval between starting two of them
+type pluginLimiter struct {
+	mux         *sync.Mutex
+	interval    time.Duration
+	concurrency int
+	next        map[string]time.Time
+	// sems key is plugin name, value is a semaphore with capacity of concurrency
+	sems map[string]chan struct{}
+}
+
+func newPluginLimiter(interval time.Duration, concurrency int) *pluginLimiter {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &pluginLimiter{
+		mux:         new(sync.Mutex),
+		interval:    interval,
+		concurrency: concurrency,
+		next:        make(map[string]time.Time),
+		sems:        make(map[string]chan struct{}),
+	}
+}
+
+// tryAcquire returns the function to call after the update if an update via plugin name could start now;
+// otherwise it returns nil and how long to wait for the interval, 0 if the plugin has max concurrent updates running
+func (pl *pluginLimiter) tryAcquire(name string) (func(), time.Duration) {
+	pl.mux.Lock()
+	defer pl.mux.Unlock()
+	sem, ok := pl.sems[name]
+	if !ok {
+		sem = make(chan struct{}, pl.concurrency)
+		pl.sems[name] = sem
+	}
+	now := time.Now()
+	if wait := pl.next[name].Sub(now); wait > 0 {
+		return nil, wait
+	}
+	select {
+	case sem <- struct{}{}:
+	default:
+		return nil, 0
+	}
+	pl.next[name] = now.Add(pl.interval)
+	return func() { <-sem }, 0
+}
+
+// Scheduler updates all subscriptions periodically in background
+type Scheduler struct {
+	mux     *sync.RWMutex
+	cfg     conf.AutoUpdateConf
+	limiter *pluginLimiter
+	// handler is called with results after every round of update
+	handler func(UpdateResultList)
+	// progressHandler is called after every subscription in a round is updated
+	progressHandler func(done, failed, total int)
+	results         UpdateResultList
+	lastRun         time.Time
+	// running is 1 when a round of update is running
+	running  int32
+	stop     chan struct{}
+	stopOnce *sync.Once
+}
+
+func NewScheduler(cfg conf.AutoUpdateConf, h func(UpdateResultList)) *Scheduler {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	return &Scheduler{
+		mux:      new(sync.RWMutex),
+		cfg:      cfg,
+		limiter:  newPluginLimiter(time.Duration(cfg.PluginIntervalSeconds)*time.Second, cfg.PluginConcurrency),
+		handler:  h,
+		stop:     make(chan struct{}),
+		stopOnce: new(sync.Once),
+	}
+}
+
+// Start starts the periodical update, it does nothing if auto update is disabled
+func (s *Scheduler) Start() {
+	if s.cfg.IntervalMinutes <= 0 {
+		return
+	}
+	go s.loop(time.Duration(s.cfg.IntervalMinutes) * time.Minute)
+}
+
+func (s *Scheduler) loop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+		s.RunNow()
+	}
+}
+
+// Stop stops the periodical update, updates not started yet are skipped
+func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+// SetProgressHandler sets h to be called after every subscription in a round is updated
+func (s *Scheduler) SetProgressHandler(h func(done, failed, total int)) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.progressHandler = h
+}
+
+// Running returns true if a round of update is running
+func (s *Scheduler) Running() bool {
+	return atomic.LoadInt32(&s.running) == 1
+}
+
+// ErrRunning is returned when a round of update is started while another one is running
+var ErrRunning = errors.New("a round of update is already running")
+
+// RunNow updates all current subscriptions, it returns ErrRunning if a round of update is already running
+func (s *Scheduler) RunNow() (UpdateResultList, error) {
+	return s.Run(CurrentSubscriptions.Get())
+}
+
+// Run updates subs as a round of update, it returns ErrRunning if a round of update is already running
+func (s *Scheduler) Run(subs []*Subscription) (UpdateResultList, error) {
+	if !atomic.CompareAndSwapInt32(&s.running, 0, 1) {
+		return nil, ErrRunning
+	}
+	defer atomic.StoreInt32(&s.running, 0)
+	results := s.UpdateAll(subs)
+	s.mux.Lock()
+	s.results = results
+	s.lastRun = time.Now()
+	s.mux.Unlock()
+	log.Printf("update finished, %d updated, %d failed", len(results.Updated()), len(results.Failed()))
+	if s.handler != nil {
+		s.handler(results)
+	}
+	return results, nil
+}
+
+// Results returns results of the last round of update and when it finished
+func (s *Scheduler) Results() (UpdateResultList, time.Time) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return s.results, s.lastRun
+}
+
+type updateJob struct {
+	id  int
+	n   int
+	err error
+}
+
+// UpdateAll updates subs with at most cfg.Concurrency updates running at the same time,
+// and at most cfg.PluginConcurrency of them via the same plugin;
+// every plugin has its own queue, an update is only started when its plugin is free,
+// so that updates via a busy plugin don't hold others;
+// subscriptions already being updated are skipped, as are the ones not started before the scheduler stops;
+// skipped ones are counted as done in the progress
+func (s *Scheduler) UpdateAll(subs []*Subscription) UpdateResultList {
+	results := make(UpdateResultList, len(subs))
+	skipped := make([]bool, len(subs))
+	total := len(subs)
+	// queues key is plugin name, names is the order of plugins
+	queues := make(map[string][]int)
+	names := []string{}
+	for i, sub := range subs {
+		sub.setWaiting()
+		name := sub.sources()[0].PluginName
+		if _, ok := queues[name]; !ok {
+			names = append(names, name)
+		}
+		queues[name] = append(queues[name], i)
+	}
+	s.mux.RLock()
+	progress := s.progressHandler
+	s.mux.RUnlock()
+	if progress == nil {
+		progress = func(done, failed, total int) {}
+	}
+	progress(0, 0, total)
+	done, failed := 0, 0
+	queued, running := total, 0
+	finished := make(chan updateJob)
+	stop := s.stop
+	// skipQueued skips updates not started yet
+	skipQueued := func() {
+		stop = nil
+		for _, name := range names {
+			for _, id := range queues[name] {
+				skipped[id] = true
+				subs[id].clearWaiting()
+				done++
+			}
+			queues[name] = nil
+		}
+		queued = 0
+		progress(done, failed, total)
+	}
+	for queued > 0 || running > 0 {
+		select {
+		case <-stop:
+			skipQueued()
+			continue
+		default:
+		}
+		// start updates of free plugins, wait is the shortest time until a plugin is free of its interval
+		var wait time.Duration
+		for _, name := range names {
+			for len(queues[name]) > 0 && running < s.cfg.Concurrency {
+				release, w := s.limiter.tryAcquire(name)
+				if release == nil {
+					if w > 0 && (wait == 0 || w < wait) {
+						wait = w
+					}
+					break
+				}
+				id := queues[name][0]
+				queues[name] = queues[name][1:]
+				queued--
+				running++
+				go func(id int) {
+					defer release()
+					n, err := subs[id].Update()
+					finished <- updateJob{id: id, n: n, err: err}
+				}(id)
+			}
+		}
+		var timer <-chan time.Time
+		if wait > 0 && running < s.cfg.Concurrency {
+			timer = time.After(wait)
+		}
+		select {
+		case job := <-finished:
+			running--
+			if errors.Is(job.err, ErrUpdating) {
+				// started by user while waiting, or already running
+				skipped[job.id] = true
+			} else {
+				if job.err != nil {
+					failed++
+				}
+				results[job.id] = UpdateResult{
+					BookName:    subs[job.id].BookName(),
+					NewChapters: job.n,
+					Err:         job.err,
+					Time:        time.Now(),
+				}
+			}
+			done++
+			progress(done, failed, total)
+		case <-timer:
+		case <-stop:
+			// running ones are waited
+			skipQueued()
+		}
+	}
+	r := UpdateResultList{}
+	for i := range results {
+		if !skipped[i] {
+			r = append(r, results[i])
+		}
+	}
+	return r
+}
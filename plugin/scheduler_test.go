@@ -0,0 +1,121 @@
+package plugin
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hujun-open/golitebook/conf"
+)
+
+func TestPluginLimiter(t *testing.T) {
+	pl := newPluginLimiter(100*time.Millisecond, 1)
+	releaseA, _ := pl.tryAcquire("a")
+	releaseB, _ := pl.tryAcquire("b")
+	if releaseA == nil || releaseB == nil {
+		t.Fatal("different plugins should not wait for each other")
+	}
+	releaseB()
+	if release, wait := pl.tryAcquire("a"); release != nil || wait <= 0 {
+		t.Fatalf("expect waiting for the interval of plugin a, got %v", wait)
+	}
+	time.Sleep(150 * time.Millisecond)
+	// concurrency of plugin a is 1, the next one waits until released
+	if release, wait := pl.tryAcquire("a"); release != nil || wait != 0 {
+		t.Fatal("expect waiting for the running update of plugin a")
+	}
+	releaseA()
+	if release, _ := pl.tryAcquire("a"); release == nil {
+		t.Fatal("expect plugin a free after released")
+	}
+}
+
+func TestUpdateAll(t *testing.T) {
+	s := NewScheduler(conf.AutoUpdateConf{Concurrency: 2}, nil)
+	working := NewSubscription("working", "", "url0", "none", nil)
+	working.finished = DownloadResultWorking
+	subs := []*Subscription{
+		working,
+		NewSubscription("book1", "", "url1", "none", nil),
+		NewSubscription("book2", "", "url2", "none", nil),
+	}
+	if _, err := working.Update(); !errors.Is(err, ErrUpdating) {
+		t.Fatalf("expect ErrUpdating, got %v", err)
+	}
+	mux := new(sync.Mutex)
+	var maxDone, lastTotal int
+	s.SetProgressHandler(func(done, failed, total int) {
+		mux.Lock()
+		defer mux.Unlock()
+		if done > maxDone {
+			maxDone = done
+		}
+		lastTotal = total
+	})
+	results := s.UpdateAll(subs)
+	if maxDone != lastTotal {
+		t.Fatalf("expect all done, got %d/%d", maxDone, lastTotal)
+	}
+	// subscription being updated is skipped, others fail since the plugin is not loaded
+	if len(results) != 2 || len(results.Failed()) != 2 {
+		t.Fatalf("expect 2 failed results, got %+v", results)
+	}
+	if subs[1].Status() != DownloadResultFailed {
+		t.Fatalf("expect book1 failed, got %v", subs[1].Status())
+	}
+}
+
+func TestRunStopped(t *testing.T) {
+	s := NewScheduler(conf.AutoUpdateConf{Concurrency: 1}, nil)
+	s.running = 1
+	if _, err := s.Run(nil); !errors.Is(err, ErrRunning) {
+		t.Fatalf("expect ErrRunning, got %v", err)
+	}
+	s.running = 0
+	s.Stop()
+	var lastDone, lastTotal int
+	s.SetProgressHandler(func(done, failed, total int) {
+		lastDone, lastTotal = done, total
+	})
+	results, err := s.Run([]*Subscription{NewSubscription("book1", "", "url1", "none", nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// updates not started before stopped are skipped, and counted as done
+	if len(results) != 0 || lastDone != 1 || lastTotal != 1 {
+		t.Fatalf("expect skipped and done, got %+v, %d/%d", results, lastDone, lastTotal)
+	}
+}
+
+func TestUpdateAllBusyPlugin(t *testing.T) {
+	s := NewScheduler(conf.AutoUpdateConf{Concurrency: 1}, nil)
+	s.limiter = newPluginLimiter(time.Hour, 1)
+	subs := []*Subscription{
+		NewSubscription("book1", "", "url1", "slow", nil),
+		NewSubscription("book2", "", "url2", "slow", nil),
+		NewSubscription("book3", "", "url3", "fast", nil),
+	}
+	s.SetProgressHandler(func(done, failed, total int) {
+		if done == 2 {
+			s.Stop()
+		}
+	})
+	finished := make(chan UpdateResultList)
+	go func() {
+		finished <- s.UpdateAll(subs)
+	}()
+	var results UpdateResultList
+	select {
+	case results = <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("updates via plugin fast are held by plugin slow")
+	}
+	// book2 waits for the interval of plugin slow, skipped after stopped
+	if len(results) != 2 || results[1].BookName != "book3" {
+		t.Fatalf("expect book1 and book3 updated, got %+v", results)
+	}
+	if subs[1].item()[1] != "" {
+		t.Fatal("expect book2 not waiting after skipped")
+	}
+}
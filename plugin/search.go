@@ -0,0 +1,42 @@
+package plugin
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/hujun-open/golitebook/api"
+)
+
+// SearchAll searches kw via plugins concurrently, h is called as soon as each plugin answers;
+// it returns after all plugins answered or ctx is cancelled
+func SearchAll(ctx context.Context, plugins []*Plugin, kw string, h func(p *Plugin, results SearchResultList, err error)) {
+	wg := new(sync.WaitGroup)
+	for _, p := range plugins {
+		if !p.Supports(api.Capability_CapSearch) {
+			continue
+		}
+		wg.Add(1)
+		go func(p *Plugin) {
+			defer wg.Done()
+			results, err := p.SearchBookContext(ctx, kw)
+			if ctx.Err() != nil {
+				// cancelled
+				return
+			}
+			h(p, results, err)
+		}(p)
+	}
+	wg.Wait()
+}
+
+// Plugins returns all loaded plugins sorted by name
+func (list PluginList) Plugins() []*Plugin {
+	names := list.NameList()
+	sort.Strings(names)
+	r := []*Plugin{}
+	for _, n := range names {
+		r = append(r, list[n])
+	}
+	return r
+}
@@ -0,0 +1,40 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestSearchAll(t *testing.T) {
+	os.Setenv(fakePluginEnv, "1")
+	defer os.Unsetenv(fakePluginEnv)
+	plugins := []*Plugin{}
+	for i := 0; i < 2; i++ {
+		p, err := NewPlugin(os.Args[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p.Stop()
+		plugins = append(plugins, p)
+	}
+	mux := new(sync.Mutex)
+	var all SearchResultList
+	SearchAll(context.Background(), plugins, "book", func(p *Plugin, results SearchResultList, err error) {
+		if err != nil {
+			t.Error(err)
+		}
+		mux.Lock()
+		all = append(all, results...)
+		mux.Unlock()
+	})
+	if len(all) != 2 || all[0].BookName != "book" {
+		t.Fatalf("expect 2 results, got %+v", all)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	SearchAll(ctx, plugins, "book", func(*Plugin, SearchResultList, error) {
+		t.Error("expect no result after cancelled")
+	})
+}
@@ -0,0 +1,62 @@
+package plugin
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// matchItem returns true if field i of item contains kw, case is ignored;
+// i == -1 means any field, an empty kw matches everything
+func matchItem(item []string, kw string, i int) bool {
+	kw = strings.ToLower(strings.TrimSpace(kw))
+	if kw == "" {
+		return true
+	}
+	for fi, v := range item {
+		if i != -1 && fi != i {
+			continue
+		}
+		if strings.Contains(strings.ToLower(v), kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// size units returned by plugins, both K and KB are 1024 bytes;
+// 字 is a char, counted as 3 bytes as in UTF-8
+var sizeUnits = map[string]float64{
+	"":   1,
+	"b":  1,
+	"k":  1 << 10,
+	"kb": 1 << 10,
+	"m":  1 << 20,
+	"mb": 1 << 20,
+	"g":  1 << 30,
+	"gb": 1 << 30,
+	"字":  3,
+	"千字": 3 * 1000,
+	"万字": 3 * 10000,
+}
+
+// ParseSize parses a book size like "1.5MB", "300K" or "12万字" into bytes,
+// -1 is returned if s can't be parsed
+func ParseSize(s string) int64 {
+	s = strings.TrimSpace(s)
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.' && r != ','
+	})
+	if end == -1 {
+		end = len(s)
+	}
+	num, err := strconv.ParseFloat(strings.ReplaceAll(s[:end], ",", ""), 64)
+	if err != nil {
+		return -1
+	}
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(s[end:]))]
+	if !ok {
+		return -1
+	}
+	return int64(num * unit)
+}
@@ -0,0 +1,137 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{
+		"100":     100,
+		"1.5K":    1536,
+		"2 MB":    2 << 20,
+		"1,024b":  1024,
+		"1G":      1 << 30,
+		"12万字":    360000,
+		"unknown": -1,
+		"":        -1,
+		"3 TB":    -1,
+	}
+	for in, expect := range cases {
+		if got := ParseSize(in); got != expect {
+			t.Errorf("ParseSize(%q) = %d, expect %d", in, got, expect)
+		}
+	}
+}
+
+func TestSearchResultListSort(t *testing.T) {
+	now := time.Now()
+	list := SearchResultList{
+		{BookName: "b", AuthorName: "甲", BookSize: "2M", LastUpdate: now},
+		{BookName: "a", AuthorName: "乙", BookSize: "900K", LastUpdate: now.Add(-time.Hour)},
+		{BookName: "c", AuthorName: "甲", BookSize: "1.5M", LastUpdate: now.Add(time.Hour)},
+	}
+	list.Sort(2, true)
+	if list[0].BookName != "a" || list[2].BookName != "b" {
+		t.Fatalf("unexpected order by size %v %v %v", list[0].BookName, list[1].BookName, list[2].BookName)
+	}
+	list.Sort(5, false)
+	if list[0].BookName != "c" || list[2].BookName != "a" {
+		t.Fatalf("unexpected order by last update %v %v %v", list[0].BookName, list[1].BookName, list[2].BookName)
+	}
+	list.Sort(0, true)
+	if list[0].BookName != "a" {
+		t.Fatalf("unexpected order by name %v", list[0].BookName)
+	}
+}
+
+func TestMergedResultViewSortFilter(t *testing.T) {
+	v := NewMergedResultView()
+	v.SetList(MergeResults(SearchResultList{
+		{BookName: "b", AuthorName: "甲"},
+		{BookName: "a", AuthorName: "乙"},
+	}))
+	v.Sort(0, true)
+	v.Filter("甲", 1)
+	if v.Len() != 1 || v.At(0).BookName != "b" {
+		t.Fatalf("expect only b, got %d", v.Len())
+	}
+	// the sort and filter are kept when the list is replaced
+	v.SetList(MergeResults(SearchResultList{
+		{BookName: "c", AuthorName: "甲"},
+		{BookName: "b", AuthorName: "甲"},
+		{BookName: "a", AuthorName: "乙"},
+	}))
+	if v.Len() != 2 || v.At(0).BookName != "b" || v.At(1).BookName != "c" {
+		t.Fatalf("expect b, c, got %v", v.Item(0))
+	}
+	v.Filter("", -1)
+	if v.Len() != 3 || v.At(0).BookName != "a" || v.At(3) != nil {
+		t.Fatalf("expect all books sorted by name, got %d", v.Len())
+	}
+	old := v.At(1)
+	counted := old.WithChapterCounts([]int{10})
+	v.Replace(old, counted)
+	if v.At(1) != counted || v.Len() != 3 {
+		t.Fatalf("expect b replaced in place, got %+v", v.At(1))
+	}
+}
+
+func TestSubscriptionListSortFilter(t *testing.T) {
+	slist := NewSubscriptionList()
+	for _, name := range []string{"Beta", "alpha", "gamma"} {
+		slist.Append(NewSubscription(name, "", "url/"+name, "p", nil))
+	}
+	slist.Sort(0, false)
+	if slist.Item(0)[0] != "gamma" {
+		t.Fatalf("expect gamma first, got %v", slist.Item(0))
+	}
+	slist.Filter("A", -1)
+	if slist.Len() != 3 {
+		t.Fatalf("expect 3 matches, got %d", slist.Len())
+	}
+	slist.Filter("BETA", 0)
+	if slist.Len() != 1 || slist.At(0).BookName() != "Beta" {
+		t.Fatalf("expect only Beta, got %d", slist.Len())
+	}
+	slist.Remove(0)
+	slist.Filter("", -1)
+	if slist.Len() != 2 || len(slist.Get()) != 2 {
+		t.Fatalf("expect 2 subscriptions after removing Beta, got %d", slist.Len())
+	}
+}
+
+// TestSortEqualKeysDescending checks items with equal keys keep their order when sorted descending
+func TestSortEqualKeysDescending(t *testing.T) {
+	list := SearchResultList{
+		{BookName: "a", AuthorName: "甲"},
+		{BookName: "b", AuthorName: "甲"},
+		{BookName: "c", AuthorName: "乙"},
+		{BookName: "d", AuthorName: "甲"},
+	}
+	list.Sort(3, false)
+	got := ""
+	for _, sr := range list {
+		got += sr.BookName
+	}
+	if got != "abdc" {
+		t.Fatalf("expect abdc, got %v", got)
+	}
+	sources := SourceList{
+		{PluginName: "a", ChapterCount: 10},
+		{PluginName: "b", ChapterCount: 10},
+		{PluginName: "c", ChapterCount: 20},
+	}
+	sources.Sort(1, false)
+	if sources[0].PluginName != "c" || sources[1].PluginName != "a" || sources[2].PluginName != "b" {
+		t.Fatalf("expect cab, got %v%v%v", sources[0].PluginName, sources[1].PluginName, sources[2].PluginName)
+	}
+	slist := NewSubscriptionList()
+	for _, name := range []string{"a", "b", "c"} {
+		slist.Append(NewSubscription(name, "", "url", "p", nil))
+	}
+	slist.Sort(2, false)
+	if slist.Item(0)[0] != "a" || slist.Item(2)[0] != "c" {
+		t.Fatalf("expect the order unchanged, got %v, %v", slist.Item(0)[0], slist.Item(2)[0])
+	}
+}
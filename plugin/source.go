@@ -0,0 +1,139 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hujun-open/golitebook/api"
+)
+
+// Source is where a subscription is downloaded from
+type Source struct {
+	PluginName string
+	BookURL    string
+}
+
+// SetFallbacks sets the sources used when the plugin of sub fails,
+// they are expected to have the same chapters in the same order
+func (sub *Subscription) SetFallbacks(list []Source) {
+	sub.mux.Lock()
+	defer sub.mux.Unlock()
+	sub.fallbacks = list
+	sub.fallbackOffsets = nil
+}
+
+// sources returns the source of sub followed by the fallback sources
+func (sub *Subscription) sources() []Source {
+	sub.mux.RLock()
+	defer sub.mux.RUnlock()
+	return append([]Source{{PluginName: sub.pluginName, BookURL: sub.bookURL}}, sub.fallbacks...)
+}
+
+// updatableVia returns error if sub can't be updated via src,
+// a book already downloaded could only be updated by plugin with CapUpdateOnly
+func (sub *Subscription) updatableVia(src Source) error {
+	p, ok := LoadedPlugins[src.PluginName]
+	if !ok {
+		return fmt.Errorf("plugin %v is not loaded", src.PluginName)
+	}
+	sub.mux.RLock()
+	downloaded := sub.startingChapter > 0
+	sub.mux.RUnlock()
+	if downloaded && !p.Supports(api.Capability_CapUpdateOnly) {
+		return fmt.Errorf("plugin %v doesn't support update", src.PluginName)
+	}
+	return nil
+}
+
+// Updatable returns error if sub can't be updated via any of its sources
+func (sub *Subscription) Updatable() error {
+	var firstErr error
+	for _, src := range sub.sources() {
+		err := sub.updatableVia(src)
+		if err == nil {
+			return nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// connect returns the client, book info and chapter offset of the first source works,
+// errors of all sources are returned if they all fail;
+// a fallback source is used only if the last stored chapters are found in it by name,
+// since its chapter ids could differ from the primary one
+func (sub *Subscription) connect(ctx context.Context) (api.GoLitebookPluginClient, *api.GetBookInfoResp, int, error) {
+	var firstErr error
+	for i, src := range sub.sources() {
+		client, resp, err := sub.connectVia(ctx, src)
+		offset := 0
+		if err == nil {
+			if i == 0 {
+				sub.mux.RLock()
+				offset = sub.chapterOffset
+				sub.mux.RUnlock()
+			} else if offset, err = sub.fallbackOffset(ctx, src, client, resp.BookIndexURL); err != nil {
+				err = fmt.Errorf("fallback source %v refused, %w", src.PluginName, err)
+				log.Printf("failed to update %v, %v", sub.BookName(), err)
+			}
+		}
+		if err == nil {
+			if i > 0 {
+				log.Printf("updating %v via fallback source %v, chapter offset %d", sub.BookName(), src.PluginName, offset)
+			}
+			sub.setBookInfo(resp)
+			return client, resp, offset, nil
+		}
+		if ctx.Err() != nil {
+			return nil, nil, 0, err
+		}
+		if firstErr == nil {
+			firstErr = err
+		} else {
+			firstErr = fmt.Errorf("%w; %v", firstErr, err)
+		}
+	}
+	return nil, nil, 0, firstErr
+}
+
+// fallbackOffset returns the chapter offset of fallback source src, see offsetIn;
+// it is cached once found, so that the chapters of src are only got by the first update via it in a session
+func (sub *Subscription) fallbackOffset(ctx context.Context, src Source, client api.GoLitebookPluginClient, indexURL string) (int, error) {
+	sub.mux.RLock()
+	offset, ok := sub.fallbackOffsets[src]
+	// nothing to map if no chapter is stored
+	stored := sub.startingChapter > 0
+	sub.mux.RUnlock()
+	if ok || !stored {
+		return offset, nil
+	}
+	offset, err := sub.offsetIn(ctx, LoadedPlugins[src.PluginName], client, indexURL)
+	if err != nil {
+		return 0, err
+	}
+	sub.mux.Lock()
+	if sub.fallbackOffsets == nil {
+		sub.fallbackOffsets = make(map[Source]int)
+	}
+	sub.fallbackOffsets[src] = offset
+	sub.mux.Unlock()
+	return offset, nil
+}
+
+func (sub *Subscription) connectVia(ctx context.Context, src Source) (api.GoLitebookPluginClient, *api.GetBookInfoResp, error) {
+	if err := sub.updatableVia(src); err != nil {
+		return nil, nil, err
+	}
+	client, err := LoadedPlugins[src.PluginName].Client()
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := sub.getBookInfo(ctx, client, src.BookURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, resp, nil
+}
@@ -0,0 +1,276 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hujun-open/golitebook/api"
+	"github.com/hujun-open/golitebook/char"
+)
+
+// BookStore keeps every chapter of a downloaded book in its own file in a directory per book,
+// named after the ChapterId, the first line of the file is the chapter name;
+// a chapter could be replaced by downloading it again, and a bad download only affects its own chapter
+type BookStore struct {
+	bookName string
+	dir      string
+}
+
+// Chapter is a stored chapter
+type Chapter struct {
+	ID      int
+	Name    string
+	Content string
+}
+
+const chapterFileExt = ".chapter"
+
+// assembledStampFile in the book directory records the stamp of the store when the book is last assembled
+const assembledStampFile = "assembled"
+
+func getBookStorePath(bookname string) string {
+	return filepath.Join(GetLocalSavePath(), "chapters", bookname)
+}
+
+func NewBookStore(bookname string) *BookStore {
+	return &BookStore{
+		bookName: bookname,
+		dir:      getBookStorePath(bookname),
+	}
+}
+
+func (bs *BookStore) chapterPath(id int) string {
+	return filepath.Join(bs.dir, fmt.Sprintf("%08d%v", id, chapterFileExt))
+}
+
+// Put saves ch, replacing the stored chapter with same id
+func (bs *BookStore) Put(ch *api.GetChapterResp) error {
+	if err := os.MkdirAll(bs.dir, 0755); err != nil {
+		return err
+	}
+	fname := bs.chapterPath(int(ch.ChapterId))
+	// write to a temp file first, so a chapter file is either complete or absent
+	tmp := fname + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(ch.ChapterName+"\n"+ch.ChapterContent), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fname)
+}
+
+func (bs *BookStore) Get(id int) (Chapter, error) {
+	buf, err := ioutil.ReadFile(bs.chapterPath(id))
+	if err != nil {
+		return Chapter{}, err
+	}
+	r := Chapter{ID: id}
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		r.Name = string(buf[:i])
+		r.Content = string(buf[i+1:])
+	} else {
+		r.Name = string(buf)
+	}
+	return r, nil
+}
+
+// IDs returns sorted ids of all stored chapters
+func (bs *BookStore) IDs() ([]int, error) {
+	fileList, err := ioutil.ReadDir(bs.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	r := []int{}
+	for _, f := range fileList {
+		if !strings.HasSuffix(f.Name(), chapterFileExt) {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSuffix(f.Name(), chapterFileExt))
+		if err != nil {
+			continue
+		}
+		r = append(r, id)
+	}
+	sort.Ints(r)
+	return r, nil
+}
+
+// Contiguous returns number of stored chapters with contiguous ids starting from 0,
+// downloading should resume from there
+func (bs *BookStore) Contiguous() (int, error) {
+	ids, err := bs.IDs()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, id := range ids {
+		if id != n {
+			break
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Chapters returns all stored chapters sorted by id
+func (bs *BookStore) Chapters() ([]Chapter, error) {
+	ids, err := bs.IDs()
+	if err != nil {
+		return nil, err
+	}
+	r := []Chapter{}
+	for _, id := range ids {
+		ch, err := bs.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, ch)
+	}
+	return r, nil
+}
+
+// Lines assembles all stored chapters into formatted lines for reading
+func (bs *BookStore) Lines() ([][]byte, error) {
+	chapters, err := bs.Chapters()
+	if err != nil {
+		return nil, err
+	}
+	resultList := []string{}
+	for _, ch := range chapters {
+		resultList = append(resultList, "\n"+BookMarkChar+ch.Name+"\n"+ch.Content)
+	}
+	lines := char.SplitLinesBytes([]byte(strings.Join(resultList, "\n\n")))
+	return FormatTxt(lines, DefaultFormatMinimalLineWidthInChars, nil), nil
+}
+
+// stamp changes whenever a chapter is put into the store,
+// it consists of number, total size and latest modification time of the chapter files
+func (bs *BookStore) stamp() (string, error) {
+	fileList, err := ioutil.ReadDir(bs.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	n, size := 0, int64(0)
+	var latest time.Time
+	for _, f := range fileList {
+		if !strings.HasSuffix(f.Name(), chapterFileExt) {
+			continue
+		}
+		n++
+		size += f.Size()
+		if f.ModTime().After(latest) {
+			latest = f.ModTime()
+		}
+	}
+	return fmt.Sprintf("%d %d %d", n, size, latest.UnixNano()), nil
+}
+
+// Assemble writes all stored chapters to the txt file of the book, and returns its path;
+// the file is only rewritten if the store changed since last assembly,
+// release is called with the path right before the file is replaced, so that a reader could unmap it first
+func (bs *BookStore) Assemble(release func(string)) (string, error) {
+	fname := GetLocalSavedFilePath(bs.bookName)
+	stamp, err := bs.stamp()
+	if err != nil {
+		return "", err
+	}
+	stampFile := filepath.Join(bs.dir, assembledStampFile)
+	if last, err := ioutil.ReadFile(stampFile); err == nil && string(last) == stamp {
+		if _, err := os.Stat(fname); err == nil {
+			return fname, nil
+		}
+	}
+	lines, err := bs.Lines()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(GetLocalSavePath(), 0755); err != nil {
+		return "", err
+	}
+	tmp := fname + ".tmp"
+	if err := ioutil.WriteFile(tmp, bytes.Join(lines, []byte("\n")), 0644); err != nil {
+		return "", err
+	}
+	if release != nil {
+		release(fname)
+	}
+	if err := os.Rename(tmp, fname); err != nil {
+		return "", err
+	}
+	// store without any chapter has no directory, it is always assembled
+	if err := ioutil.WriteFile(stampFile, []byte(stamp), 0644); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	return fname, nil
+}
+
+// migrate imports the book file downloaded by old version into the store, if the store doesn't exist
+func (bs *BookStore) migrate() error {
+	if _, err := os.Stat(bs.dir); err == nil || !os.IsNotExist(err) {
+		return err
+	}
+	buf, err := ioutil.ReadFile(GetLocalSavedFilePath(bs.bookName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	id := 0
+	for _, ch := range SplitChapters(char.SplitLinesBytes(buf)) {
+		if ch.Title == prefaceTitle {
+			continue
+		}
+		err = bs.Put(&api.GetChapterResp{
+			ChapterId:      uint32(id),
+			ChapterName:    ch.Title,
+			ChapterContent: strings.Join(ch.Paragraphs, "\n"),
+		})
+		if err != nil {
+			return err
+		}
+		id++
+	}
+	log.Printf("imported %d chapters of %v into chapter store", id, bs.bookName)
+	return nil
+}
+
+func (sub *Subscription) Store() *BookStore {
+	return NewBookStore(sub.BookName())
+}
+
+// syncStore sets sub.startingChapter to number of contiguous stored chapters,
+// book downloaded by old version is imported first
+func (sub *Subscription) syncStore() error {
+	store := sub.Store()
+	if err := store.migrate(); err != nil {
+		return fmt.Errorf("failed to import %v into chapter store, %w", store.bookName, err)
+	}
+	n, err := store.Contiguous()
+	if err != nil {
+		return err
+	}
+	sub.mux.Lock()
+	sub.startingChapter = n
+	sub.mux.Unlock()
+	return nil
+}
+
+// Assemble writes the stored chapters to the txt file for reading, and returns its path,
+// release is called before the file is replaced, see BookStore.Assemble
+func (sub *Subscription) Assemble(release func(string)) (string, error) {
+	store := sub.Store()
+	if err := store.migrate(); err != nil {
+		return "", err
+	}
+	return store.Assemble(release)
+}
@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hujun-open/golitebook/api"
+)
+
+func TestBookStore(t *testing.T) {
+	os.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+	bs := NewBookStore("book")
+	// chapter 2 is missing
+	for _, id := range []uint32{0, 1, 3} {
+		ch := &api.GetChapterResp{ChapterId: id, ChapterName: "name" + string('0'+rune(id)), ChapterContent: "content"}
+		if err := bs.Put(ch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := bs.Contiguous(); err != nil || n != 2 {
+		t.Fatalf("expect 2 contiguous chapters, got %d, %v", n, err)
+	}
+	// replace chapter 1
+	if err := bs.Put(&api.GetChapterResp{ChapterId: 1, ChapterName: "name1", ChapterContent: "revised"}); err != nil {
+		t.Fatal(err)
+	}
+	if ch, err := bs.Get(1); err != nil || ch.Content != "revised" {
+		t.Fatalf("expect revised chapter 1, got %+v, %v", ch, err)
+	}
+	released := ""
+	release := func(fname string) { released = fname }
+	fname, err := bs.Assemble(release)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released != fname {
+		t.Fatalf("expect %v released before replaced, got %q", fname, released)
+	}
+	buf, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{BookMarkChar + "name0", "revised", BookMarkChar + "name3"} {
+		if !strings.Contains(string(buf), s) {
+			t.Fatalf("expect %v in assembled book, got %q", s, buf)
+		}
+	}
+	// not assembled again if the store is not changed
+	released = ""
+	if _, err := bs.Assemble(release); err != nil || released != "" {
+		t.Fatalf("expect unchanged book not assembled, got %q, %v", released, err)
+	}
+	if err := bs.Put(&api.GetChapterResp{ChapterId: 2, ChapterName: "name2", ChapterContent: "content"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bs.Assemble(release); err != nil || released != fname {
+		t.Fatalf("expect changed book assembled, got %q, %v", released, err)
+	}
+	if buf, _ := ioutil.ReadFile(fname); !strings.Contains(string(buf), BookMarkChar+"name2") {
+		t.Fatalf("expect name2 in assembled book, got %q", buf)
+	}
+}
+
+func TestBookStoreMigrate(t *testing.T) {
+	os.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+	os.MkdirAll(GetLocalSavePath(), 0755)
+	legacy := "\n" + BookMarkChar + "第一章\n\n内容一\n\n" + BookMarkChar + "第二章\n\n内容二"
+	if err := ioutil.WriteFile(GetLocalSavedFilePath("old"), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sub := NewSubscription("old", "author", "url", "fake", nil)
+	if err := sub.syncStore(); err != nil {
+		t.Fatal(err)
+	}
+	if sub.startingChapter != 2 {
+		t.Fatalf("expect 2 chapters imported, got %d", sub.startingChapter)
+	}
+	if ch, err := sub.Store().Get(1); err != nil || ch.Name != "第二章" || ch.Content != "内容二" {
+		t.Fatalf("unexpected chapter %+v, %v", ch, err)
+	}
+}
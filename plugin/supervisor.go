@@ -0,0 +1,471 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hujun-open/golitebook/api"
+	"github.com/hujun-open/golitebook/conf"
+	"google.golang.org/grpc"
+)
+
+type PluginState int
+
+const (
+	PluginStarting PluginState = iota
+	PluginRunning
+	// PluginUnhealthy means the plugin process is running but failed the health check
+	PluginUnhealthy
+	PluginRestarting
+	PluginStopped
+	// PluginFailed means the plugin failed to load
+	PluginFailed
+)
+
+func (s PluginState) String() string {
+	switch s {
+	case PluginStarting:
+		return "启动中"
+	case PluginRunning:
+		return "运行中"
+	case PluginUnhealthy:
+		return "无响应"
+	case PluginRestarting:
+		return "重启中"
+	case PluginStopped:
+		return "已停止"
+	case PluginFailed:
+		return "加载失败"
+	}
+	return "未知"
+}
+
+const (
+	// max time to wait for a started plugin to accept API connection
+	startTimeout = 10 * time.Second
+	// the allocated port is dialed if there is no handshake line after this long,
+	// handshake line is still accepted until startTimeout
+	handshakeTimeout    = time.Second
+	healthCheckInterval = 15 * time.Second
+	keepaliveInterval   = 10 * time.Second
+	// plugin is restarted after this many health checks failed in a row
+	maxProbeFailures = 3
+	// wait time before restarting is doubled after every failed restart, until the plugin passes a health check
+	minRestartBackoff = time.Second
+	maxRestartBackoff = time.Minute
+)
+
+var statusHandlerMux = new(sync.RWMutex)
+var statusHandler func(*Plugin)
+
+// SetStatusHandler sets h to be called whenever state of a plugin changes
+func SetStatusHandler(h func(*Plugin)) {
+	statusHandlerMux.Lock()
+	defer statusHandlerMux.Unlock()
+	statusHandler = h
+}
+
+func (p *Plugin) setState(state PluginState, err error) {
+	p.mux.Lock()
+	if p.state == PluginStopped {
+		// a stopped plugin is never started again
+		p.mux.Unlock()
+		return
+	}
+	changed := p.state != state || err != nil
+	p.state = state
+	if err != nil {
+		p.lastErr = err
+	}
+	p.mux.Unlock()
+	if !changed {
+		return
+	}
+	statusHandlerMux.RLock()
+	h := statusHandler
+	statusHandlerMux.RUnlock()
+	if h != nil {
+		h(p)
+	}
+}
+
+// start starts the plugin process on a newly allocated port and connects to it
+func (p *Plugin) start() error {
+	port, err := allocPort()
+	if err != nil {
+		return fmt.Errorf("failed to allocate port for plugin %v, %w", p.Name, err)
+	}
+	logfpath := filepath.Join(conf.ConfDir(), p.Name+".log")
+	cmd := exec.Command(p.path, "-p", fmt.Sprintf("%d", port), "-logf", logfpath)
+	hw := newHandshakeWriter()
+	cmd.Stdout = hw
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	exited := make(chan struct{})
+	go func() {
+		err := cmd.Wait()
+		log.Printf("plugin %v exited, %v", p.Name, err)
+		close(exited)
+		p.poke()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+	go func() {
+		// stop dialing if the plugin exits before accepting connection
+		select {
+		case <-exited:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	conn, port, err := dialPlugin(ctx, port, hw.ports)
+	if err != nil {
+		cmd.Process.Kill()
+		return fmt.Errorf("failed to create API connection to plugin %v, %v", p.Name, err)
+	}
+	client := api.NewGoLitebookPluginClient(conn)
+	manifest, err := getManifest(p.Name, client)
+	if err == nil {
+		err = manifest.compatible()
+	}
+	if err != nil {
+		conn.Close()
+		cmd.Process.Kill()
+		return fmt.Errorf("failed to get compatible manifest of plugin %v, %w", p.Name, err)
+	}
+	p.mux.Lock()
+	p.cmd = cmd
+	p.manifest = manifest
+	p.port = port
+	p.conn = conn
+	p.client = client
+	p.exited = exited
+	p.mux.Unlock()
+	p.setState(PluginRunning, nil)
+	go p.keepalive(client)
+	return nil
+}
+
+type dialResult struct {
+	conn *grpc.ClientConn
+	port int
+	// seq is the sequence number of the dialing
+	seq int
+	err error
+}
+
+// dialPlugin connects to the plugin until ctx is done;
+// the port in handshake line is dialed as soon as it is received, canceling the dialing in progress;
+// plugin that doesn't print handshake line listens on the allocated port,
+// which is dialed if there is no handshake line after handshakeTimeout
+func dialPlugin(ctx context.Context, allocated int, handshake <-chan int) (*grpc.ClientConn, int, error) {
+	dialed := make(chan dialResult, 2)
+	pending := 0
+	// port and seq are of the latest dialing, results of earlier ones are stale even if the port is the same
+	port, seq := 0, 0
+	var cancelDial context.CancelFunc
+	dial := func(p int) {
+		if cancelDial != nil {
+			cancelDial()
+		}
+		var dctx context.Context
+		dctx, cancelDial = context.WithCancel(ctx)
+		port = p
+		seq++
+		pending++
+		go func(seq int) {
+			conn, err := grpc.DialContext(dctx, fmt.Sprintf("127.0.0.1:%d", p), grpc.WithInsecure(), grpc.WithBlock())
+			dialed <- dialResult{conn: conn, port: p, seq: seq, err: err}
+		}(seq)
+	}
+	defer func() {
+		if cancelDial != nil {
+			cancelDial()
+		}
+		// close connections of canceled dialing
+		go func(n int) {
+			for i := 0; i < n; i++ {
+				if r := <-dialed; r.conn != nil {
+					r.conn.Close()
+				}
+			}
+		}(pending)
+	}()
+	waitHandshake := time.After(handshakeTimeout)
+	done := ctx.Done()
+	for {
+		select {
+		case p := <-handshake:
+			handshake = nil
+			dial(p)
+		case <-waitHandshake:
+			waitHandshake = nil
+			if port == 0 {
+				dial(allocated)
+			}
+		case r := <-dialed:
+			pending--
+			if r.seq != seq {
+				// canceled by the handshake
+				if r.conn != nil {
+					r.conn.Close()
+				}
+				continue
+			}
+			return r.conn, r.port, r.err
+		case <-done:
+			done = nil
+			if pending == 0 {
+				return nil, 0, ctx.Err()
+			}
+		}
+	}
+}
+
+// kill kills current plugin process and closes the connection to it
+func (p *Plugin) kill() {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.cmd != nil && p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+	if p.conn != nil {
+		p.conn.Close()
+	}
+}
+
+// poke triggers a health check
+func (p *Plugin) poke() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Plugin) keepalive(client api.GoLitebookPluginClient) {
+	stream, err := client.Keepalive(context.Background())
+	if err != nil {
+		log.Printf("failed to start keepalive with plugin %v, %v", p.Name, err)
+		p.poke()
+		return
+	}
+	req := new(api.Empty)
+	for {
+		err = stream.Send(req)
+		if err != nil {
+			if p.State() != PluginStopped {
+				log.Printf("keepalive with plugin %v failed, %v", p.Name, err)
+				p.poke()
+			}
+			return
+		}
+		time.Sleep(keepaliveInterval)
+	}
+}
+
+// probe checks if the plugin responds to API call
+func (p *Plugin) probe() error {
+	p.mux.RLock()
+	exited := p.exited
+	p.mux.RUnlock()
+	select {
+	case <-exited:
+		return fmt.Errorf("plugin %v exited", p.Name)
+	default:
+	}
+	_, err := p.GetDesc()
+	return err
+}
+
+// supervise checks the health of plugin periodically, and restarts it if it crashed or stops responding
+func (p *Plugin) supervise() {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+	failures := 0
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		case <-p.wake:
+		}
+		err := p.probe()
+		if err == nil {
+			failures = 0
+			p.mux.Lock()
+			p.backoff = minRestartBackoff
+			p.mux.Unlock()
+			p.setState(PluginRunning, nil)
+			continue
+		}
+		p.mux.RLock()
+		exited := p.exited
+		p.mux.RUnlock()
+		select {
+		case <-exited:
+			failures = maxProbeFailures
+		default:
+			failures++
+		}
+		log.Printf("health check of plugin %v failed (%d/%d), %v", p.Name, failures, maxProbeFailures, err)
+		if failures < maxProbeFailures {
+			p.setState(PluginUnhealthy, err)
+			continue
+		}
+		if !p.restart(err) {
+			return
+		}
+		failures = 0
+	}
+}
+
+// restart restarts the plugin until it succeeds, with increasing wait time;
+// return false if the plugin is stopped
+func (p *Plugin) restart(reason error) bool {
+	for {
+		p.kill()
+		p.setState(PluginRestarting, reason)
+		p.mux.Lock()
+		backoff := p.backoff
+		p.backoff *= 2
+		if p.backoff > maxRestartBackoff {
+			p.backoff = maxRestartBackoff
+		}
+		p.mux.Unlock()
+		log.Printf("restarting plugin %v in %v", p.Name, backoff)
+		select {
+		case <-p.stop:
+			return false
+		case <-time.After(backoff):
+		}
+		err := p.start()
+		if err == nil {
+			select {
+			case <-p.stop:
+				// stopped while starting
+				p.kill()
+				return false
+			default:
+			}
+			p.mux.Lock()
+			p.restarts++
+			p.mux.Unlock()
+			// the new process is only regarded as healthy after passing a health check
+			return true
+		}
+		log.Printf("failed to restart plugin %v, %v", p.Name, err)
+		reason = err
+	}
+}
+
+// Restart kills the plugin process, supervisor will start it again
+func (p *Plugin) Restart() {
+	if p.State() == PluginStopped {
+		return
+	}
+	p.kill()
+}
+
+// Stop kills the plugin and stops supervising it
+func (p *Plugin) Stop() {
+	p.stopOnce.Do(func() {
+		p.setState(PluginStopped, nil)
+		close(p.stop)
+		p.kill()
+	})
+}
+
+func (p *Plugin) State() PluginState {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+	return p.state
+}
+
+// Client returns the API client of the plugin, it returns error if the plugin is not running
+func (p *Plugin) Client() (api.GoLitebookPluginClient, error) {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+	switch p.state {
+	case PluginRunning, PluginUnhealthy:
+		return p.client, nil
+	}
+	return nil, fmt.Errorf("plugin %v is %v", p.Name, p.state)
+}
+
+// PluginStatus is a snapshot of the plugin state
+type PluginStatus struct {
+	Name      string
+	Version   string
+	State     PluginState
+	Port      int
+	Restarts  int
+	LastError string
+}
+
+func (p *Plugin) Status() PluginStatus {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+	r := PluginStatus{
+		Name:     p.Name,
+		State:    p.state,
+		Port:     p.port,
+		Restarts: p.restarts,
+	}
+	if p.manifest != nil {
+		r.Version = p.manifest.Version
+	}
+	if p.lastErr != nil {
+		r.LastError = p.lastErr.Error()
+	}
+	return r
+}
+
+type PluginStatusList []PluginStatus
+
+func (list PluginStatusList) Len() int {
+	return len(list)
+}
+
+func (list PluginStatusList) Fields() []string {
+	return []string{"插件", "版本", "状态", "端口", "重启次数", "最后错误"}
+}
+
+func (list PluginStatusList) Item(id int) []string {
+	if id < 0 || id >= len(list) {
+		return nil
+	}
+	return []string{
+		list[id].Name,
+		list[id].Version,
+		list[id].State.String(),
+		fmt.Sprintf("%d", list[id].Port),
+		fmt.Sprintf("%d", list[id].Restarts),
+		list[id].LastError,
+	}
+}
+
+func (list PluginStatusList) Sort(field int, ascend bool) {
+}
+
+func (list PluginStatusList) Filter(kw string, i int) {
+}
+
+// plugins failed to load by InitPlugins
+var failedPlugins PluginStatusList
+
+// StatusList returns status of all loaded plugins and plugins failed to load, sorted by name
+func StatusList() PluginStatusList {
+	r := append(PluginStatusList{}, failedPlugins...)
+	for _, p := range LoadedPlugins {
+		r = append(r, p.Status())
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Name < r[j].Name })
+	return r
+}
@@ -0,0 +1,247 @@
+// supervisor_test
+package plugin
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hujun-open/golitebook/api"
+	"google.golang.org/grpc"
+)
+
+// env var that makes the test binary run as a fake plugin
+const fakePluginEnv = "GOLITEBOOK_FAKE_PLUGIN"
+
+type fakePlugin struct {
+	api.UnimplementedGoLitebookPluginServer
+}
+
+// manifestPlugin is a fake plugin implements GetManifest
+type manifestPlugin struct {
+	fakePlugin
+	apiVersion uint32
+}
+
+func (mp manifestPlugin) GetManifest(context.Context, *api.Empty) (*api.Manifest, error) {
+	return &api.Manifest{
+		Name:         "manifest",
+		Version:      "1.0",
+		Domains:      []string{"example.com"},
+		APIVersion:   mp.apiVersion,
+		Capabilities: []api.Capability{api.Capability_CapCover, api.Capability_CapUpdateOnly, api.Capability_CapListChapters},
+	}, nil
+}
+
+func (fakePlugin) GetDesc(context.Context, *api.Empty) (*api.PluginDesc, error) {
+	return &api.PluginDesc{Desc: "fake"}, nil
+}
+
+func (fakePlugin) Search(_ context.Context, req *api.SearchReq) (*api.SearchResp, error) {
+	return &api.SearchResp{ResultList: []*api.SearchBookResp{{BookName: req.Keyword, AuthorName: "author"}}}, nil
+}
+
+// fakeChapters is the book returned by fake plugin
+var fakeChapters = []string{"作品相关", "第1章 开始", "第2章 结束"}
+
+func (fakePlugin) GetBookInfo(context.Context, *api.GetBookInfoReq) (*api.GetBookInfoResp, error) {
+	return &api.GetBookInfoResp{
+		TotalChapterCount: uint32(len(fakeChapters)),
+		LastChapterName:   fakeChapters[len(fakeChapters)-1],
+		BookIndexURL:      "index",
+	}, nil
+}
+
+func (fakePlugin) ListChapters(context.Context, *api.ListChaptersReq) (*api.ListChaptersResp, error) {
+	r := &api.ListChaptersResp{}
+	for id, name := range fakeChapters {
+		r.Chapters = append(r.Chapters, &api.ChapterInfo{ChapterId: uint32(id), ChapterName: name, ChapterURL: fmt.Sprintf("url/%d", id)})
+	}
+	return r, nil
+}
+
+func (fakePlugin) GetBookMeta(context.Context, *api.GetBookInfoReq) (*api.BookMeta, error) {
+	return &api.BookMeta{Cover: []byte("cover"), CoverType: "image/png", Synopsis: "synopsis", Tags: []string{"玄幻"}, WordCount: 123456}, nil
+}
+
+func (fakePlugin) GetBook(req *api.GetBookReq, stream api.GoLitebookPlugin_GetBookServer) error {
+	from := 0
+	if req.UpdateOnly {
+		from = int(req.CurrentChaptCount)
+	}
+	for id := from; id < len(fakeChapters); id++ {
+		err := stream.Send(&api.GetChapterResp{ChapterId: uint32(id), ChapterName: fakeChapters[id], ChapterContent: "content"})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fakePlugin) Keepalive(stream api.GoLitebookPlugin_KeepaliveServer) error {
+	for {
+		if _, err := stream.Recv(); err != nil {
+			return err
+		}
+	}
+}
+
+// runFakePlugin runs a plugin listening on the port specified by "-p",
+// or a random port reported via handshake line if mode is "handshake" or "slowhandshake",
+// the latter prints the handshake line after handshakeTimeout;
+// if mode is "lateport", it prints the allocated port in handshake line after handshakeTimeout, then listens on it;
+// the plugin implements GetManifest if mode is "manifest" or "incompatible"
+func runFakePlugin(mode string) {
+	handshake := mode == "handshake" || mode == "slowhandshake"
+	fs := flag.NewFlagSet("fake", flag.ExitOnError)
+	port := fs.Int("p", 0, "")
+	fs.String("logf", "", "")
+	fs.Parse(os.Args[1:])
+	if handshake {
+		*port = 0
+	}
+	if mode == "lateport" {
+		time.Sleep(handshakeTimeout + 500*time.Millisecond)
+		fmt.Printf("%v%d\n", HandshakePrefix, *port)
+		// the dialing started after handshakeTimeout is still waiting when the handshake line arrives
+		time.Sleep(500 * time.Millisecond)
+	}
+	l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", *port))
+	if err != nil {
+		os.Exit(1)
+	}
+	if mode == "slowhandshake" {
+		time.Sleep(handshakeTimeout + 500*time.Millisecond)
+	}
+	if handshake {
+		fmt.Println("starting fake plugin")
+		fmt.Printf("%v%d\n", HandshakePrefix, l.Addr().(*net.TCPAddr).Port)
+	}
+	s := grpc.NewServer()
+	switch mode {
+	case "manifest":
+		api.RegisterGoLitebookPluginServer(s, manifestPlugin{apiVersion: APIVersion})
+	case "incompatible":
+		api.RegisterGoLitebookPluginServer(s, manifestPlugin{apiVersion: APIVersion + 1})
+	default:
+		api.RegisterGoLitebookPluginServer(s, fakePlugin{})
+	}
+	s.Serve(l)
+}
+
+func TestMain(m *testing.M) {
+	if v := os.Getenv(fakePluginEnv); v != "" {
+		runFakePlugin(v)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func waitFor(t *testing.T, desc string, f func() bool) {
+	deadline := time.Now().Add(10 * time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %v", desc)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestSupervisor(t *testing.T) {
+	os.Setenv(fakePluginEnv, "1")
+	defer os.Unsetenv(fakePluginEnv)
+	p, err := NewPlugin(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+	if desc, err := p.GetDesc(); err != nil || desc != "fake" {
+		t.Fatalf("expect desc fake, got %v, %v", desc, err)
+	}
+	// crash the plugin, it should be restarted
+	p.Restart()
+	waitFor(t, "restart", func() bool {
+		st := p.Status()
+		return st.Restarts == 1 && st.State == PluginRunning
+	})
+	if _, err := p.GetDesc(); err != nil {
+		t.Fatalf("failed to call restarted plugin, %v", err)
+	}
+	p.Stop()
+	if p.State() != PluginStopped {
+		t.Fatalf("expect stopped, got %v", p.State())
+	}
+	if _, err := p.Client(); err == nil {
+		t.Fatal("expect no client after stopped")
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	os.Setenv(fakePluginEnv, "handshake")
+	defer os.Unsetenv(fakePluginEnv)
+	p, err := NewPlugin(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+	if _, err := p.GetDesc(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSlowHandshake checks the handshake line is accepted after the allocated port is dialed
+func TestSlowHandshake(t *testing.T) {
+	os.Setenv(fakePluginEnv, "slowhandshake")
+	defer os.Unsetenv(fakePluginEnv)
+	p, err := NewPlugin(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+	if _, err := p.GetDesc(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestLateAllocatedPort checks the handshake line of the allocated port after the port is dialed
+func TestLateAllocatedPort(t *testing.T) {
+	os.Setenv(fakePluginEnv, "lateport")
+	defer os.Unsetenv(fakePluginEnv)
+	p, err := NewPlugin(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+	if _, err := p.GetDesc(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewPluginList(t *testing.T) {
+	os.Setenv(fakePluginEnv, "1")
+	defer os.Unsetenv(fakePluginEnv)
+	dir := t.TempDir()
+	if err := os.Symlink(os.Args[0], filepath.Join(dir, "good")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "bad"), []byte("not a plugin"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	list, err := newPluginList(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer list.KillAll()
+	if _, ok := list["good"]; !ok || len(list) != 1 {
+		t.Fatalf("expect only plugin good loaded, got %v", list.NameList())
+	}
+	if len(failedPlugins) != 1 || failedPlugins[0].Name != "bad" {
+		t.Fatalf("expect plugin bad failed, got %+v", failedPlugins)
+	}
+}
@@ -0,0 +1,77 @@
+package searchdown
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hujun-open/golitebook/plugin"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/hujun-open/dvlist"
+)
+
+// AutoUpdateWin shows results of the last round of update, either in background or started by user, it is not modal
+type AutoUpdateWin struct {
+	fyne.Window
+	lv        *dvlist.DVList
+	summary   *widget.Label
+	scheduler *plugin.Scheduler
+}
+
+func NewAutoUpdateWin(s *plugin.Scheduler) *AutoUpdateWin {
+	r := new(AutoUpdateWin)
+	r.Window = fyne.CurrentApp().NewWindow("更新结果")
+	r.scheduler = s
+	r.summary = widget.NewLabel("")
+	r.lv, _ = dvlist.NewDVList(plugin.UpdateResultList{})
+	buttonContainer := fyne.NewContainerWithLayout(layout.NewVBoxLayout(),
+		widget.NewSeparator(),
+		fyne.NewContainerWithLayout(layout.NewGridLayout(2),
+			widget.NewButton("立即更新", r.onRunNow),
+			widget.NewButton("关闭", r.Hide),
+		),
+	)
+	r.SetContent(fyne.NewContainerWithLayout(
+		layout.NewBorderLayout(r.summary, buttonContainer, nil, nil),
+		r.summary, buttonContainer, r.lv,
+	))
+	r.Canvas().SetOnTypedKey(r.lv.TypedKey)
+	r.Resize(defaultDialogSize)
+	r.SetCloseIntercept(r.Hide)
+	r.refresh()
+	return r
+}
+
+func (awin *AutoUpdateWin) onRunNow() {
+	awin.summary.SetText("更新中...")
+	go func() {
+		// the summary is refreshed after the running round finishes
+		if _, err := awin.scheduler.RunNow(); errors.Is(err, plugin.ErrRunning) {
+			dialog.ShowError(fmt.Errorf("已经在更新中..."), awin)
+		}
+	}()
+}
+
+func (awin *AutoUpdateWin) refresh() {
+	results, last := awin.scheduler.Results()
+	awin.lv.SetData(results)
+	if last.IsZero() {
+		awin.summary.SetText("还没有进行过更新")
+		return
+	}
+	awin.summary.SetText(fmt.Sprintf("上次更新：%v，%d本书有新章节，%d本书更新失败",
+		last.Format("2006-01-02 15:04:05"), len(results.Updated()), len(results.Failed())))
+}
+
+// updatedBookNames returns names of books with new chapters
+func updatedBookNames(results plugin.UpdateResultList) string {
+	names := []string{}
+	for _, res := range results.Updated() {
+		names = append(names, fmt.Sprintf("%v(%d章)", res.BookName, res.NewChapters))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,105 @@
+package searchdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hujun-open/golitebook/plugin"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/hujun-open/dvlist"
+)
+
+// time limit of searching the book via all plugins
+const findSourceTimeout = time.Minute
+
+// ChangeSourceWin searches other sources of a subscription and changes its source to the selected one
+type ChangeSourceWin struct {
+	fyne.Window
+	lv      *dvlist.DVList
+	status  *widget.Label
+	sub     *plugin.Subscription
+	sources plugin.SourceList
+	// onChanged is called after the source is changed
+	onChanged func(*plugin.Subscription)
+}
+
+func NewChangeSourceWin(onChanged func(*plugin.Subscription)) *ChangeSourceWin {
+	r := new(ChangeSourceWin)
+	r.Window = fyne.CurrentApp().NewWindow("换源")
+	r.onChanged = onChanged
+	r.status = widget.NewLabel("")
+	r.lv, _ = dvlist.NewDVList(plugin.SourceList{}, dvlist.WithDoubleClickHandler(r.change))
+	buttonContainer := fyne.NewContainerWithLayout(layout.NewVBoxLayout(),
+		widget.NewSeparator(),
+		fyne.NewContainerWithLayout(layout.NewGridLayout(2),
+			widget.NewButton("换源", r.onChange),
+			widget.NewButton("取消", r.Hide),
+		),
+	)
+	r.SetContent(fyne.NewContainerWithLayout(
+		layout.NewBorderLayout(r.status, buttonContainer, nil, nil),
+		r.status, buttonContainer, r.lv,
+	))
+	r.Canvas().SetOnTypedKey(r.lv.TypedKey)
+	r.Resize(defaultDialogSize)
+	r.SetCloseIntercept(r.Hide)
+	return r
+}
+
+// Find searches other sources of sub and shows them
+func (cwin *ChangeSourceWin) Find(sub *plugin.Subscription) {
+	cwin.sub = sub
+	cwin.sources = plugin.SourceList{}
+	cwin.lv.SetData(cwin.sources)
+	cwin.status.SetText(fmt.Sprintf("正在搜索 %v 的其他来源...", sub.BookName()))
+	cwin.Show()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), findSourceTimeout)
+		defer cancel()
+		sources := plugin.SourceList(sub.FindSources(ctx))
+		if cwin.sub != sub {
+			// another subscription is being searched
+			return
+		}
+		cwin.sources = sources
+		cwin.lv.SetData(cwin.sources)
+		if len(sources) == 0 {
+			cwin.status.SetText(fmt.Sprintf("没有找到 %v 的其他来源", sub.BookName()))
+			return
+		}
+		cwin.status.SetText(fmt.Sprintf("找到 %d 个来源，已下载的章节会保留，之后从新来源继续更新", len(sources)))
+	}()
+}
+
+func (cwin *ChangeSourceWin) onChange() {
+	cwin.change(cwin.lv.FirstSelected())
+}
+
+func (cwin *ChangeSourceWin) change(i int) {
+	if i < 0 || i >= len(cwin.sources) {
+		return
+	}
+	sr := cwin.sources[i]
+	sub := cwin.sub
+	cwin.status.SetText(fmt.Sprintf("正在匹配 %v 的章节...", sr.PluginName))
+	go func() {
+		err := sub.ChangeSource(plugin.Source{PluginName: sr.PluginName, BookURL: sr.BookPageURL})
+		if errors.Is(err, plugin.ErrUpdating) {
+			cwin.status.SetText("还在下载中...")
+			return
+		}
+		if err != nil {
+			cwin.status.SetText("换源失败：" + err.Error())
+			return
+		}
+		cwin.Hide()
+		if cwin.onChanged != nil {
+			cwin.onChanged(sub)
+		}
+	}()
+}
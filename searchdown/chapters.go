@@ -0,0 +1,134 @@
+package searchdown
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hujun-open/golitebook/plugin"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/hujun-open/dvlist"
+)
+
+// ChapterPreviewWin shows the chapter index of a search result before downloading,
+// and downloads the whole book or the selected range of chapters
+type ChapterPreviewWin struct {
+	fyne.Window
+	lv       *dvlist.DVList
+	status   *widget.Label
+	sr       *plugin.SearchResult
+	chapters plugin.ChapterInfoList
+	cancel   context.CancelFunc
+	// rangeHandler downloads chapters with source id in [from, to) of sr
+	rangeHandler func(sr *plugin.SearchResult, from, to int)
+}
+
+func NewChapterPreviewWin(h func(sr *plugin.SearchResult, from, to int)) *ChapterPreviewWin {
+	r := new(ChapterPreviewWin)
+	r.Window = fyne.CurrentApp().NewWindow("章节目录")
+	r.rangeHandler = h
+	r.status = widget.NewLabel("")
+	r.lv, _ = dvlist.NewDVList(plugin.ChapterInfoList{}, dvlist.WithMultiSelections())
+	buttonContainer := fyne.NewContainerWithLayout(layout.NewVBoxLayout(),
+		widget.NewSeparator(),
+		fyne.NewContainerWithLayout(layout.NewGridLayout(3),
+			widget.NewButton("下载所选范围", r.onDownloadRange),
+			widget.NewButton("下载全部", r.onDownloadAll),
+			widget.NewButton("关闭", r.onClose),
+		),
+	)
+	r.SetContent(fyne.NewContainerWithLayout(
+		layout.NewBorderLayout(r.status, buttonContainer, nil, nil),
+		r.status, buttonContainer, r.lv,
+	))
+	r.Canvas().SetOnTypedKey(r.lv.TypedKey)
+	r.Resize(fyne.NewSize(600, 800))
+	r.SetCloseIntercept(r.onClose)
+	return r
+}
+
+// Preview lists chapters of sr
+func (pwin *ChapterPreviewWin) Preview(sr *plugin.SearchResult) {
+	pwin.stop()
+	p, ok := plugin.LoadedPlugins[sr.PluginName]
+	if !ok {
+		dialog.ShowError(fmt.Errorf("plugin %v is not loaded", sr.PluginName), pwin)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	pwin.cancel = cancel
+	pwin.sr = sr
+	pwin.chapters = plugin.ChapterInfoList{}
+	pwin.lv.SetData(pwin.chapters)
+	pwin.SetTitle("章节目录 - " + sr.BookName)
+	pwin.status.SetText(fmt.Sprintf("正在获取 %v 的章节目录...", sr.PluginName))
+	pwin.Show()
+	go func() {
+		list, err := p.ListChapters(ctx, sr.BookPageURL)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			pwin.status.SetText("获取章节目录失败：" + err.Error())
+			return
+		}
+		pwin.chapters = list
+		pwin.lv.SetData(pwin.chapters)
+		pwin.status.SetText(fmt.Sprintf("共 %d 章，选中起止章节后点击\"下载所选范围\"", len(list)))
+	}()
+}
+
+func (pwin *ChapterPreviewWin) stop() {
+	if pwin.cancel != nil {
+		pwin.cancel()
+		pwin.cancel = nil
+	}
+}
+
+func (pwin *ChapterPreviewWin) onClose() {
+	pwin.stop()
+	pwin.Hide()
+}
+
+// selectedRange returns the source ids of the first and after the last selected chapters
+func (pwin *ChapterPreviewWin) selectedRange() (from, to int, ok bool) {
+	first, last := -1, -1
+	for i, selected := range pwin.lv.CurrentSelections() {
+		if selected && i < len(pwin.chapters) {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return 0, 0, false
+	}
+	return pwin.chapters[first].ID, pwin.chapters[last].ID + 1, true
+}
+
+func (pwin *ChapterPreviewWin) onDownloadRange() {
+	from, to, ok := pwin.selectedRange()
+	if !ok {
+		return
+	}
+	pwin.download(from, to)
+}
+
+func (pwin *ChapterPreviewWin) onDownloadAll() {
+	if len(pwin.chapters) == 0 {
+		return
+	}
+	pwin.download(0, pwin.chapters[len(pwin.chapters)-1].ID+1)
+}
+
+func (pwin *ChapterPreviewWin) download(from, to int) {
+	if pwin.rangeHandler == nil || pwin.sr == nil {
+		return
+	}
+	pwin.onClose()
+	pwin.rangeHandler(pwin.sr, from, to)
+}
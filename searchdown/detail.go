@@ -0,0 +1,63 @@
+package searchdown
+
+import (
+	"strings"
+
+	"github.com/hujun-open/golitebook/plugin"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+var coverSize = fyne.NewSize(120, 160)
+
+// bookDetail shows the cover, tags, word count and synopsis of a book
+type bookDetail struct {
+	cover *canvas.Image
+	info  *widget.Label
+	// box is the container to put into a window
+	box fyne.CanvasObject
+}
+
+func newBookDetail() *bookDetail {
+	r := new(bookDetail)
+	r.cover = canvas.NewImageFromResource(nil)
+	r.cover.FillMode = canvas.ImageFillContain
+	r.cover.SetMinSize(coverSize)
+	r.info = widget.NewLabel("")
+	r.info.Wrapping = fyne.TextWrapWord
+	r.box = container.NewBorder(container.NewCenter(r.cover), nil, nil, nil, container.NewVScroll(r.info))
+	return r
+}
+
+// setText shows txt instead of metadata, like the loading status
+func (bd *bookDetail) setText(txt string) {
+	bd.cover.Resource = nil
+	bd.cover.Refresh()
+	bd.info.SetText(txt)
+}
+
+// set shows m of the book name
+func (bd *bookDetail) set(name string, m *plugin.BookMeta) {
+	bd.cover.Resource = nil
+	if len(m.Cover) > 0 {
+		bd.cover.Resource = fyne.NewStaticResource(name+"-cover", m.Cover)
+	}
+	bd.cover.Refresh()
+	lines := []string{name}
+	if tags := m.TagStr(); tags != "" {
+		lines = append(lines, "标签："+tags)
+	}
+	if words := m.WordCountStr(); words != "" {
+		lines = append(lines, "字数："+words)
+	}
+	if m.Synopsis != "" {
+		lines = append(lines, "", m.Synopsis)
+	}
+	if m.Empty() {
+		lines = append(lines, "没有详细信息")
+	}
+	bd.info.SetText(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,60 @@
+package searchdown
+
+import (
+	"github.com/hujun-open/golitebook/plugin"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/hujun-open/dvlist"
+)
+
+// PluginStatusWin shows the state of all loaded plugins, it is refreshed when any state changes
+type PluginStatusWin struct {
+	fyne.Window
+	lv   *dvlist.DVList
+	data plugin.PluginStatusList
+}
+
+func NewPluginStatusWin() *PluginStatusWin {
+	r := new(PluginStatusWin)
+	r.Window = fyne.CurrentApp().NewWindow("插件状态")
+	r.data = plugin.StatusList()
+	r.lv, _ = dvlist.NewDVList(r.data)
+	buttonContainer := fyne.NewContainerWithLayout(layout.NewVBoxLayout(),
+		widget.NewSeparator(),
+		fyne.NewContainerWithLayout(layout.NewGridLayout(3),
+			widget.NewButton("重启", r.onRestart),
+			widget.NewButton("刷新", r.refresh),
+			widget.NewButton("关闭", r.Hide),
+		),
+	)
+	r.SetContent(fyne.NewContainerWithLayout(
+		layout.NewBorderLayout(nil, buttonContainer, nil, nil),
+		buttonContainer, r.lv,
+	))
+	r.Canvas().SetOnTypedKey(r.lv.TypedKey)
+	r.Resize(defaultDialogSize)
+	r.SetCloseIntercept(r.Hide)
+	plugin.SetStatusHandler(r.onStatusChange)
+	return r
+}
+
+func (pwin *PluginStatusWin) onStatusChange(*plugin.Plugin) {
+	pwin.refresh()
+}
+
+func (pwin *PluginStatusWin) refresh() {
+	pwin.data = plugin.StatusList()
+	pwin.lv.SetData(pwin.data)
+}
+
+func (pwin *PluginStatusWin) onRestart() {
+	i := pwin.lv.FirstSelected()
+	if i < 0 || i >= len(pwin.data) {
+		return
+	}
+	if p, ok := plugin.LoadedPlugins[pwin.data[i].Name]; ok {
+		p.Restart()
+	}
+}
@@ -2,17 +2,21 @@
 package searchdown
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
+	"github.com/hujun-open/golitebook/api"
+	"github.com/hujun-open/golitebook/conf"
 	"github.com/hujun-open/golitebook/plugin"
 
 	// "log"
 	"strings"
 
-	// "time"
+	"time"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
@@ -41,7 +45,9 @@ func (sd *searchInputDiag) onSelectChange(s string) {
 	}
 	desc, err := plugin.LoadedPlugins[s].GetDesc()
 	if err == nil {
-		sd.pluginDesc.SetText(desc)
+		m := plugin.LoadedPlugins[s].Manifest()
+		sd.pluginDesc.SetText(fmt.Sprintf("%v\n版本：%v  API版本：%d\n网站：%v\n功能：%v",
+			desc, m.Version, m.APIVersion, strings.Join(m.Domains, ","), m.CapabilityStr()))
 		sd.selectedPlugin = plugin.LoadedPlugins[s].Name
 
 	} else {
@@ -58,7 +64,8 @@ func newSearchInputDiag(parent fyne.Window, closehandler func(bool)) *searchInpu
 	r.kwEntry = widget.NewEntry()
 	r.kwEntry.OnChanged = r.onKWChange
 	r.pluginDesc = widget.NewMultiLineEntry()
-	nameList := append([]string{allPluginLabelTxt}, plugin.LoadedPlugins.NameList()...)
+	// plugins without search capability are not listed
+	nameList := append([]string{allPluginLabelTxt}, plugin.LoadedPlugins.NameListWith(api.Capability_CapSearch)...)
 	r.pluginNameList = widget.NewSelect(nameList, r.onSelectChange)
 	r.pluginNameList.SetSelectedIndex(0)
 	r.form.Append("关键词：", r.kwEntry)
@@ -73,49 +80,317 @@ type searchResultDiag struct {
 	win                                                fyne.Window
 	data                                               plugin.SearchResultList
 	lv                                                 *dvlist.DVList
-	cancelButton, downloadButton                       *widget.Button
+	cancelButton, downloadButton, stopButton           *widget.Button
+	fallbackButton                                     *widget.Button
 	overallContainer, innerBContainer, buttonContainer *fyne.Container
 	sep                                                *widget.Separator
-	downloadHandler                                    func(pluginName, name, bookurl string)
-	selected                                           int
-	mux                                                *sync.RWMutex
+	downloadHandler                                    func(*plugin.SearchResult, []plugin.Source)
+	// previewWin is created when first used, rangeHandler downloads the range selected in it
+	previewWin   *ChapterPreviewWin
+	rangeHandler func(sr *plugin.SearchResult, from, to int)
+	selected     int
+	mux          *sync.RWMutex
+	// merged is data grouped by book, shown is the books in merged match the filter
+	// in the order of the header sort, shown in lv
+	merged plugin.MergedResultList
+	shown  *plugin.MergedResultView
+	filter *widget.Entry
+	// srcLV shows sources, a copy of the sources of the selected book
+	srcLV   *dvlist.DVList
+	sources plugin.SourceList
+	// detail shows metadata of the selected book, metas key is plugin name + book url
+	detail *bookDetail
+	metas  map[string]*plugin.BookMeta
+	// status shows the search status of every plugin
+	status *widget.Label
+	// pluginNames is the plugins being searched, pluginStatus key is plugin name
+	pluginNames  []string
+	pluginStatus map[string]string
+	// cancel stops the running search
+	cancel context.CancelFunc
 }
 
 func (srd *searchResultDiag) setData(d plugin.SearchResultList) {
 	srd.mux.Lock()
 	defer srd.mux.Unlock()
 	srd.data = d
-	srd.lv.SetData(d)
+	srd.merged = plugin.MergeResults(d)
+	srd.showMerged()
+	srd.setSources(nil)
+}
+
+// showMerged shows srd.merged with the current filter and sort, caller must hold srd.mux
+func (srd *searchResultDiag) showMerged() {
+	srd.shown.SetList(srd.merged)
+	srd.lv.SetData(srd.shown)
+}
+
+func (srd *searchResultDiag) onFilterChanged(kw string) {
+	srd.mux.Lock()
+	defer srd.mux.Unlock()
+	srd.shown.Filter(kw, -1)
+	srd.lv.SetData(srd.shown)
+	srd.setSources(nil)
+}
+
+// setSources shows a copy of sources of mr in srcLV, caller must hold srd.mux
+func (srd *searchResultDiag) setSources(mr *plugin.MergedResult) {
+	srd.sources = plugin.SourceList{}
+	if mr != nil {
+		srd.sources = append(srd.sources, mr.Sources...)
+	}
+	srd.srcLV.SetData(srd.sources)
+}
+
+// onSelected shows sources of the selected book, and gets their chapter counts
+func (srd *searchResultDiag) onSelected(i int, selected bool) {
+	if !selected {
+		return
+	}
+	srd.mux.Lock()
+	mr := srd.shown.At(i)
+	if mr == nil {
+		srd.mux.Unlock()
+		return
+	}
+	srd.setSources(mr)
+	srd.mux.Unlock()
+	go srd.showMeta(mr.Best())
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		counted := mr.WithChapterCounts(mr.FetchChapterCounts(ctx))
+		srd.mux.Lock()
+		defer srd.mux.Unlock()
+		srd.replaceMerged(mr, counted)
+		if srd.shown.At(srd.lv.FirstSelected()) == counted {
+			srd.setSources(counted)
+		}
+		srd.lv.Refresh()
+	}()
+}
+
+// replaceMerged replaces book old with mr, and the sources of old in srd.data with the ones of mr,
+// so that they are kept when results are merged again; caller must hold srd.mux
+func (srd *searchResultDiag) replaceMerged(old, mr *plugin.MergedResult) {
+	sources := make(map[string]*plugin.SearchResult)
+	for _, sr := range mr.Sources {
+		sources[sr.PluginName+"\x00"+sr.BookPageURL] = sr
+	}
+	for i, sr := range srd.data {
+		if src, ok := sources[sr.PluginName+"\x00"+sr.BookPageURL]; ok {
+			srd.data[i] = src
+		}
+	}
+	for i := range srd.merged {
+		if srd.merged[i] == old {
+			srd.merged[i] = mr
+		}
+	}
+	srd.shown.Replace(old, mr)
+}
+
+// showMeta shows metadata of sr in the detail pane, metadata is got once for each source
+func (srd *searchResultDiag) showMeta(sr *plugin.SearchResult) {
+	key := sr.PluginName + "\x00" + sr.BookPageURL
+	srd.mux.RLock()
+	m, ok := srd.metas[key]
+	srd.mux.RUnlock()
+	if !ok {
+		p, loaded := plugin.LoadedPlugins[sr.PluginName]
+		if !loaded {
+			return
+		}
+		srd.detail.setText("加载中...")
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		var err error
+		if m, err = p.GetBookMeta(ctx, sr.BookPageURL); err != nil {
+			srd.detail.setText("获取详细信息失败：" + err.Error())
+			return
+		}
+		srd.mux.Lock()
+		srd.metas[key] = m
+		srd.mux.Unlock()
+	}
+	if primary, _ := srd.selectedSources(); primary != nil && primary.BookName == sr.BookName {
+		srd.detail.set(sr.BookName, m)
+	}
+}
 
+// search searches kw via plugins concurrently, results are added to the list as each plugin answers
+func (srd *searchResultDiag) search(plugins []*plugin.Plugin, kw string) {
+	srd.stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	srd.mux.Lock()
+	srd.cancel = cancel
+	srd.pluginNames = []string{}
+	srd.pluginStatus = make(map[string]string)
+	for _, p := range plugins {
+		if p.Supports(api.Capability_CapSearch) {
+			srd.pluginNames = append(srd.pluginNames, p.Name)
+			srd.pluginStatus[p.Name] = "搜索中"
+		}
+	}
+	srd.mux.Unlock()
+	srd.setData(plugin.SearchResultList{})
+	srd.refreshStatus()
+	srd.stopButton.Enable()
+	srd.win.Show()
+	go func() {
+		plugin.SearchAll(ctx, plugins, kw, func(p *plugin.Plugin, results plugin.SearchResultList, err error) {
+			srd.onPluginResult(ctx, p, results, err)
+		})
+		srd.mux.Lock()
+		if ctx.Err() == nil {
+			srd.cancel = nil
+		}
+		srd.mux.Unlock()
+		if ctx.Err() == nil {
+			srd.stopButton.Disable()
+		}
+	}()
+}
+
+func (srd *searchResultDiag) onPluginResult(ctx context.Context, p *plugin.Plugin, results plugin.SearchResultList, err error) {
+	srd.mux.Lock()
+	if ctx.Err() != nil {
+		// result of a stopped search
+		srd.mux.Unlock()
+		return
+	}
+	if err != nil {
+		srd.pluginStatus[p.Name] = "失败(" + err.Error() + ")"
+	} else {
+		srd.pluginStatus[p.Name] = fmt.Sprintf("%d条", len(results))
+		srd.data = append(srd.data, results...)
+		srd.merged = plugin.MergeResults(srd.data)
+		srd.showMerged()
+	}
+	srd.mux.Unlock()
+	srd.refreshStatus()
+}
+
+func (srd *searchResultDiag) refreshStatus() {
+	srd.mux.RLock()
+	list := []string{}
+	for _, name := range srd.pluginNames {
+		list = append(list, name+"："+srd.pluginStatus[name])
+	}
+	srd.mux.RUnlock()
+	srd.status.SetText(strings.Join(list, "  "))
 }
+
+// stop cancels the running search, results already received are kept
+func (srd *searchResultDiag) stop() {
+	srd.mux.Lock()
+	cancel := srd.cancel
+	srd.cancel = nil
+	for name, st := range srd.pluginStatus {
+		if st == "搜索中" {
+			srd.pluginStatus[name] = "已停止"
+		}
+	}
+	srd.mux.Unlock()
+	if cancel != nil {
+		cancel()
+		srd.refreshStatus()
+	}
+	srd.stopButton.Disable()
+}
+
 func (srd *searchResultDiag) onClose() {
+	srd.stop()
 	srd.win.Hide()
 }
 
-func (srd *searchResultDiag) onOK() {
+// selectedSources returns the source selected in srcLV or the best source of the selected book,
+// followed by other sources of the book
+func (srd *searchResultDiag) selectedSources() (*plugin.SearchResult, []plugin.Source) {
 	srd.selected = srd.lv.FirstSelected()
-	if srd.selected == -1 {
-		return
-	}
 	srd.mux.RLock()
 	defer srd.mux.RUnlock()
-	srd.downloadHandler(
-		srd.data[srd.selected].PluginName,
-		srd.data[srd.selected].BookName,
-		srd.data[srd.selected].BookPageURL)
+	mr := srd.shown.At(srd.selected)
+	if mr == nil {
+		return nil, nil
+	}
+	primary := mr.Best()
+	if i := srd.srcLV.FirstSelected(); i >= 0 && i < len(srd.sources) {
+		primary = srd.sources[i]
+	}
+	others := []plugin.Source{}
+	for _, sr := range mr.Sources {
+		if sr != primary {
+			others = append(others, plugin.Source{PluginName: sr.PluginName, BookURL: sr.BookPageURL})
+		}
+	}
+	return primary, others
 }
 
-func newSearchResultDiag(data plugin.SearchResultList, dh func(string, string, string)) *searchResultDiag {
+func (srd *searchResultDiag) onOK() {
+	primary, _ := srd.selectedSources()
+	if primary == nil {
+		return
+	}
+	srd.downloadHandler(primary, nil)
+}
+
+// onDownloadWithFallback downloads with other sources of the book as fallback sources
+func (srd *searchResultDiag) onDownloadWithFallback() {
+	primary, others := srd.selectedSources()
+	if primary == nil {
+		return
+	}
+	srd.downloadHandler(primary, others)
+}
+
+// onPreview shows chapters of the selected source
+func (srd *searchResultDiag) onPreview() {
+	primary, _ := srd.selectedSources()
+	if primary == nil {
+		return
+	}
+	if p, ok := plugin.LoadedPlugins[primary.PluginName]; !ok || !p.Supports(api.Capability_CapListChapters) {
+		dialog.ShowError(fmt.Errorf("插件 %v 不支持章节目录", primary.PluginName), srd.win)
+		return
+	}
+	if srd.previewWin == nil {
+		srd.previewWin = NewChapterPreviewWin(srd.onDownloadRange)
+	}
+	srd.previewWin.Preview(primary)
+}
+
+func (srd *searchResultDiag) onDownloadRange(sr *plugin.SearchResult, from, to int) {
+	srd.rangeHandler(sr, from, to)
+}
+
+func newSearchResultDiag(data plugin.SearchResultList, dh func(*plugin.SearchResult, []plugin.Source), rh func(*plugin.SearchResult, int, int)) *searchResultDiag {
 	r := new(searchResultDiag)
 	r.data = data
+	r.merged = plugin.MergeResults(data)
+	r.shown = plugin.NewMergedResultView()
+	r.shown.SetList(r.merged)
 	r.mux = new(sync.RWMutex)
-	r.lv, _ = dvlist.NewDVList(data)
+	r.lv, _ = dvlist.NewDVList(r.shown, dvlist.WithSelectionHandler(r.onSelected))
+	r.sources = plugin.SourceList{}
+	r.srcLV, _ = dvlist.NewDVList(r.sources)
+	r.detail = newBookDetail()
+	r.metas = make(map[string]*plugin.BookMeta)
+	r.filter = widget.NewEntry()
+	r.filter.SetPlaceHolder("过滤")
+	r.filter.OnChanged = r.onFilterChanged
 	r.win = fyne.CurrentApp().NewWindow("搜索结果")
 	r.downloadButton = widget.NewButton("下载", r.onOK)
+	r.fallbackButton = widget.NewButton("下载(其他来源备用)", r.onDownloadWithFallback)
+	previewButton := widget.NewButton("预览章节", r.onPreview)
+	r.stopButton = widget.NewButton("停止", r.stop)
+	r.stopButton.Disable()
 	r.cancelButton = widget.NewButton("取消", r.onClose)
+	r.status = widget.NewLabel("")
+	r.status.Wrapping = fyne.TextWrapWord
 	r.innerBContainer = fyne.NewContainerWithLayout(
-		layout.NewGridLayout(2),
-		r.downloadButton, r.cancelButton)
+		layout.NewGridLayout(5),
+		r.downloadButton, r.fallbackButton, previewButton, r.stopButton, r.cancelButton)
 	r.sep = widget.NewSeparator()
 	r.buttonContainer = fyne.NewContainerWithLayout(
 		layout.NewVBoxLayout(),
@@ -123,12 +398,18 @@ func newSearchResultDiag(data plugin.SearchResultList, dh func(string, string, s
 		r.innerBContainer,
 	)
 
+	vsplit := container.NewVSplit(r.lv, r.srcLV)
+	vsplit.Offset = 0.7
+	split := container.NewHSplit(vsplit, r.detail.box)
+	split.Offset = 0.75
+	top := container.NewVBox(r.status, r.filter)
 	r.overallContainer = fyne.NewContainerWithLayout(
-		layout.NewBorderLayout(nil, r.buttonContainer, nil, nil),
-		r.buttonContainer, r.lv)
+		layout.NewBorderLayout(top, r.buttonContainer, nil, nil),
+		top, r.buttonContainer, split)
 	r.win.SetContent(r.overallContainer)
-	r.win.Resize(defaultDialogSize)
+	r.win.Resize(fyne.NewSize(defaultDialogSize.Width, 500))
 	r.downloadHandler = dh
+	r.rangeHandler = rh
 	r.win.SetCloseIntercept(r.onClose)
 	r.win.Canvas().SetOnTypedKey(r.lv.TypedKey)
 	return r
@@ -139,12 +420,17 @@ type Downloader struct {
 	searchDiag *searchInputDiag
 	resultDiag *searchResultDiag
 	subsDiag   *SubscriptionWin
+	pluginWin  *PluginStatusWin
+	autoWin    *AutoUpdateWin
+	scheduler  *plugin.Scheduler
 	// subList           plugin.SubscriptionList
 	lvLoadFileHandler func(string)
+	// lvReleaseFileHandler is called before a file being read is replaced
+	lvReleaseFileHandler func(string)
 }
 
-func NewDownloader(win fyne.Window, h func(string)) *Downloader {
-	r := &Downloader{parent: win, lvLoadFileHandler: h}
+func NewDownloader(win fyne.Window, h func(string), release func(string)) *Downloader {
+	r := &Downloader{parent: win, lvLoadFileHandler: h, lvReleaseFileHandler: release}
 	r.initSubs()
 	return r
 }
@@ -167,6 +453,68 @@ func (down *Downloader) ShowSubsWin() {
 	down.subsDiag.Show()
 }
 
+// StartAutoUpdate starts updating all subscriptions in background according to cfg
+func (down *Downloader) StartAutoUpdate(cfg conf.AutoUpdateConf) {
+	down.scheduler = plugin.NewScheduler(cfg, down.onAutoUpdate)
+	down.scheduler.SetProgressHandler(down.onUpdateProgress)
+	down.scheduler.Start()
+}
+
+// StopAutoUpdate stops the background update
+func (down *Downloader) StopAutoUpdate() {
+	if down.scheduler != nil {
+		down.scheduler.Stop()
+	}
+}
+
+func (down *Downloader) onUpdateProgress(done, failed, total int) {
+	if down.subsDiag != nil {
+		down.subsDiag.onUpdateProgress(done, failed, total)
+	}
+}
+
+// onAutoUpdate refreshes the summary and notifies user if any book got new chapters or failed to update;
+// the summary window is only shown when user asks for it
+func (down *Downloader) onAutoUpdate(results plugin.UpdateResultList) {
+	if down.subsDiag != nil {
+		down.subsDiag.lv.SetData(plugin.CurrentSubscriptions)
+	}
+	if down.autoWin != nil {
+		down.autoWin.refresh()
+	}
+	msg := updatedBookNames(results)
+	if n := len(results.Failed()); n > 0 {
+		if msg != "" {
+			msg += "\n"
+		}
+		msg += fmt.Sprintf("%d 本更新失败", n)
+	}
+	if msg != "" {
+		fyne.CurrentApp().SendNotification(fyne.NewNotification("订阅更新", msg))
+	}
+}
+
+func (down *Downloader) ShowAutoUpdateWin() {
+	if down.scheduler == nil {
+		return
+	}
+	if down.autoWin == nil {
+		down.autoWin = NewAutoUpdateWin(down.scheduler)
+	} else {
+		down.autoWin.refresh()
+	}
+	down.autoWin.Show()
+}
+
+func (down *Downloader) ShowPluginStatusWin() {
+	if down.pluginWin == nil {
+		down.pluginWin = NewPluginStatusWin()
+	} else {
+		down.pluginWin.refresh()
+	}
+	down.pluginWin.Show()
+}
+
 func (down *Downloader) onCloseSearchInputDiag(confirm bool) {
 	if !confirm {
 		return
@@ -175,37 +523,19 @@ func (down *Downloader) onCloseSearchInputDiag(confirm bool) {
 		dialog.ShowError(fmt.Errorf("selected plugin or kw is empty, %v, %v", down.searchDiag.selectedPlugin, down.searchDiag.keyword), down.parent)
 		return
 	}
-	//do the search
-	var rlist plugin.SearchResultList
-	var err error
-	if down.searchDiag.selectedPlugin == allPluginLabelTxt {
-		diag := dialog.NewProgress("搜索", "搜索中...", down.parent)
-		diag.Show()
-		i := 0
-		for _, p := range plugin.LoadedPlugins {
-			results, err := p.SearchBook(down.searchDiag.keyword)
-			if err != nil {
-				dialog.ShowError(err, down.parent)
-			} else {
-				rlist = append(rlist, results...)
-			}
-			diag.SetValue(float64(i) / float64(len(plugin.LoadedPlugins)))
-			i++
-		}
-		diag.Hide()
-
-	} else {
-		rlist, err = plugin.LoadedPlugins[down.searchDiag.selectedPlugin].SearchBook(down.searchDiag.keyword)
-		if err != nil {
-			dialog.ShowError(err, down.parent)
+	plugins := plugin.LoadedPlugins.Plugins()
+	if down.searchDiag.selectedPlugin != allPluginLabelTxt {
+		p, ok := plugin.LoadedPlugins[down.searchDiag.selectedPlugin]
+		if !ok {
+			dialog.ShowError(fmt.Errorf("plugin %v is not loaded", down.searchDiag.selectedPlugin), down.parent)
 			return
 		}
+		plugins = []*plugin.Plugin{p}
 	}
 	if down.resultDiag == nil {
-		down.resultDiag = newSearchResultDiag(rlist, down.download)
-	} else {
-		down.resultDiag.setData(rlist)
+		down.resultDiag = newSearchResultDiag(plugin.SearchResultList{}, down.download, down.downloadRange)
 	}
+	down.resultDiag.search(plugins, down.searchDiag.keyword)
 	down.resultDiag.win.Show()
 }
 
@@ -217,11 +547,12 @@ func (down *Downloader) initSubs() {
 	}
 }
 
-func (down *Downloader) download(pluginName, name, url string) {
+func (down *Downloader) download(sr *plugin.SearchResult, fallbacks []plugin.Source) {
 	if down.resultDiag != nil {
 		down.resultDiag.onClose()
 	}
-	sub := plugin.NewSubscription(name, url, pluginName, down.onDownloadProgress)
+	sub := plugin.NewSubscription(sr.BookName, sr.AuthorName, sr.BookPageURL, sr.PluginName, down.onDownloadProgress)
+	sub.SetFallbacks(fallbacks)
 	plugin.CurrentSubscriptions.Append(sub)
 	if down.subsDiag == nil {
 		down.subsDiag = NewSubscriptionWin(plugin.CurrentSubscriptions, down)
@@ -232,24 +563,40 @@ func (down *Downloader) download(pluginName, name, url string) {
 	sub.Update()
 }
 
-func (down *Downloader) onDownloadProgress(bookurl string, done, total int) {
-	if total == 0 {
-		dialog.ShowError(fmt.Errorf("没有更新的章节"), down.subsDiag)
-		return
+// downloadRange subscribes sr and downloads chapters with source id in [from, to),
+// later updates continue after to
+func (down *Downloader) downloadRange(sr *plugin.SearchResult, from, to int) {
+	if down.resultDiag != nil {
+		down.resultDiag.onClose()
+	}
+	sub := plugin.NewSubscription(sr.BookName, sr.AuthorName, sr.BookPageURL, sr.PluginName, down.onDownloadProgress)
+	plugin.CurrentSubscriptions.Append(sub)
+	if down.subsDiag == nil {
+		down.subsDiag = NewSubscriptionWin(plugin.CurrentSubscriptions, down)
+	} else {
+		down.subsDiag.lv.SetData(plugin.CurrentSubscriptions)
 	}
+	down.subsDiag.Show()
+	go sub.DownloadRange(from, to)
+}
+
+func (down *Downloader) onDownloadProgress(bookurl string, done, total int) {
 	found := false
-	for i := 0; i < plugin.CurrentSubscriptions.Len(); i++ {
-		if plugin.CurrentSubscriptions.Item(i)[2] == bookurl {
+	for _, sub := range plugin.CurrentSubscriptions.Get() {
+		if sub.BookURL() == bookurl {
 			newstatus := fmt.Sprintf("下载中 %d/%d", done, total)
-			if done == total {
+			if total == 0 {
+				// a background update shouldn't pop up dialog
+				newstatus = "没有更新的章节"
+			} else if done == total {
 				newstatus = fmt.Sprintf("下载完毕 %d/%d", done, total)
 			}
-			plugin.CurrentSubscriptions.Get()[i].SetStatusTxt(newstatus)
+			sub.SetStatusTxt(newstatus)
 			found = true
 			break
 		}
 	}
-	if found {
+	if found && down.subsDiag != nil {
 		down.subsDiag.lv.SetData(plugin.CurrentSubscriptions)
 	}
 }
@@ -260,4 +607,10 @@ func (down *Downloader) CloseAllWindow() {
 	if down.subsDiag != nil {
 		down.subsDiag.Close()
 	}
+	if down.pluginWin != nil {
+		down.pluginWin.Close()
+	}
+	if down.autoWin != nil {
+		down.autoWin.Close()
+	}
 }
@@ -2,43 +2,75 @@
 package searchdown
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hujun-open/golitebook/plugin"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/widget"
 	"github.com/hujun-open/dvlist"
 )
 
 type SubscriptionWin struct {
 	fyne.Window
-	lv          *dvlist.DVList
-	subs        *plugin.SubscriptionList
-	downloader  *Downloader
-	loadingDiag *dialog.ProgressInfiniteDialog
+	lv           *dvlist.DVList
+	updateButton *widget.Button
+	subs         *plugin.SubscriptionList
+	downloader   *Downloader
+	loadingDiag  *dialog.ProgressInfiniteDialog
+	// progress shows the progress of updating multiple subscriptions
+	progress *widget.Label
+	// changeWin is created when first used
+	changeWin *ChangeSourceWin
+	// detail shows metadata of selected subscription
+	detail *bookDetail
 }
 
 func NewSubscriptionWin(subs *plugin.SubscriptionList, d *Downloader) *SubscriptionWin {
 	r := new(SubscriptionWin)
 	r.Window = fyne.CurrentApp().NewWindow("订阅列表")
-	r.lv, _ = dvlist.NewDVList(subs, dvlist.WithDoubleClickHandler(r.onDoubleClicked))
+	r.lv, _ = dvlist.NewDVList(subs,
+		dvlist.WithDoubleClickHandler(r.onDoubleClicked),
+		dvlist.WithSelectionHandler(r.onSelected),
+		dvlist.WithMultiSelections(),
+	)
 	r.loadingDiag = dialog.NewProgressInfinite("loading", "加载中...", r)
 	r.loadingDiag.Hide()
+	r.updateButton = widget.NewButton("更新", r.onUpdate)
+	r.progress = widget.NewLabel("")
+	r.detail = newBookDetail()
+	filter := widget.NewEntry()
+	filter.SetPlaceHolder("过滤")
+	filter.OnChanged = r.onFilterChanged
 	buttonContainer := fyne.NewContainerWithLayout(layout.NewVBoxLayout(),
 		widget.NewSeparator(),
-		fyne.NewContainerWithLayout(layout.NewGridLayout(4),
-			widget.NewButton("更新", r.onUpdate),
+		r.progress,
+		fyne.NewContainerWithLayout(layout.NewGridLayout(5),
+			r.updateButton,
+			widget.NewButton("更新所选", r.onUpdateSelected),
+			widget.NewButton("全部更新", r.onUpdateAll),
+			widget.NewButton("停止", r.onStop),
+			widget.NewButton("重新下载", r.onRedownload),
+			widget.NewButton("换源", r.onChangeSource),
 			widget.NewButton("阅读", r.onRead),
 			widget.NewButton("删除", r.onDel),
+			widget.NewButton("导出EPUB", r.onExport),
 			widget.NewButton("取消", r.Hide),
 		),
 	)
+	split := container.NewHSplit(r.lv, r.detail.box)
+	split.Offset = 0.75
 	r.SetContent(fyne.NewContainerWithLayout(
-		layout.NewBorderLayout(nil, buttonContainer, nil, nil),
-		buttonContainer, r.lv,
+		layout.NewBorderLayout(filter, buttonContainer, nil, nil),
+		filter, buttonContainer, split,
 	))
 	r.subs = subs
 	r.downloader = d
@@ -51,16 +83,82 @@ func NewSubscriptionWin(subs *plugin.SubscriptionList, d *Downloader) *Subscript
 func (swin *SubscriptionWin) onClose() {
 	swin.Hide()
 }
+func (swin *SubscriptionWin) onFilterChanged(kw string) {
+	swin.subs.Filter(kw, -1)
+	swin.lv.SetData(swin.subs)
+}
+
 func (swin *SubscriptionWin) onDoubleClicked(i int) {
 	swin.read(i)
 }
 
+// onSelected shows metadata of selected subscription, and disables update button if its plugin can't update it
+func (swin *SubscriptionWin) onSelected(i int, selected bool) {
+	if !selected || i < 0 || i >= swin.subs.Len() {
+		return
+	}
+	sub := swin.subs.At(i)
+	m := sub.Meta()
+	swin.detail.set(sub.BookName(), &m)
+	if sub.Updatable() != nil {
+		swin.updateButton.Disable()
+	} else {
+		swin.updateButton.Enable()
+	}
+}
+
+// updateMulti updates subs by the bounded worker pool of the scheduler
+func (swin *SubscriptionWin) updateMulti(subs []*plugin.Subscription) {
+	scheduler := swin.downloader.scheduler
+	if scheduler == nil || len(subs) == 0 {
+		return
+	}
+	go func() {
+		if _, err := scheduler.Run(subs); errors.Is(err, plugin.ErrRunning) {
+			dialog.ShowError(fmt.Errorf("已经在更新中..."), swin)
+		}
+	}()
+}
+
+func (swin *SubscriptionWin) onUpdateAll() {
+	swin.updateMulti(swin.subs.Get())
+}
+
+func (swin *SubscriptionWin) onUpdateSelected() {
+	subs := []*plugin.Subscription{}
+	for i, selected := range swin.lv.CurrentSelections() {
+		if sub := swin.subs.At(i); selected && sub != nil {
+			subs = append(subs, sub)
+		}
+	}
+	swin.updateMulti(subs)
+}
+
+// onUpdateProgress shows the aggregated progress of updating multiple subscriptions
+func (swin *SubscriptionWin) onUpdateProgress(done, failed, total int) {
+	if done == total {
+		swin.progress.SetText(fmt.Sprintf("更新完毕 %d/%d，失败 %d", done, total, failed))
+	} else {
+		swin.progress.SetText(fmt.Sprintf("更新中 %d/%d，失败 %d", done, total, failed))
+	}
+	swin.lv.SetData(swin.subs)
+}
+
+// startTask runs download task f in background, user is told if the subscription is already being updated
+func (swin *SubscriptionWin) startTask(f func() (int, error)) {
+	go func() {
+		if _, err := f(); errors.Is(err, plugin.ErrUpdating) {
+			dialog.ShowError(fmt.Errorf("已经在更新中..."), swin)
+		}
+	}()
+}
+
 func (swin *SubscriptionWin) update(i int) {
-	if plugin.CurrentSubscriptions.Get()[i].Status() == plugin.DownloadResultWorking {
-		dialog.ShowError(fmt.Errorf("已经在更新中..."), swin)
+	if err := swin.subs.At(i).Updatable(); err != nil {
+		dialog.ShowError(err, swin)
 		return
 	}
-	go plugin.CurrentSubscriptions.Get()[i].Update()
+	swin.startTask(swin.subs.At(i).Update)
 }
 
 func (swin *SubscriptionWin) onUpdate() {
@@ -68,17 +166,89 @@ func (swin *SubscriptionWin) onUpdate() {
 	if i < 0 {
 		return
 	}
-	if swin.subs.Get()[i].Status() == plugin.DownloadResultWorking {
-		dialog.ShowError(fmt.Errorf("还在下载中..."), swin)
+	swin.update(i)
+
+}
+
+// onStop cancels the download of selected subscription, downloaded chapters are kept
+func (swin *SubscriptionWin) onStop() {
+	i := swin.lv.FirstSelected()
+	if i < 0 {
 		return
 	}
-	swin.update(i)
+	sub := swin.subs.At(i)
+	if sub.Status() != plugin.DownloadResultWorking {
+		return
+	}
+	sub.Cancel()
+	sub.SetStatusTxt("停止中...")
+	swin.lv.SetData(swin.subs)
+	go func() {
+		// show the final status after the update returns
+		for sub.Status() == plugin.DownloadResultWorking {
+			time.Sleep(100 * time.Millisecond)
+		}
+		swin.lv.SetData(swin.subs)
+	}()
+}
+
+// onRedownload downloads a range of chapters of selected subscription again
+func (swin *SubscriptionWin) onRedownload() {
+	i := swin.lv.FirstSelected()
+	if i < 0 {
+		return
+	}
+	sub := swin.subs.At(i)
+	fromEntry := widget.NewEntry()
+	fromEntry.SetText("1")
+	toEntry := widget.NewEntry()
+	toEntry.SetText(swin.subs.Item(i)[4])
+	items := []*widget.FormItem{
+		widget.NewFormItem("从第", fromEntry),
+		widget.NewFormItem("到第", toEntry),
+	}
+	dialog.ShowForm("重新下载章节", "下载", "取消", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		from, err1 := strconv.Atoi(strings.TrimSpace(fromEntry.Text))
+		to, err2 := strconv.Atoi(strings.TrimSpace(toEntry.Text))
+		if err1 != nil || err2 != nil || from < 1 || to < from {
+			dialog.ShowError(fmt.Errorf("无效的章节范围"), swin)
+			return
+		}
+		// chapter id starts from 0
+		swin.startTask(func() (int, error) { return sub.Redownload(from-1, to) })
+	}, swin)
+}
 
+// onChangeSource searches other sources of selected subscription, and updates it after its source is changed
+func (swin *SubscriptionWin) onChangeSource() {
+	i := swin.lv.FirstSelected()
+	if i < 0 {
+		return
+	}
+	sub := swin.subs.At(i)
+	if swin.changeWin == nil {
+		swin.changeWin = NewChangeSourceWin(swin.onSourceChanged)
+	}
+	swin.changeWin.Find(sub)
+}
+
+func (swin *SubscriptionWin) onSourceChanged(sub *plugin.Subscription) {
+	swin.lv.SetData(swin.subs)
+	swin.startTask(sub.Update)
 }
+
 func (swin *SubscriptionWin) read(i int) {
 	swin.loadingDiag.Show()
 	defer swin.loadingDiag.Hide()
-	filename := plugin.GetLocalSavedFilePath(plugin.CurrentSubscriptions.Item(i)[0])
+	// the book file is assembled from stored chapters
+	filename, err := swin.subs.At(i).Assemble(swin.downloader.lvReleaseFileHandler)
+	if err != nil {
+		dialog.ShowError(err, swin)
+		return
+	}
 	swin.downloader.lvLoadFileHandler(filename)
 	swin.Hide()
 }
@@ -88,7 +258,7 @@ func (swin *SubscriptionWin) onRead() {
 	if i < 0 {
 		return
 	}
-	if swin.subs.Get()[i].Status() == plugin.DownloadResultWorking {
+	if swin.subs.At(i).Status() == plugin.DownloadResultWorking {
 		dialog.ShowError(fmt.Errorf("还在下载中..."), swin)
 		return
 	}
@@ -99,7 +269,7 @@ func (swin *SubscriptionWin) onDel() {
 	if i < 0 {
 		return
 	}
-	if swin.subs.Get()[i].Status() == plugin.DownloadResultWorking {
+	if swin.subs.At(i).Status() == plugin.DownloadResultWorking {
 		dialog.ShowError(fmt.Errorf("还在下载中..."), swin)
 		return
 	}
@@ -123,3 +293,40 @@ func (swin *SubscriptionWin) del(confirm bool) {
 	plugin.CurrentSubscriptions.Remove(i)
 	swin.lv.SetData(plugin.CurrentSubscriptions)
 }
+
+func (swin *SubscriptionWin) onExport() {
+	i := swin.lv.FirstSelected()
+	if i < 0 {
+		return
+	}
+	sub := swin.subs.At(i)
+	if sub.Status() == plugin.DownloadResultWorking {
+		dialog.ShowError(fmt.Errorf("还在下载中..."), swin)
+		return
+	}
+	diag := dialog.NewFileSave(func(w fyne.URIWriteCloser, err error) {
+		swin.export(sub, w, err)
+	}, swin)
+	diag.SetFilter(storage.NewExtensionFileFilter([]string{".epub"}))
+	diag.SetFileName(sub.BookName() + ".epub")
+	diag.Show()
+}
+
+func (swin *SubscriptionWin) export(sub *plugin.Subscription, w fyne.URIWriteCloser, err error) {
+	if err != nil {
+		dialog.ShowError(err, swin)
+		return
+	}
+	if w == nil {
+		return
+	}
+	err = sub.ExportEPUB(w)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("failed to export %v, %v", sub.BookName(), err), swin)
+		return
+	}
+	dialog.ShowInformation("导出EPUB", fmt.Sprintf("已导出到 %v", w.URI().Path()), swin)
+}
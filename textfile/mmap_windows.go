@@ -0,0 +1,27 @@
+//go:build windows
+// +build windows
+
+package textfile
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func mmap(f *os.File, size int) ([]byte, func() error, error) {
+	h, err := syscall.CreateFileMapping(syscall.Handle(f.Fd()), nil, syscall.PAGE_READONLY,
+		uint32(uint64(size)>>32), uint32(size), nil)
+	if err != nil {
+		return nil, nil, os.NewSyscallError("CreateFileMapping", err)
+	}
+	// the view keeps the mapping alive after its handle is closed
+	addr, err := syscall.MapViewOfFile(h, syscall.FILE_MAP_READ, 0, 0, uintptr(size))
+	syscall.CloseHandle(h)
+	if err != nil {
+		return nil, nil, os.NewSyscallError("MapViewOfFile", err)
+	}
+	// addr is not managed by Go, convert it without unsafe.Pointer(uintptr) which vet reports
+	data := unsafe.Slice((*byte)(*(*unsafe.Pointer)(unsafe.Pointer(&addr))), size)
+	return data, func() error { return syscall.UnmapViewOfFile(addr) }, nil
+}
@@ -0,0 +1,154 @@
+// textfile reads lines of a text file on demand, the file is memory-mapped instead of loaded into memory
+package textfile
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/hujun-open/golitebook/char"
+)
+
+// File is a text file, it implements liteview.Lines;
+// lines are split by "\n", a trailing "\r" is removed, and decoded to UTF-8 when they are read
+type File struct {
+	mux  *sync.RWMutex
+	data []byte
+	// offsets[i] is the start of line i, the last one is len(data)+1
+	offsets   []int
+	detection char.Detection
+	// decode is false if data is already in UTF-8
+	decode bool
+	unmap  func() error
+}
+
+// Open memory-maps the file at path and indexes its lines;
+// charset is the charset name of the file, empty string means detecting via det
+func Open(path string, det *char.DetChar, charset string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fi.Size()
+	if int64(int(size)) != size {
+		return nil, fmt.Errorf("%v is too large, %d bytes", path, size)
+	}
+	var data []byte
+	var unmap func() error
+	if size > 0 {
+		data, unmap, err = mmap(f, int(size))
+		if err != nil {
+			return nil, fmt.Errorf("failed to map %v, %w", path, err)
+		}
+	}
+	r, err := newFile(data, unmap, det, charset)
+	if err != nil {
+		if unmap != nil {
+			unmap()
+		}
+		return nil, fmt.Errorf("failed to load %v, %w", path, err)
+	}
+	return r, nil
+}
+
+// New returns a File of buf which is already in memory,
+// charset is the charset name of buf, empty string means detecting via det
+func New(buf []byte, det *char.DetChar, charset string) (*File, error) {
+	return newFile(buf, nil, det, charset)
+}
+
+func newFile(data []byte, unmap func() error, det *char.DetChar, charset string) (*File, error) {
+	r := &File{
+		mux:   new(sync.RWMutex),
+		data:  data,
+		unmap: unmap,
+	}
+	if charset == "" {
+		r.detection = det.Detect(data)
+	} else {
+		var err error
+		if r.detection, err = char.NewDetection(data, charset); err != nil {
+			return nil, err
+		}
+	}
+	r.decode = r.detection.Encoding != nil
+	if !r.detection.LineSafe() {
+		// lines can't be located before decoding, so the text is decoded into memory
+		buf, err := r.detection.Decode(data)
+		if err != nil {
+			return nil, err
+		}
+		if unmap != nil {
+			unmap()
+		}
+		r.data, r.unmap, r.decode = buf, nil, false
+	} else {
+		r.data = data[r.detection.BOMLen:]
+	}
+	r.index()
+	return r, nil
+}
+
+// Detection returns the charset of the file
+func (f *File) Detection() char.Detection {
+	return f.detection
+}
+
+// index records the start of every line, it only scans the data without copying it
+func (f *File) index() {
+	f.offsets = make([]int, 0, len(f.data)/64+2)
+	f.offsets = append(f.offsets, 0)
+	start := 0
+	for {
+		i := bytes.IndexByte(f.data[start:], '\n')
+		if i < 0 {
+			break
+		}
+		start += i + 1
+		f.offsets = append(f.offsets, start)
+	}
+	f.offsets = append(f.offsets, len(f.data)+1)
+}
+
+// Len returns number of lines
+func (f *File) Len() int {
+	f.mux.RLock()
+	defer f.mux.RUnlock()
+	return len(f.offsets) - 1
+}
+
+// Line returns a copy of line i in UTF-8, it returns nil after f is closed
+func (f *File) Line(i int) []byte {
+	f.mux.RLock()
+	defer f.mux.RUnlock()
+	if i < 0 || i >= len(f.offsets)-1 {
+		return nil
+	}
+	line := bytes.TrimSuffix(f.data[f.offsets[i]:f.offsets[i+1]-1], []byte("\r"))
+	if f.decode {
+		if r, err := f.detection.Encoding.NewDecoder().Bytes(line); err == nil {
+			return r
+		}
+	}
+	return append([]byte{}, line...)
+}
+
+// Close unmaps the file, f has no lines after it is closed
+func (f *File) Close() error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.data = nil
+	f.offsets = []int{0}
+	if f.unmap == nil {
+		return nil
+	}
+	unmap := f.unmap
+	f.unmap = nil
+	return unmap()
+}
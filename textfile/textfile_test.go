@@ -0,0 +1,71 @@
+// textfile_test
+package textfile
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hujun-open/golitebook/char"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestOpen(t *testing.T) {
+	gb, err := simplifiedchinese.GB18030.NewEncoder().Bytes([]byte("第一章 开始\r\n这是一本用来测试的书，里面都是中文。\r\n\r\n最后一行"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	utf16, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().Bytes([]byte("第一章\r\n开始"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sjis, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte("第一章\nはじまり"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		content     []byte
+		charset     string
+		want        []string
+		wantCharset string
+	}{
+		{[]byte("line1\nline2\r\n\nlast"), "", []string{"line1", "line2", "", "last"}, "UTF-8"},
+		{[]byte("line1\n"), "", []string{"line1", ""}, "UTF-8"},
+		{[]byte{}, "", []string{""}, "UTF-8"},
+		{[]byte("\xEF\xBB\xBFline1"), "", []string{"line1"}, "UTF-8"},
+		{gb, "", []string{"第一章 开始", "这是一本用来测试的书，里面都是中文。", "", "最后一行"}, "GB18030"},
+		{utf16, "", []string{"第一章", "开始"}, "UTF-16LE"},
+		{utf16[2:], "", []string{"第一章", "开始"}, "UTF-16LE"},
+		{sjis, "Shift_JIS", []string{"第一章", "はじまり"}, "Shift_JIS"},
+	}
+	det := char.NewDetChar()
+	for i, c := range cases {
+		p := filepath.Join(t.TempDir(), "book.txt")
+		if err := ioutil.WriteFile(p, c.content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		f, err := Open(p, det, c.charset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Detection().Name != c.wantCharset {
+			t.Fatalf("case %d expect charset %v, got %v", i, c.wantCharset, f.Detection())
+		}
+		if f.Len() != len(c.want) {
+			t.Fatalf("case %d expect %d lines, got %d", i, len(c.want), f.Len())
+		}
+		for j, w := range c.want {
+			if got := string(f.Line(j)); got != w {
+				t.Fatalf("case %d line %d expect %q, got %q", i, j, w, got)
+			}
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if f.Len() != 0 || f.Line(0) != nil {
+			t.Fatalf("case %d expect no line after close", i)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package toc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/hujun-open/golitebook/conf"
+)
+
+func getPatternFilePath() string {
+	return filepath.Join(conf.ConfDir(), "chapterpatterns")
+}
+
+// DefaultChapterPatterns are the chapter heading patterns used when there is no configuration
+var DefaultChapterPatterns = []string{
+	`第[零〇一二两三四五六七八九十百千万0-9０-９]+[章节回篇]`,
+	`(?i)chapter\s*[0-9ivxlc]+`,
+	`序章|序言|楔子|引子|尾声|后记|番外`,
+}
+
+// DefaultVolumePatterns are the volume heading patterns used when there is no configuration
+var DefaultVolumePatterns = []string{
+	`第[零〇一二两三四五六七八九十百千万0-9０-９]+[卷部集]`,
+	`卷[零〇一二两三四五六七八九十百千万0-9０-９]+`,
+	`(?i)(volume|book)\s*[0-9ivxlc]+`,
+}
+
+// a line longer than maxTitleLen runes is never a chapter heading
+const maxTitleLen = 50
+
+// PatternSet is the heading patterns of volumes and chapters
+type PatternSet struct {
+	Chapter []string
+	Volume  []string
+}
+
+func DefaultPatternSet() PatternSet {
+	return PatternSet{
+		Chapter: append([]string{}, DefaultChapterPatterns...),
+		Volume:  append([]string{}, DefaultVolumePatterns...),
+	}
+}
+
+func (ps PatternSet) copy() PatternSet {
+	return PatternSet{
+		Chapter: append([]string{}, ps.Chapter...),
+		Volume:  append([]string{}, ps.Volume...),
+	}
+}
+
+// Compile compiles all patterns, each pattern is anchored at the start of the line
+func (ps PatternSet) Compile() (*CompiledPatternSet, error) {
+	var err error
+	r := new(CompiledPatternSet)
+	if r.chapter, err = compilePatterns(ps.Chapter); err != nil {
+		return nil, err
+	}
+	if r.volume, err = compilePatterns(ps.Volume); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+type CompiledPatternSet struct {
+	chapter, volume []*regexp.Regexp
+}
+
+// ChapterPatternConf is the heading patterns configuration,
+// Books key is the id of book, its value overrides Default for that book
+type ChapterPatternConf struct {
+	Default PatternSet
+	Books   map[string]PatternSet
+	mux     *sync.RWMutex
+}
+
+func NewChapterPatternConf() *ChapterPatternConf {
+	return &ChapterPatternConf{
+		Default: DefaultPatternSet(),
+		Books:   make(map[string]PatternSet),
+		mux:     new(sync.RWMutex),
+	}
+}
+
+// Get returns the patterns for book, and whether they are book specific
+func (pconf *ChapterPatternConf) Get(book string) (PatternSet, bool) {
+	pconf.mux.RLock()
+	defer pconf.mux.RUnlock()
+	if ps, ok := pconf.Books[book]; ok {
+		return ps.copy(), true
+	}
+	return pconf.Default.copy(), false
+}
+
+// SetDefault set the default patterns, return error if any of patterns is invalid
+func (pconf *ChapterPatternConf) SetDefault(ps PatternSet) error {
+	if _, err := ps.Compile(); err != nil {
+		return err
+	}
+	pconf.mux.Lock()
+	defer pconf.mux.Unlock()
+	pconf.Default = ps.copy()
+	return nil
+}
+
+// SetBook set the patterns for book, a nil ps removes the override;
+// return error if any of patterns is invalid
+func (pconf *ChapterPatternConf) SetBook(book string, ps *PatternSet) error {
+	pconf.mux.Lock()
+	defer pconf.mux.Unlock()
+	if ps == nil {
+		delete(pconf.Books, book)
+		return nil
+	}
+	if _, err := ps.Compile(); err != nil {
+		return err
+	}
+	pconf.Books[book] = ps.copy()
+	return nil
+}
+
+// RenameBook moves the patterns of book old to book new, unless new already has its own
+func (pconf *ChapterPatternConf) RenameBook(old, new string) {
+	pconf.mux.Lock()
+	defer pconf.mux.Unlock()
+	ps, ok := pconf.Books[old]
+	if _, exists := pconf.Books[new]; !ok || exists {
+		return
+	}
+	pconf.Books[new] = ps
+	delete(pconf.Books, old)
+}
+
+func (pconf *ChapterPatternConf) Save() error {
+	pconf.mux.RLock()
+	defer pconf.mux.RUnlock()
+	buf, err := json.MarshalIndent(pconf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(getPatternFilePath(), buf, 0644)
+}
+
+func (pconf *ChapterPatternConf) Load() error {
+	buf, err := ioutil.ReadFile(getPatternFilePath())
+	if err != nil {
+		return err
+	}
+	pconf.mux.Lock()
+	defer pconf.mux.Unlock()
+	err = json.Unmarshal(buf, pconf)
+	if pconf.Books == nil {
+		pconf.Books = make(map[string]PatternSet)
+	}
+	return err
+}
+
+var ChapterPatterns *ChapterPatternConf
+
+func init() {
+	ChapterPatterns = NewChapterPatternConf()
+	ChapterPatterns.Load()
+}
+
+// compilePatterns compiles plist, each pattern is anchored at the start of the line
+func compilePatterns(plist []string) ([]*regexp.Regexp, error) {
+	r := []*regexp.Regexp{}
+	for _, p := range plist {
+		re, err := regexp.Compile(`^\s*(?:` + p + `)`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %v, %w", p, err)
+		}
+		r = append(r, re)
+	}
+	return r, nil
+}
+
+// isTitle returns true if line is a heading matches any of relist
+func isTitle(line []byte, relist []*regexp.Regexp) bool {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || len(line) > maxTitleLen*utf8.UTFMax || utf8.RuneCount(line) > maxTitleLen {
+		return false
+	}
+	for _, re := range relist {
+		if re.Match(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// headingLevel returns LevelVolume or LevelChapter if line is a heading, otherwise -1
+func (cps *CompiledPatternSet) headingLevel(line []byte) int {
+	if cps == nil {
+		return -1
+	}
+	if isTitle(line, cps.volume) {
+		return LevelVolume
+	}
+	if isTitle(line, cps.chapter) {
+		return LevelChapter
+	}
+	return -1
+}
@@ -0,0 +1,45 @@
+// pattern_test
+package toc
+
+import (
+	"testing"
+
+	"github.com/hujun-open/golitebook/liteview"
+)
+
+func TestChapterPatterns(t *testing.T) {
+	cps, err := DefaultPatternSet().Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	val := liteview.ByteLines{
+		[]byte("第一卷 风起"),
+		[]byte("  第一章 开始"),
+		[]byte("他说第二章写得很好"),
+		[]byte("Chapter 12 The End"),
+		[]byte("楔子"),
+		[]byte(""),
+		[]byte("»第三章 已下载"),
+	}
+	want := []int{0, 1, 3, 4, 6}
+	wantLevel := []int{LevelVolume, LevelChapter, LevelChapter, LevelChapter, LevelChapter}
+	toc := newChapterLocationListviaByteLineList(val, cps)
+	if len(toc) != len(want) {
+		t.Fatalf("expect %d chapters, got %v", len(want), toc)
+	}
+	for i, ch := range toc {
+		if ch.StartLine != want[i] || ch.Level != wantLevel[i] {
+			t.Fatalf("chapter %d expect line %d level %d, got %+v", i, want[i], wantLevel[i], ch)
+		}
+	}
+	children := toc.childrenMap()
+	if len(children[""]) != 1 || len(children["0"]) != 4 {
+		t.Fatalf("wrong tree %v", children)
+	}
+	if ch, vol := toc.locate(5); ch != 3 || vol != 0 {
+		t.Fatalf("line 5 expect in chapter 3 of volume 0, got %d, %d", ch, vol)
+	}
+	if _, err := (PatternSet{Chapter: []string{"第("}}).Compile(); err == nil {
+		t.Fatal("invalid pattern is accepted")
+	}
+}
@@ -0,0 +1,146 @@
+package toc
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/hujun-open/dvlist"
+	"github.com/hujun-open/golitebook/liteview"
+)
+
+// PatternDialog is the window to edit and preview chapter heading patterns
+type PatternDialog struct {
+	fyne.Window
+	book         string
+	getVal       func() liteview.Lines
+	onSaved      func()
+	patternEntry *widget.Entry
+	volumeEntry  *widget.Entry
+	bookOnly     *widget.Check
+	statusLabel  *widget.Label
+	list         *dvlist.DVList
+}
+
+// NewPatternDialog creates a new PatternDialog, getVal returns the text to preview,
+// onSaved is called after patterns are saved
+func NewPatternDialog(getVal func() liteview.Lines, onSaved func()) *PatternDialog {
+	r := new(PatternDialog)
+	r.Window = fyne.CurrentApp().NewWindow("章节规则")
+	r.SetCloseIntercept(func() { r.Hide() })
+	r.getVal = getVal
+	r.onSaved = onSaved
+	r.patternEntry = widget.NewMultiLineEntry()
+	r.patternEntry.SetPlaceHolder("章规则，每行一个正则表达式")
+	r.volumeEntry = widget.NewMultiLineEntry()
+	r.volumeEntry.SetPlaceHolder("卷规则，每行一个正则表达式")
+	r.bookOnly = widget.NewCheck("仅用于本书", nil)
+	r.statusLabel = widget.NewLabel("")
+	r.list, _ = dvlist.NewDVList(newChapterLocationView(nil))
+	form := widget.NewForm(
+		widget.NewFormItem("章", r.patternEntry),
+		widget.NewFormItem("卷", r.volumeEntry),
+	)
+	topContainer := fyne.NewContainerWithLayout(layout.NewVBoxLayout(),
+		form,
+		r.bookOnly,
+		r.statusLabel,
+	)
+	buttonContainer := fyne.NewContainerWithLayout(layout.NewVBoxLayout(),
+		widget.NewSeparator(),
+		fyne.NewContainerWithLayout(layout.NewGridLayout(4),
+			widget.NewButton("预览", r.preview),
+			widget.NewButton("保存", r.save),
+			widget.NewButton("恢复默认", r.reset),
+			widget.NewButton("关闭", r.Hide),
+		),
+	)
+	r.SetContent(fyne.NewContainerWithLayout(
+		layout.NewBorderLayout(topContainer, buttonContainer, nil, nil),
+		topContainer, buttonContainer, r.list))
+	r.Resize(fyne.NewSize(500, 800))
+	return r
+}
+
+// Set loads the patterns of book
+func (pdiag *PatternDialog) Set(book string) {
+	pdiag.book = book
+	ps, bookonly := ChapterPatterns.Get(book)
+	pdiag.setPatternSet(ps)
+	pdiag.bookOnly.SetChecked(bookonly)
+	pdiag.statusLabel.SetText("")
+	pdiag.list.SetData(newChapterLocationView(nil))
+}
+
+func (pdiag *PatternDialog) setPatternSet(ps PatternSet) {
+	pdiag.patternEntry.SetText(strings.Join(ps.Chapter, "\n"))
+	pdiag.volumeEntry.SetText(strings.Join(ps.Volume, "\n"))
+}
+
+// splitPatterns returns non-empty lines of txt
+func splitPatterns(txt string) []string {
+	r := []string{}
+	for _, p := range strings.Split(txt, "\n") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			r = append(r, p)
+		}
+	}
+	return r
+}
+
+func (pdiag *PatternDialog) patternSet() PatternSet {
+	return PatternSet{
+		Chapter: splitPatterns(pdiag.patternEntry.Text),
+		Volume:  splitPatterns(pdiag.volumeEntry.Text),
+	}
+}
+
+func (pdiag *PatternDialog) preview() {
+	cps, err := pdiag.patternSet().Compile()
+	if err != nil {
+		pdiag.statusLabel.SetText(err.Error())
+		return
+	}
+	toc := newChapterLocationListviaByteLineList(pdiag.getVal(), cps)
+	volumes := 0
+	for _, ch := range toc {
+		if ch.Level == LevelVolume {
+			volumes++
+		}
+	}
+	pdiag.list.SetData(newChapterLocationView(toc))
+	pdiag.statusLabel.SetText(fmt.Sprintf("共检测到 %d 卷, %d 章", volumes, len(toc)-volumes))
+}
+
+func (pdiag *PatternDialog) save() {
+	var err error
+	ps := pdiag.patternSet()
+	if pdiag.bookOnly.Checked {
+		err = ChapterPatterns.SetBook(pdiag.book, &ps)
+	} else {
+		err = ChapterPatterns.SetDefault(ps)
+		if err == nil {
+			err = ChapterPatterns.SetBook(pdiag.book, nil)
+		}
+	}
+	if err != nil {
+		pdiag.statusLabel.SetText(err.Error())
+		return
+	}
+	if err = ChapterPatterns.Save(); err != nil {
+		pdiag.statusLabel.SetText(fmt.Sprintf("failed to save chapter patterns, %v", err))
+		return
+	}
+	pdiag.statusLabel.SetText("已保存")
+	if pdiag.onSaved != nil {
+		pdiag.onSaved()
+	}
+}
+
+func (pdiag *PatternDialog) reset() {
+	pdiag.setPatternSet(DefaultPatternSet())
+	pdiag.bookOnly.SetChecked(false)
+}
@@ -2,21 +2,30 @@
 package toc
 
 import (
+	"github.com/hujun-open/golitebook/liteview"
 	"github.com/hujun-open/golitebook/plugin"
-	// "log"
+	"log"
 	"sort"
+	"strconv"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	// "fyne.io/fyne/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
-	"github.com/hujun-open/dvlist"
+)
+
+// level of a heading in the ToC tree
+const (
+	LevelVolume = iota
+	LevelChapter
 )
 
 // key is the chapter name, int the is the its line id
 type ChapterLocation struct {
 	Name      string
 	StartLine int
+	Level     int
 }
 
 type ChapterLocationList []ChapterLocation
@@ -34,52 +43,339 @@ func (clist ChapterLocationList) Fields() []string {
 	return []string{"章节"}
 }
 func (clist ChapterLocationList) Item(id int) []string {
+	if clist[id].Level == LevelChapter {
+		return []string{"    " + clist[id].Name}
+	}
 	return []string{clist[id].Name}
 }
+
+// Sort sorts headings by their position in the book, the only field is the heading
 func (clist ChapterLocationList) Sort(field int, ascend bool) {
-	sort.Sort(clist)
+	if ascend {
+		sort.Stable(clist)
+	} else {
+		sort.Stable(sort.Reverse(clist))
+	}
 }
-func (clist ChapterLocationList) Filter(kw string, i int) {
 
+// chapterLocationView is the headings in list contain the filter keyword, it is the list data of dvlist
+type chapterLocationView struct {
+	list ChapterLocationList
+	view ChapterLocationList
+	kw   string
+}
+
+func newChapterLocationView(list ChapterLocationList) *chapterLocationView {
+	r := &chapterLocationView{list: list}
+	r.Filter("", -1)
+	return r
+}
+
+func (v *chapterLocationView) Len() int {
+	return len(v.view)
+}
+
+func (v *chapterLocationView) Fields() []string {
+	return v.list.Fields()
+}
+
+func (v *chapterLocationView) Item(id int) []string {
+	if id < 0 || id >= len(v.view) {
+		return nil
+	}
+	return v.view.Item(id)
+}
+
+// Sort sorts all headings, the filter is kept
+func (v *chapterLocationView) Sort(field int, ascend bool) {
+	v.list.Sort(field, ascend)
+	v.Filter(v.kw, -1)
+}
+
+// Filter shows only headings contain kw, the only field is the heading; an empty kw clears the filter
+func (v *chapterLocationView) Filter(kw string, i int) {
+	v.kw = kw
+	v.view = ChapterLocationList{}
+	for _, ch := range v.list {
+		if ch.match(kw) {
+			v.view = append(v.view, ch)
+		}
+	}
+}
+
+func (ch ChapterLocation) match(kw string) bool {
+	return strings.Contains(strings.ToLower(ch.Name), strings.ToLower(strings.TrimSpace(kw)))
+}
+
+// childrenMap returns the tree structure of clist: key is the node id, value is list of children node id;
+// node id is the index in clist, root node id is "";
+// a chapter is the child of the closest volume before it, chapters before the first volume are at top level
+func (clist ChapterLocationList) childrenMap() map[widget.TreeNodeID][]widget.TreeNodeID {
+	r := map[widget.TreeNodeID][]widget.TreeNodeID{"": {}}
+	curVolume := ""
+	for i, ch := range clist {
+		uid := strconv.Itoa(i)
+		switch ch.Level {
+		case LevelVolume:
+			r[""] = append(r[""], uid)
+			r[uid] = []widget.TreeNodeID{}
+			curVolume = uid
+		default:
+			r[curVolume] = append(r[curVolume], uid)
+		}
+	}
+	return r
+}
+
+// filteredChildrenMap is childrenMap with only headings contain kw,
+// a volume is kept if any of its chapters contains kw
+func (clist ChapterLocationList) filteredChildrenMap(kw string) map[widget.TreeNodeID][]widget.TreeNodeID {
+	if strings.TrimSpace(kw) == "" {
+		return clist.childrenMap()
+	}
+	r := map[widget.TreeNodeID][]widget.TreeNodeID{"": {}}
+	for uid, children := range clist.childrenMap() {
+		if uid == "" {
+			continue
+		}
+		i, _ := strconv.Atoi(uid)
+		matched := []widget.TreeNodeID{}
+		for _, c := range children {
+			ci, _ := strconv.Atoi(c)
+			if clist[ci].match(kw) {
+				matched = append(matched, c)
+			}
+		}
+		if len(matched) > 0 || clist[i].match(kw) {
+			r[uid] = matched
+		}
+	}
+	for _, uid := range clist.childrenMap()[""] {
+		i, _ := strconv.Atoi(uid)
+		if _, ok := r[uid]; ok || clist[i].match(kw) {
+			r[""] = append(r[""], uid)
+		}
+	}
+	return r
+}
+
+// locate returns index of the heading that lineid belongs to, and index of its volume;
+// -1 means none
+func (clist ChapterLocationList) locate(lineid int) (chapter, volume int) {
+	chapter, volume = -1, -1
+	for i, ch := range clist {
+		if lineid < ch.StartLine {
+			break
+		}
+		chapter = i
+		if ch.Level == LevelVolume {
+			volume = i
+		}
+	}
+	return
 }
 
 type GOTOChapterHandler func(name string, lineid int)
 type ToCDialog struct {
 	fyne.Window
-	toc  ChapterLocationList
-	list *dvlist.DVList
-	h    GOTOChapterHandler
+	toc      ChapterLocationList
+	children map[widget.TreeNodeID][]widget.TreeNodeID
+	tree     *widget.Tree
+	filter   *widget.Entry
+	h        GOTOChapterHandler
+	// selecting is true when the selection is not made by user
+	selecting bool
+	// cursor is the selected node, moved by arrow keys
+	cursor widget.TreeNodeID
 }
 
+// read goes to the chapter and unselects it, so it could be chosen again next time
 func (tocdiag *ToCDialog) read(chapid int) {
 	if tocdiag.h != nil {
 		tocdiag.h(tocdiag.toc[chapid].Name, tocdiag.toc[chapid].StartLine)
 	}
+	tocdiag.tree.UnselectAll()
+	tocdiag.cursor = ""
 	tocdiag.Hide()
 }
 
-func (tocdiag *ToCDialog) Set(linelist [][]byte) {
-	tocdiag.toc = newChapterLocationListviaByteLineList(linelist)
-	tocdiag.list.SetData(tocdiag.toc)
+// activate toggles uid if it is a volume, otherwise reads the chapter
+func (tocdiag *ToCDialog) activate(uid widget.TreeNodeID) {
+	i, err := strconv.Atoi(uid)
+	if err != nil || i < 0 || i >= len(tocdiag.toc) {
+		return
+	}
+	if tocdiag.isBranch(uid) {
+		tocdiag.tree.ToggleBranch(uid)
+		tocdiag.moveCursor(uid)
+		return
+	}
+	tocdiag.read(i)
 }
 
-func (tocdiag *ToCDialog) SetSelection(curStartline int) {
-	var i int
-	var ch ChapterLocation
-	for i, ch = range tocdiag.toc {
-		if curStartline < ch.StartLine {
+func (tocdiag *ToCDialog) onSelected(uid widget.TreeNodeID) {
+	if tocdiag.selecting {
+		return
+	}
+	tocdiag.activate(uid)
+}
+
+// moveCursor selects uid without reading it
+func (tocdiag *ToCDialog) moveCursor(uid widget.TreeNodeID) {
+	tocdiag.selecting = true
+	defer func() { tocdiag.selecting = false }()
+	tocdiag.cursor = uid
+	tocdiag.tree.Select(uid)
+	tocdiag.tree.ScrollTo(uid)
+}
+
+// visibleUIDs returns nodes shown in the tree from top to bottom, children of a branch are shown if it is open
+func visibleUIDs(children map[widget.TreeNodeID][]widget.TreeNodeID, isOpen func(widget.TreeNodeID) bool) []widget.TreeNodeID {
+	r := []widget.TreeNodeID{}
+	var walk func(uid widget.TreeNodeID)
+	walk = func(uid widget.TreeNodeID) {
+		for _, c := range children[uid] {
+			r = append(r, c)
+			if _, branch := children[c]; branch && isOpen(c) {
+				walk(c)
+			}
+		}
+	}
+	walk("")
+	return r
+}
+
+// stepCursor moves the cursor by step among visible nodes
+func (tocdiag *ToCDialog) stepCursor(step int) {
+	nodes := visibleUIDs(tocdiag.children, tocdiag.tree.IsBranchOpen)
+	if len(nodes) == 0 {
+		return
+	}
+	next := 0
+	for i, uid := range nodes {
+		if uid == tocdiag.cursor {
+			next = i + step
 			break
 		}
 	}
-	i--
+	if next < 0 {
+		next = 0
+	}
+	if next >= len(nodes) {
+		next = len(nodes) - 1
+	}
+	tocdiag.moveCursor(nodes[next])
+}
+
+func (tocdiag *ToCDialog) childUIDs(uid widget.TreeNodeID) []widget.TreeNodeID {
+	return tocdiag.children[uid]
+}
+
+func (tocdiag *ToCDialog) isBranch(uid widget.TreeNodeID) bool {
+	_, ok := tocdiag.children[uid]
+	return ok
+}
+
+// tocNode is a heading in the tree, tapping it always activates the heading,
+// even if it is already selected
+type tocNode struct {
+	widget.Label
+	uid  widget.TreeNodeID
+	diag *ToCDialog
+}
+
+func newToCNode(diag *ToCDialog) *tocNode {
+	r := &tocNode{diag: diag}
+	r.ExtendBaseWidget(r)
+	return r
+}
+
+func (node *tocNode) Tapped(*fyne.PointEvent) {
+	node.diag.activate(node.uid)
+}
+
+func (tocdiag *ToCDialog) createNode(branch bool) fyne.CanvasObject {
+	return newToCNode(tocdiag)
+}
+
+func (tocdiag *ToCDialog) updateNode(uid widget.TreeNodeID, branch bool, node fyne.CanvasObject) {
+	i, err := strconv.Atoi(uid)
+	if err != nil || i < 0 || i >= len(tocdiag.toc) {
+		return
+	}
+	n := node.(*tocNode)
+	n.uid = uid
+	n.SetText(tocdiag.toc[i].Name)
+}
+
+// Set detects chapters in linelist with the chapter patterns of book
+func (tocdiag *ToCDialog) Set(linelist liteview.Lines, book string) {
+	tocdiag.SetChapters(newChapterLocationListviaByteLineList(linelist, bookChapterPatterns(book)))
+}
+
+// SetChapters sets the ToC to clist instead of detecting from text
+func (tocdiag *ToCDialog) SetChapters(clist ChapterLocationList) {
+	tocdiag.toc = clist
+	tocdiag.filter.SetText("")
+	tocdiag.children = tocdiag.toc.childrenMap()
+	tocdiag.tree.CloseAllBranches()
+	tocdiag.tree.UnselectAll()
+	tocdiag.cursor = ""
+	tocdiag.tree.Refresh()
+}
+
+// onFilterChanged shows only headings contain kw, with their volumes opened
+func (tocdiag *ToCDialog) onFilterChanged(kw string) {
+	tocdiag.children = tocdiag.toc.filteredChildrenMap(kw)
+	if strings.TrimSpace(kw) == "" {
+		tocdiag.tree.CloseAllBranches()
+	} else {
+		tocdiag.tree.OpenAllBranches()
+	}
+	tocdiag.tree.Refresh()
+}
+
+// SetSelection selects the heading curStartline belongs to, and opens its volume
+func (tocdiag *ToCDialog) SetSelection(curStartline int) {
+	i, volume := tocdiag.toc.locate(curStartline)
 	if i < 0 {
+		if len(tocdiag.toc) == 0 {
+			return
+		}
 		i = 0
 	}
-	tocdiag.list.ScrollTo(i)
-	tocdiag.list.SetSelection(i, true)
+	if volume >= 0 {
+		tocdiag.tree.OpenBranch(strconv.Itoa(volume))
+	}
+	tocdiag.moveCursor(strconv.Itoa(i))
 }
 func (tocdiag *ToCDialog) Refresh() {
-	tocdiag.list.Refresh()
+	tocdiag.tree.Refresh()
+}
+
+// onTypedKey: up/down moves the cursor, enter opens the chapter or toggles the volume,
+// left/right closes/opens the volume
+func (tocdiag *ToCDialog) onTypedKey(evt *fyne.KeyEvent) {
+	switch evt.Name {
+	case fyne.KeyEscape:
+		tocdiag.Hide()
+	case fyne.KeyUp:
+		tocdiag.stepCursor(-1)
+	case fyne.KeyDown:
+		tocdiag.stepCursor(1)
+	case fyne.KeyReturn, fyne.KeyEnter:
+		if tocdiag.cursor != "" {
+			tocdiag.activate(tocdiag.cursor)
+		}
+	case fyne.KeyLeft:
+		if tocdiag.cursor != "" && tocdiag.isBranch(tocdiag.cursor) {
+			tocdiag.tree.CloseBranch(tocdiag.cursor)
+		}
+	case fyne.KeyRight:
+		if tocdiag.cursor != "" && tocdiag.isBranch(tocdiag.cursor) {
+			tocdiag.tree.OpenBranch(tocdiag.cursor)
+		}
+	}
 }
 
 func newToCDialog(toc ChapterLocationList, parent fyne.Window, h GOTOChapterHandler) *ToCDialog {
@@ -87,25 +383,52 @@ func newToCDialog(toc ChapterLocationList, parent fyne.Window, h GOTOChapterHand
 	r.Window = fyne.CurrentApp().NewWindow("章节列表")
 	r.SetCloseIntercept(func() { r.Hide() })
 	r.toc = toc
+	r.children = toc.childrenMap()
 	r.h = h
-	r.list, _ = dvlist.NewDVList(r.toc, dvlist.WithDoubleClickHandler(r.read))
+	r.tree = widget.NewTree(r.childUIDs, r.isBranch, r.createNode, r.updateNode)
+	r.tree.OnSelected = r.onSelected
+	r.filter = widget.NewEntry()
+	r.filter.SetPlaceHolder("过滤")
+	r.filter.OnChanged = r.onFilterChanged
+	// leaves the filter so that arrow keys move the cursor
+	r.filter.OnSubmitted = func(string) {
+		r.Canvas().Unfocus()
+		r.stepCursor(0)
+	}
 	button := widget.NewButton("关闭", r.Hide)
-	r.SetContent(fyne.NewContainerWithLayout(layout.NewBorderLayout(nil, button, nil, nil), button, r.list))
+	r.SetContent(fyne.NewContainerWithLayout(layout.NewBorderLayout(r.filter, button, nil, nil), r.filter, button, r.tree))
 	r.Resize(fyne.NewSize(500, 800))
-	r.Canvas().SetOnTypedKey(r.list.TypedKey)
+	r.Canvas().SetOnTypedKey(r.onTypedKey)
 	return r
 }
-func NewToCDialog(val [][]byte, parent fyne.Window, h GOTOChapterHandler) *ToCDialog {
-	return newToCDialog(newChapterLocationListviaByteLineList(val), parent, h)
+func NewToCDialog(val liteview.Lines, book string, parent fyne.Window, h GOTOChapterHandler) *ToCDialog {
+	return newToCDialog(newChapterLocationListviaByteLineList(val, bookChapterPatterns(book)), parent, h)
+}
+
+// bookChapterPatterns returns compiled heading patterns of book
+func bookChapterPatterns(book string) *CompiledPatternSet {
+	ps, _ := ChapterPatterns.Get(book)
+	cps, err := ps.Compile()
+	if err != nil {
+		log.Printf("failed to compile chapter patterns of %v, %v", book, err)
+		return nil
+	}
+	return cps
 }
 
-func newChapterLocationListviaByteLineList(val [][]byte) ChapterLocationList {
+// newChapterLocationListviaByteLineList returns lines that are either pre-created chapter title or match any of cps
+func newChapterLocationListviaByteLineList(val liteview.Lines, cps *CompiledPatternSet) ChapterLocationList {
 	toc := ChapterLocationList{}
 	bookmarkRune := []rune(plugin.BookMarkChar)[0]
-	for i, line := range val {
-		if plugin.IsPrecreatedChapterTitle(line, bookmarkRune) {
-			toc = append(toc, ChapterLocation{Name: string(line), StartLine: i})
+	for i := 0; i < val.Len(); i++ {
+		line := val.Line(i)
+		level := LevelChapter
+		if !plugin.IsPrecreatedChapterTitle(line, bookmarkRune) {
+			if level = cps.headingLevel(line); level < 0 {
+				continue
+			}
 		}
+		toc = append(toc, ChapterLocation{Name: strings.TrimSpace(string(line)), StartLine: i, Level: level})
 	}
 	return toc
 }
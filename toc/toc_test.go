@@ -0,0 +1,72 @@
+// toc_test
+package toc
+
+import (
+	"reflect"
+	"testing"
+
+	"fyne.io/fyne/v2/widget"
+)
+
+func TestFilteredChildrenMap(t *testing.T) {
+	clist := ChapterLocationList{
+		{Name: "序章", StartLine: 0, Level: LevelChapter},
+		{Name: "第一卷", StartLine: 10, Level: LevelVolume},
+		{Name: "第一章 开始", StartLine: 11, Level: LevelChapter},
+		{Name: "第二章 结束", StartLine: 20, Level: LevelChapter},
+		{Name: "第二卷", StartLine: 30, Level: LevelVolume},
+		{Name: "第三章", StartLine: 31, Level: LevelChapter},
+	}
+	got := clist.filteredChildrenMap("结束")
+	want := map[widget.TreeNodeID][]widget.TreeNodeID{
+		"":  {"1"},
+		"1": {"3"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expect %v, got %v", want, got)
+	}
+	if got := clist.filteredChildrenMap(" "); !reflect.DeepEqual(got, clist.childrenMap()) {
+		t.Fatalf("expect the whole tree for empty kw, got %v", got)
+	}
+	sorted := append(ChapterLocationList{}, clist...)
+	sorted.Sort(0, false)
+	if sorted[0].StartLine != 31 || sorted[5].StartLine != 0 {
+		t.Fatalf("expect descending order, got %v", sorted)
+	}
+}
+
+func TestVisibleUIDs(t *testing.T) {
+	clist := ChapterLocationList{
+		{Name: "序章", StartLine: 0, Level: LevelChapter},
+		{Name: "第一卷", StartLine: 10, Level: LevelVolume},
+		{Name: "第一章", StartLine: 11, Level: LevelChapter},
+		{Name: "第二卷", StartLine: 30, Level: LevelVolume},
+		{Name: "第二章", StartLine: 31, Level: LevelChapter},
+	}
+	open := func(uid widget.TreeNodeID) bool { return uid == "3" }
+	want := []widget.TreeNodeID{"0", "1", "3", "4"}
+	if got := visibleUIDs(clist.childrenMap(), open); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expect %v, got %v", want, got)
+	}
+}
+
+func TestChapterLocationViewFilter(t *testing.T) {
+	v := newChapterLocationView(ChapterLocationList{
+		{Name: "第一卷", StartLine: 10, Level: LevelVolume},
+		{Name: "第一章 开始", StartLine: 11, Level: LevelChapter},
+		{Name: "第二章 结束", StartLine: 20, Level: LevelChapter},
+	})
+	v.Filter("章", -1)
+	if v.Len() != 2 || v.Item(0)[0] != "    第一章 开始" || v.Item(2) != nil {
+		t.Fatalf("expect 2 chapters, got %d", v.Len())
+	}
+	// the filter is kept after sorted
+	v.Sort(0, false)
+	if v.Len() != 2 || v.Item(0)[0] != "    第二章 结束" {
+		t.Fatalf("expect chapters in descending order, got %v", v.Item(0))
+	}
+	v.Filter("", -1)
+	if v.Len() != 3 {
+		t.Fatalf("expect all headings, got %d", v.Len())
+	}
+}